@@ -0,0 +1,99 @@
+package dat
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"sort"
+
+	"github.com/lib/pq"
+)
+
+// Any creates a condition which is true when column equals any element of
+// slice, eg tag_id = ANY($1). The slice is bound as a Postgres array, an
+// empty slice matches nothing.
+func Any(column string, slice interface{}) *Expression {
+	return Expr(column+" = ANY($1)", pq.Array(slice))
+}
+
+// All creates a condition which is true when every element of the array
+// column equals value, eg $1 = ALL(scores).
+func All(column string, value interface{}) *Expression {
+	return Expr("$1 = ALL("+column+")", value)
+}
+
+// ArrayOverlap creates a condition which is true when the array column has
+// any element in common with slice, eg tag_ids && $1. An empty slice
+// matches nothing.
+func ArrayOverlap(column string, slice interface{}) *Expression {
+	return Expr(column+" && $1", pq.Array(slice))
+}
+
+// arrayBindFragments rewrites fragments for UseArrayBinding. A slice value
+// of an equality map becomes "column" = ANY($1) and a slice argument of a
+// condition is bound as a single Postgres array, so the statement text does
+// not depend on the length of the slice.
+func arrayBindFragments(fragments []*whereFragment) []*whereFragment {
+	bound := make([]*whereFragment, 0, len(fragments))
+	for _, f := range fragments {
+		if f.EqualityMap == nil {
+			bound = append(bound, arrayBindValues(f))
+			continue
+		}
+
+		var columns []string
+		eq := map[string]interface{}{}
+		for k, v := range f.EqualityMap {
+			if isArrayBindable(v) && !reflect.ValueOf(v).IsNil() {
+				columns = append(columns, k)
+			} else {
+				eq[k] = v
+			}
+		}
+		if len(columns) == 0 {
+			bound = append(bound, f)
+			continue
+		}
+		if len(eq) > 0 {
+			bound = append(bound, &whereFragment{EqualityMap: eq})
+		}
+		sort.Strings(columns)
+		for _, k := range columns {
+			bound = append(bound, &whereFragment{
+				Condition: QuoteIdent(k) + " = ANY($1)",
+				Values:    []interface{}{pq.Array(f.EqualityMap[k])},
+			})
+		}
+	}
+	return bound
+}
+
+// arrayBindValues binds the slice arguments of a condition as arrays.
+func arrayBindValues(f *whereFragment) *whereFragment {
+	var values []interface{}
+	for i, v := range f.Values {
+		if !isArrayBindable(v) {
+			continue
+		}
+		if values == nil {
+			values = append([]interface{}{}, f.Values...)
+		}
+		values[i] = pq.Array(v)
+	}
+	if values == nil {
+		return f
+	}
+	return &whereFragment{Condition: f.Condition, Values: values}
+}
+
+// isArrayBindable determines if v is a slice which is bound as an array
+// rather than as a value of its own, eg []byte binds as bytea.
+func isArrayBindable(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
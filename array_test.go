@@ -0,0 +1,63 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayConditions(t *testing.T) {
+	ids := []int64{1, 2, 3}
+	sql, args := Select("*").
+		From("posts").
+		Where("state = $1", "open").
+		Where(Any("id", ids)).
+		Where(All("scores", 10)).
+		Where(ArrayOverlap("tag_ids", []string{"a"})).
+		ToSQL()
+
+	assert.Equal(t, "SELECT * FROM posts WHERE (state = $1) AND (id = ANY($2)) AND ($3 = ALL(scores)) AND (tag_ids && $4)", sql)
+	assert.Equal(t, []interface{}{"open", pq.Array(ids), 10, pq.Array([]string{"a"})}, args)
+}
+
+func TestArrayConditionsInterpolate(t *testing.T) {
+	sql, args := Select("*").From("posts").Where(Any("id", []int64{})).Where(ArrayOverlap("tag_ids", []int64{1, 2})).ToSQL()
+	sql, _, err := Interpolate(sql, args)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM posts WHERE (id = ANY('{}')) AND (tag_ids && '{1,2}')", sql)
+}
+
+func TestUseArrayBinding(t *testing.T) {
+	ids := []int64{1, 2, 3}
+	tags := []string{"a", "b"}
+	sql, args := Select("*").
+		From("posts").
+		Where(Eq{"id": ids, "state": "open", "tag": tags}).
+		Where("user_id = ANY($1) AND title = $2", []int64{4, 5}, "x").
+		Where("image = $1", []byte{1}).
+		UseArrayBinding(true).
+		ToSQL()
+
+	assert.Equal(t, `SELECT * FROM posts WHERE ("state" = $1) AND ("id" = ANY($2)) AND ("tag" = ANY($3)) AND (user_id = ANY($4) AND title = $5) AND (image = $6)`, sql)
+	assert.Equal(t, []interface{}{"open", pq.Array(ids), pq.Array(tags), pq.Array([]int64{4, 5}), "x", []byte{1}}, args)
+
+	// the statement text does not depend on the length of the slice
+	one, _ := Select("*").From("posts").Where(Eq{"id": []int64{1}}).UseArrayBinding(true).ToSQL()
+	many, _ := Select("*").From("posts").Where(Eq{"id": []int64{1, 2, 3, 4}}).UseArrayBinding(true).ToSQL()
+	assert.Equal(t, one, many)
+
+	// a nil slice is still NULL
+	sql, args = Select("*").From("posts").Where(Eq{"id": []int64(nil)}).UseArrayBinding(true).ToSQL()
+	assert.Equal(t, `SELECT * FROM posts WHERE ("id" IS NULL)`, sql)
+	assert.Empty(t, args)
+
+	sql, args = Update("posts").Set("state", "closed").Where(Eq{"id": ids}).UseArrayBinding(true).ToSQL()
+	assert.Equal(t, `UPDATE "posts" SET "state" = $1 WHERE ("id" = ANY($2))`, sql)
+	assert.Equal(t, []interface{}{"closed", pq.Array(ids)}, args)
+
+	sql, args = DeleteFrom("posts").Where(Eq{"id": ids}).UseArrayBinding(true).ToSQL()
+	assert.Equal(t, `DELETE FROM posts WHERE ("id" = ANY($1))`, sql)
+	assert.Equal(t, []interface{}{pq.Array(ids)}, args)
+}
@@ -1,8 +1,29 @@
 package dat
 
+import "fmt"
+
+// Fragment is anything which builds SQL and args, eg a Builder or a
+// hand-rolled type, with $1..$n placeholders relative to the fragment.
+// Fragments compose into statements through Where, In, Exists, FromDerived,
+// JoinLateral, the set operations and With, which renumber their
+// placeholders.
+//
+// ToSQL returns no error so every Builder is a Fragment. Like the builders,
+// a hand-rolled fragment reports a failure by panicking with an error. The
+// fragment is built when it is composed, so the panic is raised by the
+// composing call, eg Where, as for any invalid argument.
+type Fragment interface {
+	ToSQL() (string, []interface{})
+}
+
 // Builder interface is used to tie SQL generators to executors.
 type Builder interface {
-	// ToSQL builds the SQL and arguments from builder.
+	// ToSQL builds the SQL and arguments from builder. The SQL uses $1..$n
+	// placeholders numbered in the order of args. Unless the builder is
+	// interpolated, this is exactly what runners send to the database, so
+	// the result may be handed to any database/sql compatible driver.
+	// ToSQL panics if the builder is in an invalid state, see the ToSQL
+	// function for a variant returning an error.
 	ToSQL() (string, []interface{})
 
 	// Interpolate builds the interpolation SQL and arguments from builder.
@@ -14,6 +35,36 @@ type Builder interface {
 	IsInterpolated() bool
 }
 
+// ToSQL builds the SQL and arguments from b like b.ToSQL() does, but returns
+// an error rather than panicking when the builder is in an invalid state.
+// The placeholders are those of the Dialect if it is a PlaceholderWriter,
+// eg ? for MySQL.
+func ToSQL(b Builder) (sql string, args []interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch e := r.(type) {
+			case error:
+				err = e
+			default:
+				err = fmt.Errorf("%v", r)
+			}
+			sql, args = "", nil
+		}
+	}()
+
+	if eb, ok := b.(errBuilder); ok {
+		if sql, args, err = eb.toSQL(); err != nil {
+			return "", nil, err
+		}
+	} else {
+		sql, args = b.ToSQL()
+	}
+	if pw, ok := Dialect.(PlaceholderWriter); ok {
+		return rewritePlaceholders(sql, args, pw)
+	}
+	return sql, args, nil
+}
+
 // Call creates a new CallBuilder for the given sproc and args.
 func Call(sproc string, args ...interface{}) *CallBuilder {
 	b := NewCallBuilder(sproc, args...)
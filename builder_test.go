@@ -0,0 +1,92 @@
+package dat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToSQLMatchesBuilders(t *testing.T) {
+	builders := []Builder{
+		Select("a").From("b").Where("c = $1", 1),
+		InsertInto("b").Columns("a").Values(1),
+		Update("b").Set("a", 1).Where("c = $1", 2),
+		DeleteFrom("b").Where("c = $1", 1),
+		Upsert("b").Columns("a").Values(1).Where("c = $1", 2),
+		SQL("SELECT $1", 1),
+	}
+	for _, b := range builders {
+		expectedSQL, expectedArgs := b.ToSQL()
+		sql, args, err := ToSQL(b)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedSQL, sql)
+		assert.Equal(t, expectedArgs, args)
+	}
+}
+
+func TestToSQLInvalidBuilder(t *testing.T) {
+	sql, args, err := ToSQL(Update("b"))
+	assert.EqualError(t, err, "no set clauses specified")
+	assert.Empty(t, sql)
+	assert.Nil(t, args)
+
+	objs := make([]someRecord, MaxParameters)
+	_, _, err = ToSQL(InsertInto("b").Columns("something_id", "user_id").Records(objs))
+	assert.Equal(t, ErrTooManyParameters, err)
+}
+
+// fragment is a hand-rolled condition.
+type fragment struct {
+	sql string
+	arg interface{}
+}
+
+func (f fragment) ToSQL() (string, []interface{}) {
+	return f.sql, []interface{}{f.arg}
+}
+
+// activeUsers is a hand-rolled subquery.
+type activeUsers struct {
+	since string
+}
+
+func (f activeUsers) ToSQL() (string, []interface{}) {
+	return "SELECT id FROM users WHERE active AND seen_at > $1", []interface{}{f.since}
+}
+
+func TestFragmentComposes(t *testing.T) {
+	f := activeUsers{since: "2019-01-01"}
+
+	sql, args := Select("*").
+		From("posts").
+		Where("state = $1", "published").
+		Where(In("user_id", f)).
+		ToSQL()
+	assert.Equal(t, `SELECT * FROM posts WHERE (state = $1) AND (user_id IN (SELECT id FROM users WHERE active AND seen_at > $2))`, sql)
+	assert.Equal(t, []interface{}{"published", "2019-01-01"}, args)
+
+	sql, args = Select("u.id").FromDerived(f, "u").Where("u.id > $1", 10).ToSQL()
+	assert.Equal(t, `SELECT u.id FROM (SELECT id FROM users WHERE active AND seen_at > $1) AS "u" WHERE (u.id > $2)`, sql)
+	assert.Equal(t, []interface{}{"2019-01-01", 10}, args)
+
+	sql, args = Update("users").Set("notified", true).Where(fragment{"seen_at > $1", "2019-01-01"}).ToSQL()
+	assert.Equal(t, `UPDATE "users" SET "notified" = $1 WHERE (seen_at > $2)`, sql)
+	assert.Equal(t, []interface{}{true, "2019-01-01"}, args)
+
+	assert.Panics(t, func() { Select("*").From("users").Where(f, 10) })
+}
+
+// failing is a hand-rolled fragment which cannot build.
+type failing struct{}
+
+var errFailing = errors.New("failing fragment")
+
+func (failing) ToSQL() (string, []interface{}) {
+	panic(errFailing)
+}
+
+func TestFragmentError(t *testing.T) {
+	assert.PanicsWithValue(t, errFailing, func() { Select("*").From("posts").Where(failing{}) })
+	assert.PanicsWithValue(t, errFailing, func() { In("user_id", failing{}) })
+}
@@ -14,7 +14,7 @@ type CallBuilder struct {
 // NewCallBuilder creates a new CallBuilder for the given sproc name and args.
 func NewCallBuilder(sproc string, args ...interface{}) *CallBuilder {
 	if sproc == "" {
-		logger.Error("Invalid sproc name", zap.String("name", sproc))
+		logger().Error("Invalid sproc name", zap.String("name", sproc))
 		return nil
 	}
 	return &CallBuilder{sproc: sproc, args: args, isInterpolated: EnableInterpolation}
@@ -0,0 +1,39 @@
+// Package cockroach is the CockroachDB dialect. CockroachDB is wire
+// compatible with Postgres, the dialect only overrides the differences.
+package cockroach
+
+import (
+	"strconv"
+
+	"github.com/casualjim/dat/common"
+	"github.com/casualjim/dat/postgres"
+)
+
+// RestartSavepoint is the savepoint name CockroachDB uses for client-side
+// transaction retries. Releasing it commits the whole transaction, so it is
+// only for code implementing the retry protocol, never for nesting.
+const RestartSavepoint = "cockroach_restart"
+
+// Cockroach is the CockroachDB dialect.
+type Cockroach struct {
+	*postgres.Postgres
+}
+
+// New returns a new CockroachDB dialect.
+func New() *Cockroach {
+	return &Cockroach{Postgres: postgres.New()}
+}
+
+// SavepointName returns the savepoint name for a nested transaction at
+// depth, sp_N like Postgres as nested transactions must not use
+// RestartSavepoint.
+func (cd *Cockroach) SavepointName(depth int) string {
+	return "sp_" + strconv.Itoa(depth)
+}
+
+// WriteAsOfSystemTime writes the AS OF SYSTEM TIME clause for expr, eg
+// '-10s' or follower_read_timestamp().
+func (cd *Cockroach) WriteAsOfSystemTime(buf common.BufferWriter, expr string) {
+	buf.WriteString(" AS OF SYSTEM TIME ")
+	buf.WriteString(expr)
+}
@@ -12,6 +12,10 @@ type BufferWriter interface {
 	WriteString(s string) (n int, err error)
 }
 
+// maxPooledBufferSize is the capacity above which buffers are dropped rather
+// than pooled, so a single huge statement does not pin its memory.
+const maxPooledBufferSize = 64 * 1024
+
 // BufferPool is sync pool for *bytes.Buffer
 type BufferPool struct {
 	sync.Pool
@@ -35,6 +39,9 @@ func (bp *BufferPool) Get() *bytes.Buffer {
 
 // Put reurns a buffer which was previously checked out.
 func (bp *BufferPool) Put(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBufferSize {
+		return
+	}
 	b.Reset()
 	bp.Pool.Put(b)
 }
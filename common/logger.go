@@ -0,0 +1,58 @@
+package common
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var (
+	loggerMu   sync.RWMutex
+	baseLogger *zap.Logger
+)
+
+// SetLogger replaces the logger used by all dat packages. A nil logger
+// reverts to the global zap logger.
+func SetLogger(l *zap.Logger) {
+	loggerMu.Lock()
+	baseLogger = l
+	loggerMu.Unlock()
+}
+
+// Logger returns the logger set by SetLogger or the global zap logger at
+// the time of the call.
+func Logger() *zap.Logger {
+	loggerMu.RLock()
+	l := baseLogger
+	loggerMu.RUnlock()
+	if l == nil {
+		return zap.L()
+	}
+	return l
+}
+
+// NamedLogger is a named child of Logger() which follows any changes to the
+// base logger.
+type NamedLogger struct {
+	sync.Mutex
+	name  string
+	base  *zap.Logger
+	named *zap.Logger
+}
+
+// NewNamedLogger creates a NamedLogger.
+func NewNamedLogger(name string) *NamedLogger {
+	return &NamedLogger{name: name}
+}
+
+// Get returns the named logger of the current base logger.
+func (nl *NamedLogger) Get() *zap.Logger {
+	base := Logger()
+	nl.Lock()
+	defer nl.Unlock()
+	if base != nl.base {
+		nl.base = base
+		nl.named = base.Named(nl.name)
+	}
+	return nl.named
+}
@@ -0,0 +1,331 @@
+package dat
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CompositeValue is a value of a Postgres composite type, eg
+// ROW($1, $2)::address. Scanned fields are the text of the field, nil for
+// NULL.
+type CompositeValue struct {
+	// Type is the composite type, eg address.
+	Type string
+	// Fields are the values of the attributes in the order of the type.
+	Fields []interface{}
+}
+
+// Composite creates a value of the composite type typeName from fields in
+// the order of the attributes of the type. A single struct, or pointer to
+// one, is expanded to its exported fields in declaration order, skipping
+// those tagged db:"-".
+//
+//	dat.Composite("address", addr.Street, addr.City, addr.Zip)
+//	dat.Composite("address", addr)
+//
+// Interpolated, it is written as ROW('Main St', 'Springfield', '12345')::address,
+// otherwise it is sent as a record literal, which may need a cast, eg
+// $1::address, where the type cannot be inferred.
+func Composite(typeName string, fields ...interface{}) *CompositeValue {
+	if typeName == "" {
+		panic("Composite requires a type name")
+	}
+	if len(fields) == 1 {
+		if v, ok := compositeStruct(fields[0]); ok {
+			fields = nil
+			for _, i := range compositeFieldIndexes(v.Type()) {
+				fields = append(fields, v.Field(i).Interface())
+			}
+		}
+	}
+	return &CompositeValue{Type: typeName, Fields: fields}
+}
+
+// compositeStruct returns the struct of v to expand into fields. Structs
+// which are values themselves, eg time.Time, are not expanded.
+func compositeStruct(v interface{}) (reflect.Value, bool) {
+	switch v.(type) {
+	case driver.Valuer, time.Time, *time.Time:
+		return reflect.Value{}, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return rv, rv.Kind() == reflect.Struct
+}
+
+// compositeFieldIndexes returns the indexes of the exported fields of t not
+// tagged db:"-".
+func compositeFieldIndexes(t reflect.Type) []int {
+	var indexes []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("db") == "-" {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// Expression implements Expressioner, writing a ROW constructor cast to
+// the type.
+func (c CompositeValue) Expression() (string, []interface{}, error) {
+	var buf strings.Builder
+	buf.WriteString("ROW(")
+	for i := range c.Fields {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writePlaceholder(&buf, i+1)
+	}
+	buf.WriteString(")::")
+	buf.WriteString(c.Type)
+	return Interpolate(buf.String(), c.Fields)
+}
+
+// Value implements driver.Valuer, writing a record literal, eg
+// ("Main St","Springfield",).
+func (c CompositeValue) Value() (driver.Value, error) {
+	var buf bytes.Buffer
+	buf.WriteRune('(')
+	for i, f := range c.Fields {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		if err := writeCompositeField(&buf, f); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteRune(')')
+	return buf.String(), nil
+}
+
+var compositeEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// writeCompositeField writes a field of a record literal. NULL is written
+// as nothing, other values are quoted.
+func writeCompositeField(buf *bytes.Buffer, v interface{}) error {
+	if isNilPointer(v) {
+		return nil
+	}
+	dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		return fmt.Errorf("Composite does not support fields of type %T: %v", v, err)
+	}
+
+	var s string
+	switch t := dv.(type) {
+	case nil:
+		return nil
+	case string:
+		s = t
+	case []byte:
+		s = `\x` + fmt.Sprintf("%x", t)
+	case time.Time:
+		s = t.Format("2006-01-02 15:04:05.999999999Z07:00")
+	case bool:
+		s = strconv.FormatBool(t)
+	case int64:
+		s = strconv.FormatInt(t, 10)
+	case float64:
+		s = strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Errorf("Composite does not support fields of type %T", v)
+	}
+	buf.WriteRune('"')
+	buf.WriteString(compositeEscaper.Replace(s))
+	buf.WriteRune('"')
+	return nil
+}
+
+// Scan implements sql.Scanner. The fields are scanned as strings.
+func (c *CompositeValue) Scan(src interface{}) error {
+	var s string
+	switch t := src.(type) {
+	case []byte:
+		s = string(t)
+	case string:
+		s = t
+	default:
+		return fmt.Errorf("Cannot scan %T into CompositeValue", src)
+	}
+
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return fmt.Errorf("Invalid composite %q", s)
+	}
+	fields, err := splitCompositeFields(s[1 : len(s)-1])
+	if err != nil {
+		return fmt.Errorf("Invalid composite %q: %v", s, err)
+	}
+	c.Fields = fields
+	return nil
+}
+
+// splitCompositeFields splits the text between the parentheses of a record
+// into its fields, unquoting them. An empty unquoted field is nil.
+func splitCompositeFields(s string) ([]interface{}, error) {
+	var fields []interface{}
+	var buf bytes.Buffer
+	quoted, inQuote := false, false
+	field := func() {
+		if buf.Len() > 0 || quoted {
+			fields = append(fields, buf.String())
+		} else {
+			fields = append(fields, nil)
+		}
+		buf.Reset()
+		quoted = false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(s) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			buf.WriteByte(s[i])
+		case c == '"' && inQuote && i+1 < len(s) && s[i+1] == '"':
+			// "" is a quote within a quoted field
+			i++
+			buf.WriteByte('"')
+		case c == '"':
+			quoted = true
+			inQuote = !inQuote
+		case c == ',' && !inQuote:
+			field()
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	field()
+	return fields, nil
+}
+
+// Decode assigns the scanned fields to the exported fields of the struct
+// pointed to by dest in declaration order, skipping those tagged db:"-".
+// Fields implementing sql.Scanner scan the text of the field, others
+// may be strings, numbers, bools, time.Time or pointers to them.
+func (c *CompositeValue) Decode(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Decode requires a pointer to a struct, got %T", dest)
+	}
+	v = v.Elem()
+	indexes := compositeFieldIndexes(v.Type())
+	if len(indexes) != len(c.Fields) {
+		return fmt.Errorf("Cannot decode %d composite fields into %d fields of %s", len(c.Fields), len(indexes), v.Type().Name())
+	}
+	for i, index := range indexes {
+		if err := assignCompositeField(v.Field(index), c.Fields[i]); err != nil {
+			return fmt.Errorf("Cannot decode composite field %d into %s.%s: %v", i+1, v.Type().Name(), v.Type().Field(index).Name, err)
+		}
+	}
+	return nil
+}
+
+// ScanComposite returns a sql.Scanner which scans a composite column and
+// decodes it into the struct pointed to by dest, see Decode.
+//
+//	var addr Address
+//	err := DB.Select("address").From("people").Where("id = $1", id).QueryScalar(dat.ScanComposite(&addr))
+func ScanComposite(dest interface{}) sql.Scanner {
+	return compositeScanner{dest: dest}
+}
+
+type compositeScanner struct {
+	dest interface{}
+}
+
+func (s compositeScanner) Scan(src interface{}) error {
+	if src == nil {
+		return fmt.Errorf("Cannot scan NULL into %T", s.dest)
+	}
+	var c CompositeValue
+	if err := c.Scan(src); err != nil {
+		return err
+	}
+	return c.Decode(s.dest)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// compositeTimeFormats are the formats of timestamps and dates in records.
+var compositeTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// assignCompositeField assigns the text of a composite field, nil for NULL,
+// to f.
+func assignCompositeField(f reflect.Value, src interface{}) error {
+	if scanner, ok := f.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+	if f.Kind() == reflect.Ptr {
+		if src == nil {
+			f.Set(reflect.Zero(f.Type()))
+			return nil
+		}
+		p := reflect.New(f.Type().Elem())
+		if err := assignCompositeField(p.Elem(), src); err != nil {
+			return err
+		}
+		f.Set(p)
+		return nil
+	}
+	if src == nil {
+		return fmt.Errorf("cannot assign NULL to %s", f.Type())
+	}
+
+	s := src.(string)
+	if f.Type() == timeType {
+		for _, layout := range compositeTimeFormats {
+			if t, err := time.Parse(layout, s); err == nil {
+				f.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid time %q", s)
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		f.SetBool(s == "t" || s == "true")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
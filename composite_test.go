@@ -0,0 +1,88 @@
+package dat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type compositeAddress struct {
+	Street string
+	City   *string
+	Zip    int
+	note   string
+	Skip   string `db:"-"`
+}
+
+func TestComposite(t *testing.T) {
+	c := Composite("address", "1 Main St", nil, 12345)
+	sql, args := Update("people").Set("address", c).Where("id = $1", 1).ToSQL()
+	assert.Equal(t, `UPDATE "people" SET "address" = $1 WHERE (id = $2)`, sql)
+	assert.Equal(t, []interface{}{c, 1}, args)
+
+	sql, args, err := Interpolate(sql, args)
+	assert.NoError(t, err)
+	assert.Equal(t, `UPDATE "people" SET "address" = ROW('1 Main St', NULL, 12345)::address WHERE (id = 1)`, sql)
+	assert.Empty(t, args)
+
+	city := "Springfield"
+	c = Composite("address", &compositeAddress{Street: "1 Main St", City: &city, Zip: 12345, note: "x", Skip: "y"})
+	assert.Equal(t, []interface{}{"1 Main St", &city, 12345}, c.Fields)
+
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	c = Composite("event", `say "hi" \o/`, "", nil, true, 1.5, at, []byte{1, 2})
+	v, err := c.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, `("say \"hi\" \\o/","",,"true","1.5","2020-01-02 03:04:05Z","\\x0102")`, v)
+
+	assert.Panics(t, func() { Composite("") })
+}
+
+func TestCompositeManyFields(t *testing.T) {
+	fields := make([]interface{}, maxLookup+1)
+	for i := range fields {
+		fields[i] = i
+	}
+	sql, args, err := Composite("wide", fields...).Expression()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sql, "ROW(0, 1, 2,"))
+	assert.True(t, strings.HasSuffix(sql, ", 99, 100)::wide"))
+	assert.Empty(t, args)
+}
+
+func TestCompositeScan(t *testing.T) {
+	var c CompositeValue
+	assert.NoError(t, c.Scan([]byte(`("1 Main St",,12345,"","say ""hi"" \\o/")`)))
+	assert.Equal(t, []interface{}{"1 Main St", nil, "12345", "", `say "hi" \o/`}, c.Fields)
+
+	assert.Error(t, c.Scan("1,2"))
+	assert.Error(t, c.Scan(`("a)`))
+	assert.Error(t, c.Scan(1))
+
+	var addr compositeAddress
+	assert.NoError(t, ScanComposite(&addr).Scan(`("1 Main St",Springfield,12345)`))
+	if assert.NotNil(t, addr.City) {
+		assert.Equal(t, "Springfield", *addr.City)
+	}
+	assert.Equal(t, "1 Main St", addr.Street)
+	assert.Equal(t, 12345, addr.Zip)
+
+	assert.NoError(t, ScanComposite(&addr).Scan(`(x,,1)`))
+	assert.Nil(t, addr.City)
+
+	assert.Error(t, ScanComposite(&addr).Scan(`(x,,y)`))
+	assert.Error(t, ScanComposite(&addr).Scan(`(x,y)`))
+	assert.Error(t, ScanComposite(&addr).Scan(nil))
+
+	var event struct {
+		At   time.Time
+		OK   bool
+		Note NullString
+	}
+	assert.NoError(t, ScanComposite(&event).Scan(`("2020-01-02 03:04:05+00",t,)`))
+	assert.True(t, event.At.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)))
+	assert.True(t, event.OK)
+	assert.False(t, event.Note.Valid)
+}
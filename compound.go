@@ -0,0 +1,52 @@
+package dat
+
+import "github.com/casualjim/dat/common"
+
+// compoundSelect is a query combined with a SELECT by a set operator.
+type compoundSelect struct {
+	operator string
+	query    Fragment
+}
+
+// Union combines the statement with query using UNION, removing duplicate
+// rows. OrderBy, Limit and Offset of the receiver apply to the combined
+// result.
+func (b *SelectBuilder) Union(query Fragment) *SelectBuilder {
+	return b.compound("UNION", query)
+}
+
+// UnionAll combines the statement with query using UNION ALL.
+func (b *SelectBuilder) UnionAll(query Fragment) *SelectBuilder {
+	return b.compound("UNION ALL", query)
+}
+
+// Intersect combines the statement with query using INTERSECT.
+func (b *SelectBuilder) Intersect(query Fragment) *SelectBuilder {
+	return b.compound("INTERSECT", query)
+}
+
+// Except combines the statement with query using EXCEPT.
+func (b *SelectBuilder) Except(query Fragment) *SelectBuilder {
+	return b.compound("EXCEPT", query)
+}
+
+func (b *SelectBuilder) compound(operator string, query Fragment) *SelectBuilder {
+	if query == nil {
+		panic(operator + " requires a query")
+	}
+	b.compounds = append(b.compounds, &compoundSelect{operator: operator, query: query})
+	return b
+}
+
+// writeCompoundsToSQL writes each combined query in parentheses so clauses
+// of a query bind to that query only.
+func writeCompoundsToSQL(buf common.BufferWriter, compounds []*compoundSelect, args *[]interface{}, pos *int64) {
+	for _, c := range compounds {
+		buf.WriteRune(' ')
+		buf.WriteString(c.operator)
+		buf.WriteString(" (")
+		sql, values := c.query.ToSQL()
+		Expr(sql, values...).WriteRelativeArgs(buf, args, pos)
+		buf.WriteRune(')')
+	}
+}
@@ -0,0 +1,46 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnionAll(t *testing.T) {
+	active := Select("id", "name").From("users").Where("state = $1", "active")
+	invited := Select("id", "name").From("invites").Where("org_id = $1", 10)
+
+	sql, args := active.UnionAll(invited).OrderBy("name").Limit(5).ToSQL()
+	assert.Equal(t, "(SELECT id, name FROM users WHERE (state = $1)) UNION ALL (SELECT id, name FROM invites WHERE (org_id = $2)) ORDER BY name LIMIT 5", sql)
+	assert.Exactly(t, []interface{}{"active", 10}, args)
+}
+
+func TestSetOperators(t *testing.T) {
+	a := func() *SelectBuilder { return Select("id").From("a").Where("x = $1", 1) }
+	b := Select("id").From("b").Where("y = $1", 2)
+	c := Select("id").From("c").Where("z = $1", 3).OrderBy("id").Limit(1)
+
+	sql, args := a().Union(b).Except(c).ToSQL()
+	assert.Equal(t, "(SELECT id FROM a WHERE (x = $1)) UNION (SELECT id FROM b WHERE (y = $2)) EXCEPT (SELECT id FROM c WHERE (z = $3) ORDER BY id LIMIT 1)", sql)
+	assert.Exactly(t, []interface{}{1, 2, 3}, args)
+
+	sql, _ = a().Intersect(SQL("SELECT id FROM d")).ToSQL()
+	assert.Equal(t, "(SELECT id FROM a WHERE (x = $1)) INTERSECT (SELECT id FROM d)", sql)
+}
+
+func TestUnionWith(t *testing.T) {
+	sql, args := Select("id").
+		From("recent").
+		With("recent", Select("id").From("posts").Where("created_at > $1", "2016-01-01")).
+		Where("id > $1", 5).
+		Union(Select("id").From("pinned").Where("user_id = $1", 1)).
+		ToSQL()
+	assert.Equal(t, "WITH recent AS (SELECT id FROM posts WHERE (created_at > $1)) (SELECT id FROM recent WHERE (id > $2)) UNION (SELECT id FROM pinned WHERE (user_id = $3))", sql)
+	assert.Exactly(t, []interface{}{"2016-01-01", 5, 1}, args)
+}
+
+func TestUnionForUpdate(t *testing.T) {
+	assert.Panics(t, func() {
+		Select("id").From("a").ForUpdate().Union(Select("id").From("b")).ToSQL()
+	})
+}
@@ -0,0 +1,162 @@
+package dat
+
+import "github.com/casualjim/dat/common"
+
+// ConflictBuilder builds the ON CONFLICT clause of an INSERT statement.
+// Methods not related to the conflict clause, such as Returning and the
+// Execer methods, are forwarded to the InsertBuilder.
+type ConflictBuilder struct {
+	*InsertBuilder
+
+	columns        []string
+	constraint     string
+	doUpdate       bool
+	updateAll      bool
+	updateExcept   []string
+	setClauses     []*setClause
+	whereFragments []*whereFragment
+}
+
+// OnConflict adds an ON CONFLICT clause with an optional list of columns as
+// the conflict target. The action defaults to DO NOTHING.
+func (b *InsertBuilder) OnConflict(columns ...string) *ConflictBuilder {
+	b.conflict = &ConflictBuilder{InsertBuilder: b, columns: columns}
+	return b.conflict
+}
+
+// OnConflictConstraint adds an ON CONFLICT ON CONSTRAINT clause using the
+// named constraint as the conflict target. The action defaults to DO NOTHING.
+func (b *InsertBuilder) OnConflictConstraint(constraint string) *ConflictBuilder {
+	b.conflict = &ConflictBuilder{InsertBuilder: b, constraint: constraint}
+	return b.conflict
+}
+
+// DoNothing sets the conflict action to DO NOTHING.
+func (b *ConflictBuilder) DoNothing() *ConflictBuilder {
+	b.doUpdate = false
+	b.updateAll = false
+	b.updateExcept = nil
+	b.setClauses = nil
+	b.whereFragments = nil
+	return b
+}
+
+// DoUpdate sets the conflict action to DO UPDATE. Use Set to assign columns,
+// the proposed row is available as the EXCLUDED table,
+// eg Set("count", dat.Expr("excluded.count")).
+func (b *ConflictBuilder) DoUpdate() *ConflictBuilder {
+	b.doUpdate = true
+	return b
+}
+
+// DoUpdateAllExcept sets the conflict action to DO UPDATE SET col =
+// excluded.col for each inserted column except the conflict target,
+// columns, eg "id" and "created_at", and those assigned with Set. Columns
+// are taken from Columns or the Record when ToSQL is called. If no column
+// is left to update the action is DO NOTHING.
+func (b *ConflictBuilder) DoUpdateAllExcept(columns ...string) *ConflictBuilder {
+	b.doUpdate = true
+	b.updateAll = true
+	b.updateExcept = columns
+	return b
+}
+
+// Set appends a column/value pair to DO UPDATE SET.
+func (b *ConflictBuilder) Set(column string, value interface{}) *ConflictBuilder {
+	b.doUpdate = true
+	b.setClauses = append(b.setClauses, &setClause{column: column, value: value})
+	return b
+}
+
+// SetMap appends the elements of the map as column/value pairs to DO UPDATE SET.
+func (b *ConflictBuilder) SetMap(clauses map[string]interface{}) *ConflictBuilder {
+	for col, val := range clauses {
+		b = b.Set(col, val)
+	}
+	return b
+}
+
+// Where appends a WHERE condition to DO UPDATE, rows not matching the
+// condition are left unchanged.
+func (b *ConflictBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *ConflictBuilder {
+	b.whereFragments = append(b.whereFragments, newWhereFragment(whereSQLOrMap, args))
+	return b
+}
+
+// Returning sets the columns for the RETURNING clause
+func (b *ConflictBuilder) Returning(columns ...string) *ConflictBuilder {
+	b.InsertBuilder.Returning(columns...)
+	return b
+}
+
+// ReturningInserted returns whether the row was inserted, see
+// InsertBuilder.ReturningInserted.
+func (b *ConflictBuilder) ReturningInserted(inserted *bool) *ConflictBuilder {
+	b.InsertBuilder.ReturningInserted(inserted)
+	return b
+}
+
+func (b *ConflictBuilder) writeSQL(buf common.BufferWriter, args *[]interface{}, pos *int64) {
+	buf.WriteString(" ON CONFLICT")
+	if b.constraint != "" {
+		buf.WriteString(" ON CONSTRAINT ")
+		Dialect.WriteIdentifier(buf, b.constraint)
+	} else if len(b.columns) > 0 {
+		buf.WriteString(" (")
+		for i, c := range b.columns {
+			if i > 0 {
+				buf.WriteRune(',')
+			}
+			Dialect.WriteIdentifier(buf, c)
+		}
+		buf.WriteRune(')')
+	}
+
+	setClauses := b.setClauses
+	if b.updateAll {
+		setClauses = append(setClauses[:len(setClauses):len(setClauses)], b.excludedSetClauses()...)
+	}
+	// every inserted column is excluded, there is nothing to update
+	if !b.doUpdate || b.updateAll && len(setClauses) == 0 {
+		buf.WriteString(" DO NOTHING")
+		return
+	}
+
+	if b.constraint == "" && len(b.columns) == 0 {
+		panic("DO UPDATE requires a conflict target")
+	}
+	if len(setClauses) == 0 {
+		panic("DO UPDATE requires set clauses")
+	}
+
+	buf.WriteString(" DO UPDATE SET ")
+	writeSetClauses(buf, setClauses, args, pos)
+
+	if len(b.whereFragments) > 0 {
+		buf.WriteString(" WHERE ")
+		writeAndFragmentsToSQL(buf, b.whereFragments, args, pos)
+	}
+}
+
+// excludedSetClauses assigns the proposed value to each inserted column
+// except the conflict target and those of DoUpdateAllExcept and Set.
+func (b *ConflictBuilder) excludedSetClauses() []*setClause {
+	skip := make(map[string]bool, len(b.columns)+len(b.updateExcept)+len(b.setClauses))
+	for _, c := range b.columns {
+		skip[c] = true
+	}
+	for _, c := range b.updateExcept {
+		skip[c] = true
+	}
+	for _, c := range b.setClauses {
+		skip[c.column] = true
+	}
+	var setClauses []*setClause
+	for _, c := range b.InsertBuilder.cols {
+		if skip[c] {
+			continue
+		}
+		setClauses = append(setClauses, &setClause{column: c, value: Expr("excluded." + QuoteIdent(c))})
+	}
+	return setClauses
+}
@@ -0,0 +1,119 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertOnConflictDoNothing(t *testing.T) {
+	sql, args := InsertInto("a").Columns("b", "c").Values(1, 2).OnConflict().ToSQL()
+
+	assert.Equal(t, quoteSQL("INSERT INTO a (%s,%s) VALUES ($1,$2) ON CONFLICT DO NOTHING", "b", "c"), sql)
+	assert.Equal(t, []interface{}{1, 2}, args)
+
+	sql, _ = InsertInto("a").Columns("b", "c").Values(1, 2).OnConflict("b").DoNothing().ToSQL()
+	assert.Equal(t, quoteSQL("INSERT INTO a (%s,%s) VALUES ($1,$2) ON CONFLICT (%s) DO NOTHING", "b", "c", "b"), sql)
+}
+
+func TestInsertOnConflictDoUpdate(t *testing.T) {
+	sql, args := InsertInto("a").
+		Columns("b", "c").
+		Values(1, 2).
+		OnConflict("b").
+		DoUpdate().
+		Set("c", Expr("excluded.c + $1", 10)).
+		Where("a.c < $1", 100).
+		Returning("b", "c").
+		ToSQL()
+
+	assert.Equal(t, quoteSQL("INSERT INTO a (%s,%s) VALUES ($1,$2) ON CONFLICT (%s) DO UPDATE SET %s = excluded.c + $3 WHERE (a.c < $4) RETURNING %s,%s", "b", "c", "b", "c", "b", "c"), sql)
+	assert.Equal(t, []interface{}{1, 2, 10, 100}, args)
+}
+
+func TestInsertOnConflictConstraint(t *testing.T) {
+	sql, args := InsertInto("a").
+		Columns("b", "c").
+		Values(1, 2).
+		OnConflictConstraint("a_b_key").
+		Set("c", 3).
+		ToSQL()
+
+	assert.Equal(t, quoteSQL("INSERT INTO a (%s,%s) VALUES ($1,$2) ON CONFLICT ON CONSTRAINT %s DO UPDATE SET %s = $3", "b", "c", "a_b_key", "c"), sql)
+	assert.Equal(t, []interface{}{1, 2, 3}, args)
+}
+
+func TestInsertOnConflictDoUpdateRequiresTarget(t *testing.T) {
+	assert.Panics(t, func() {
+		InsertInto("a").Columns("b").Values(1).OnConflict().Set("b", 2).ToSQL()
+	})
+	assert.Panics(t, func() {
+		InsertInto("a").Columns("b").Values(1).OnConflict("b").DoUpdate().ToSQL()
+	})
+}
+
+func TestInsertReturningInserted(t *testing.T) {
+	var inserted bool
+	sql, _ := InsertInto("a").
+		Columns("b", "c").
+		Values(1, 2).
+		OnConflict("b").
+		Set("c", Expr("excluded.c")).
+		Returning("b").
+		ReturningInserted(&inserted).
+		ToSQL()
+	assert.Equal(t, quoteSQL("INSERT INTO a (%s,%s) VALUES ($1,$2) ON CONFLICT (%s) DO UPDATE SET %s = excluded.c RETURNING %s,(xmax = 0) AS dat__inserted", "b", "c", "b", "c", "b"), sql)
+
+	b := InsertInto("a").Columns("b").Values(1).ReturningInserted(&inserted)
+	sql, _ = b.ToSQL()
+	assert.Equal(t, quoteSQL("INSERT INTO a (%s) VALUES ($1) RETURNING (xmax = 0) AS dat__inserted", "b"), sql)
+	assert.True(t, b.InsertedDest() == &inserted)
+}
+
+func TestInsertOnConflictDoUpdateAllExcept(t *testing.T) {
+	type User struct {
+		ID        int64  `db:"id"`
+		Email     string `db:"email"`
+		Name      string `db:"name"`
+		CreatedAt string `db:"created_at"`
+	}
+	u := &User{ID: 1, Email: "a@b.c", Name: "A", CreatedAt: "now"}
+	sql, args := InsertInto("users").
+		Columns("*").
+		Record(u).
+		OnConflict("email").
+		DoUpdateAllExcept("id", "created_at").
+		Set("name", Expr("coalesce(excluded.name, $1)", "anon")).
+		ToSQL()
+
+	assert.Equal(t, quoteSQL("INSERT INTO users (%s,%s,%s,%s) VALUES ($1,$2,$3,$4) ON CONFLICT (%s) DO UPDATE SET %s = coalesce(excluded.name, $5)",
+		"id", "email", "name", "created_at", "email", "name"), sql)
+	assert.Equal(t, []interface{}{int64(1), "a@b.c", "A", "now", "anon"}, args)
+
+	// nothing left to update
+	sql, args = InsertInto("users").Columns("id", "email").Values(1, "a@b.c").OnConflict("email").DoUpdateAllExcept("id").ToSQL()
+	assert.Equal(t, quoteSQL("INSERT INTO users (%s,%s) VALUES ($1,$2) ON CONFLICT (%s) DO NOTHING", "id", "email", "email"), sql)
+	assert.Equal(t, []interface{}{1, "a@b.c"}, args)
+}
+
+func TestUpsertOnConflict(t *testing.T) {
+	type User struct {
+		ID        int64  `db:"id"`
+		Email     string `db:"email"`
+		Name      string `db:"name"`
+		CreatedAt string `db:"created_at"`
+	}
+	u := &User{ID: 1, Email: "a@b.c", Name: "A", CreatedAt: "now"}
+	var inserted bool
+	b := Upsert("users").
+		Columns("*").
+		Record(u).
+		Returning("id").
+		OnConflict("email").
+		DoUpdateAllExcept("id", "created_at").
+		ReturningInserted(&inserted)
+	sql, args := b.ToSQL()
+	assert.Equal(t, quoteSQL("INSERT INTO users (%s,%s,%s,%s) VALUES ($1,$2,$3,$4) ON CONFLICT (%s) DO UPDATE SET %s = excluded.%s RETURNING %s,(xmax = 0) AS dat__inserted",
+		"id", "email", "name", "created_at", "email", "name", "name", "id"), sql)
+	assert.Equal(t, []interface{}{int64(1), "a@b.c", "A", "now"}, args)
+}
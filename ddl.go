@@ -0,0 +1,59 @@
+package dat
+
+import (
+	"regexp"
+	"strings"
+)
+
+// reDDLIdent matches a plain identifier or a properly double-quoted one.
+var reDDLIdent = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*|"([^"]|"")+")$`)
+
+// Identifier is an identifier, eg a table or partition name, which is
+// validated and quoted when interpolated. Qualify it with dots, eg
+// public.orders_2024_01. Each part must be a plain identifier,
+// [A-Za-z_][A-Za-z0-9_]*, or already double-quoted.
+type Identifier string
+
+// Expression implements Expressioner.
+func (id Identifier) Expression() (string, []interface{}, error) {
+	parts := strings.Split(string(id), ".")
+	for _, part := range parts {
+		if !reDDLIdent.MatchString(part) {
+			return "", nil, ErrInvalidIdentifier
+		}
+	}
+
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	for i, part := range parts {
+		if i > 0 {
+			buf.WriteRune('.')
+		}
+		if part[0] == '"' {
+			buf.WriteString(part)
+		} else {
+			Dialect.WriteIdentifier(buf, part)
+		}
+	}
+	return buf.String(), nil, nil
+}
+
+// DDL interpolates args into a DDL statement, which does not accept bind
+// parameters. Identifier args are validated and quoted, other args are
+// written as literals, eg
+//
+//	sql, err := dat.DDL(`CREATE TABLE $1 PARTITION OF orders FOR VALUES FROM ($2) TO ($3)`,
+//		dat.Identifier("orders_2024_01"), "2024-01-01", "2024-02-01")
+//
+// Returns ErrInvalidIdentifier for an invalid identifier and
+// ErrInvalidValue for an arg which cannot be written as a literal.
+func DDL(sql string, args ...interface{}) (string, error) {
+	s, remaining, err := Interpolate(sql, args)
+	if err != nil {
+		return "", err
+	}
+	if len(remaining) > 0 {
+		return "", ErrInvalidValue
+	}
+	return s, nil
+}
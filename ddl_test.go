@@ -0,0 +1,39 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDDL(t *testing.T) {
+	sql, err := DDL(`CREATE TABLE $1 PARTITION OF $2 FOR VALUES FROM ($3) TO ($4)`,
+		Identifier("orders_2024_01"), Identifier(`public."Orders"`), "2024-01-01", "2024-02-01")
+	assert.NoError(t, err)
+	assert.Equal(t, `CREATE TABLE "orders_2024_01" PARTITION OF "public"."Orders" FOR VALUES FROM ('2024-01-01') TO ('2024-02-01')`, sql)
+
+	sql, err = DDL(`ALTER TABLE $1 ADD CONSTRAINT $2 CHECK (qty > $3)`, Identifier("orders"), Identifier(`"qty ""positive"""`), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, `ALTER TABLE "orders" ADD CONSTRAINT "qty ""positive""" CHECK (qty > 0)`, sql)
+}
+
+func TestDDLInvalidIdentifier(t *testing.T) {
+	invalid := []string{
+		`orders; DROP TABLE users`,
+		`orders"`,
+		`"orders`,
+		`"a"b"`,
+		`1orders`,
+		``,
+		`a..b`,
+	}
+	for _, name := range invalid {
+		_, err := DDL(`CREATE TABLE $1 ()`, Identifier(name))
+		assert.Equal(t, ErrInvalidIdentifier, err, name)
+	}
+}
+
+func TestDDLInvalidValue(t *testing.T) {
+	_, err := DDL(`CREATE TABLE $1 (id int DEFAULT $2)`, Identifier("a"), JSON(`{}`))
+	assert.Equal(t, ErrInvalidValue, err)
+}
@@ -1,31 +1,50 @@
 package dat
 
+import "strings"
+
 // DeleteBuilder contains the clauses for a DELETE statement
 type DeleteBuilder struct {
 	Execer
 
 	table          string
+	usings         []string
 	whereFragments []*whereFragment
 	isInterpolated bool
+	arrayBinding   bool
 	scope          Scope
+	returnings     []string
 }
 
 // NewDeleteBuilder creates a new DeleteBuilder for the given table.
 func NewDeleteBuilder(table string) *DeleteBuilder {
 	if table == "" {
-		logger.Error("DeleteFrom requires a table name.")
+		logger().Error("DeleteFrom requires a table name.")
 		return nil
 	}
 	return &DeleteBuilder{table: table, isInterpolated: EnableInterpolation}
 }
 
-// ScopeMap uses a predefined scope in place of WHERE.
+// ScopeMap uses a predefined scope whose WHERE condition is AND-ed with
+// any Where conditions.
 func (b *DeleteBuilder) ScopeMap(mapScope *MapScope, m M) *DeleteBuilder {
 	b.scope = mapScope.mergeClone(m)
 	return b
 }
 
-// Scope uses a predefined scope in place of WHERE.
+// UseScope uses scope, which may be shared across builders, like ScopeMap.
+func (b *DeleteBuilder) UseScope(scope Scope) *DeleteBuilder {
+	b.scope = scope
+	return b
+}
+
+// Unscoped removes any scope from the statement, e.g. to include soft
+// deleted rows.
+func (b *DeleteBuilder) Unscoped() *DeleteBuilder {
+	b.scope = nil
+	return b
+}
+
+// Scope uses a predefined scope like ScopeMap.
 func (b *DeleteBuilder) Scope(sql string, args ...interface{}) *DeleteBuilder {
 	b.scope = ScopeFunc(func(table string) (string, []interface{}) {
 		return escapeScopeTable(sql, table), args
@@ -33,6 +52,16 @@ func (b *DeleteBuilder) Scope(sql string, args ...interface{}) *DeleteBuilder {
 	return b
 }
 
+// Using appends tables to the USING clause, which may be referenced in WHERE
+// to delete rows based on another table.
+//
+//	DeleteFrom("comments").Using("posts").
+//		Where("comments.post_id = posts.id AND posts.state = $1", "spam")
+func (b *DeleteBuilder) Using(tables ...string) *DeleteBuilder {
+	b.usings = append(b.usings, tables...)
+	return b
+}
+
 // Where appends a WHERE clause to the statement whereSQLOrMap can be a
 // string or map. If it's a string, args wil replaces any places holders
 func (b *DeleteBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *DeleteBuilder {
@@ -40,6 +69,34 @@ func (b *DeleteBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *D
 	return b
 }
 
+// WhereIf appends a WHERE clause only if cond is true, eg for an optional
+// filter. The args are evaluated either way.
+func (b *DeleteBuilder) WhereIf(cond bool, whereSQLOrMap interface{}, args ...interface{}) *DeleteBuilder {
+	if cond {
+		b.Where(whereSQLOrMap, args...)
+	}
+	return b
+}
+
+// Filter appends the conditions of f to the WHERE clause.
+func (b *DeleteBuilder) Filter(f *FilterBuilder) *DeleteBuilder {
+	b.whereFragments = filterFragments(b.whereFragments, f)
+	return b
+}
+
+// UseArrayBinding binds slices as a single Postgres array when enabled,
+// see SelectBuilder.UseArrayBinding.
+func (b *DeleteBuilder) UseArrayBinding(enable bool) *DeleteBuilder {
+	b.arrayBinding = enable
+	return b
+}
+
+// Returning sets the columns for the RETURNING clause
+func (b *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
+	b.returnings = columns
+	return b
+}
+
 // ToSQL serialized the DeleteBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *DeleteBuilder) ToSQL() (string, []interface{}) {
@@ -53,19 +110,38 @@ func (b *DeleteBuilder) ToSQL() (string, []interface{}) {
 	var args []interface{}
 
 	buf.WriteString("DELETE FROM ")
-	buf.WriteString(b.table)
+	writeIdentOrSQL(buf, b.table)
+
+	for i, table := range b.usings {
+		if i == 0 {
+			buf.WriteString(" USING ")
+		} else {
+			buf.WriteString(", ")
+		}
+		writeIdentOrSQL(buf, table)
+	}
 
 	var placeholderStartPos int64 = 1
 
 	// Write WHERE clause if we have any fragments
-	if b.scope == nil {
-		if len(b.whereFragments) > 0 {
-			buf.WriteString(" WHERE ")
-			writeAndFragmentsToSQL(buf, b.whereFragments, &args, &placeholderStartPos)
+	whereFragments := writeScope(buf, b.scope, b.table, b.whereFragments)
+	if b.arrayBinding {
+		whereFragments = arrayBindFragments(whereFragments)
+	}
+	if len(whereFragments) > 0 {
+		buf.WriteString(" WHERE ")
+		writeAndFragmentsToSQL(buf, whereFragments, &args, &placeholderStartPos)
+	}
+
+	// Go thru the returning clauses
+	for i, c := range b.returnings {
+		if i == 0 {
+			buf.WriteString(" RETURNING ")
+		} else {
+			buf.WriteRune(',')
 		}
-	} else {
-		whereFragment := newWhereFragment(b.scope.ToSQL(b.table))
-		writeScopeCondition(buf, whereFragment, &args, &placeholderStartPos)
+		// qualified columns disambiguate USING tables, eg comments.id
+		writeIdentParts(buf, strings.Split(c, "."))
 	}
 
 	return buf.String(), args
@@ -33,9 +33,29 @@ func TestDeleteTenStaringFromTwentyToSql(t *testing.T) {
 	assert.Equal(t, sql, "DELETE FROM a")
 }
 
+func TestDeleteReturning(t *testing.T) {
+	sql, args := DeleteFrom("a").Where("b = $1", 1).Returning("id", "c").ToSQL()
+	assert.Equal(t, `DELETE FROM a WHERE (b = $1) RETURNING "id","c"`, sql)
+	assert.Exactly(t, []interface{}{1}, args)
+
+	sql, _ = DeleteFrom("a").Returning("*").ToSQL()
+	assert.Equal(t, "DELETE FROM a RETURNING *", sql)
+}
+
 func TestDeleteWhereExprSql(t *testing.T) {
 	expr := Expr("id=$1", 100)
 	sql, args := DeleteFrom("a").Where("foo = $1", "bar").Where(expr).ToSQL()
 	assert.Equal(t, sql, `DELETE FROM a WHERE (foo = $1) AND (id=$2)`)
 	assert.Exactly(t, args, []interface{}{"bar", 100})
 }
+
+func TestDeleteUsing(t *testing.T) {
+	sql, args := DeleteFrom("comments").
+		Using("posts", "users u").
+		Where("comments.post_id = posts.id AND posts.user_id = u.id").
+		Where("u.banned = $1", true).
+		Returning("comments.id", "u.*").
+		ToSQL()
+	assert.Equal(t, `DELETE FROM comments USING posts, users u WHERE (comments.post_id = posts.id AND posts.user_id = u.id) AND (u.banned = $1) RETURNING "comments"."id","u".*`, sql)
+	assert.Exactly(t, []interface{}{true}, args)
+}
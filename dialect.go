@@ -18,3 +18,43 @@ type SQLDialect interface {
 	// WriteFormattedTime writes a time formatted for the database
 	WriteFormattedTime(buf common.BufferWriter, t time.Time)
 }
+
+// SetDialect sets the active SQLDialect, eg
+//
+//	dat.SetDialect(cockroach.New())
+//
+// Call it when the application starts, before any builder is used.
+func SetDialect(dialect SQLDialect) {
+	Dialect = dialect
+}
+
+// AsOfSystemTimeWriter is implemented by dialects supporting historical
+// reads with AS OF SYSTEM TIME.
+type AsOfSystemTimeWriter interface {
+	// WriteAsOfSystemTime writes the AS OF SYSTEM TIME clause for expr.
+	WriteAsOfSystemTime(buf common.BufferWriter, expr string)
+}
+
+// BytesWriter is implemented by dialects which interpolate binary values.
+// Otherwise []byte values are passed through as arguments.
+type BytesWriter interface {
+	// WriteBytesLiteral writes a binary literal for b.
+	WriteBytesLiteral(buf common.BufferWriter, b []byte)
+}
+
+// SavepointNamer is implemented by dialects which require specific
+// savepoint names for nested transactions.
+type SavepointNamer interface {
+	// SavepointName returns the name of the savepoint at depth, starting
+	// at 1 for the first nested transaction.
+	SavepointName(depth int) string
+}
+
+// PlaceholderWriter is implemented by dialects whose bind placeholders are
+// not Postgres' $1, $2 ... Builders always write $N placeholders, which the
+// ToSQL function rewrites in order of appearance, repeating args referenced
+// more than once.
+type PlaceholderWriter interface {
+	// WritePlaceholder writes the placeholder of the n-th arg, starting at 1.
+	WritePlaceholder(buf common.BufferWriter, n int)
+}
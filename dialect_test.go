@@ -0,0 +1,79 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/casualjim/dat/cockroach"
+	"github.com/casualjim/dat/mysql"
+	"github.com/casualjim/dat/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDialect(t *testing.T) {
+	SetDialect(cockroach.New())
+	defer SetDialect(postgres.New())
+
+	namer, ok := Dialect.(SavepointNamer)
+	assert.True(t, ok)
+	assert.Equal(t, "sp_1", namer.SavepointName(1))
+	assert.Equal(t, "sp_2", namer.SavepointName(2))
+
+	sql, _ := Update("order").Set("state", "paid").ToSQL()
+	assert.Equal(t, `UPDATE "order" SET "state" = $1`, sql)
+
+	var pg SQLDialect = postgres.New()
+	_, ok = pg.(SavepointNamer)
+	assert.False(t, ok)
+}
+
+func TestAsOfSystemTime(t *testing.T) {
+	b := Select("id").
+		From("events e INNER JOIN users u ON (u.id = e.user_id)").
+		AsOfSystemTime("'-10s'").
+		Where("u.id = $1", 1)
+
+	assert.PanicsWithValue(t, ErrAsOfSystemTimeUnsupported, func() { b.ToSQL() })
+	_, _, err := ToSQL(b)
+	assert.Equal(t, ErrAsOfSystemTimeUnsupported, err)
+	_, _, err = b.Interpolate()
+	assert.Equal(t, ErrAsOfSystemTimeUnsupported, err)
+
+	SetDialect(cockroach.New())
+	defer SetDialect(postgres.New())
+	sql, args := b.ToSQL()
+	assert.Equal(t, "SELECT id FROM events e INNER JOIN users u ON (u.id = e.user_id) AS OF SYSTEM TIME '-10s' WHERE (u.id = $1)", sql)
+	assert.Exactly(t, []interface{}{1}, args)
+}
+
+func TestPlaceholderWriter(t *testing.T) {
+	SetDialect(mysql.New())
+	defer SetDialect(postgres.New())
+
+	b := Select("id", "name").
+		From("people").
+		Where("name = $1 OR nick = $1", "it's").
+		Where(Eq{"age": 42}).
+		Where("tag = $1", "x")
+	sql, args, err := ToSQL(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id, name FROM people WHERE (name = ? OR nick = ?) AND (`age` = ?) AND (tag = ?)", sql)
+	assert.Equal(t, []interface{}{"it's", "it's", 42, "x"}, args)
+
+	// builders compose with $N
+	sql, _ = b.ToSQL()
+	assert.Equal(t, "SELECT id, name FROM people WHERE (name = $1 OR nick = $1) AND (`age` = $2) AND (tag = $3)", sql)
+
+	// placeholders in quotes and comments are kept
+	sql, args, err = ToSQL(SQL("SELECT '$1', \"$1\", $1 -- $1\n", 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '$1', \"$1\", ? -- $1\n", sql)
+	assert.Equal(t, []interface{}{1}, args)
+
+	sql, args, err = ToSQL(InsertInto("people").Columns("name", "at").Values(`a\b`, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO people (`name`,`at`) VALUES (?,?)", sql)
+	assert.Equal(t, []interface{}{`a\b`, 1}, args)
+
+	_, _, err = ToSQL(SQL("SELECT $2", 1))
+	assert.Equal(t, ErrArgumentMismatch, err)
+}
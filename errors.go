@@ -1,12 +1,26 @@
 package dat
 
 import (
+	"database/sql"
 	"errors"
 )
 
+// notFoundError is no rows found, wrapping sql.ErrNoRows.
+type notFoundError struct{}
+
+func (notFoundError) Error() string {
+	return "not found"
+}
+
+// Unwrap returns sql.ErrNoRows so errors.Is(err, sql.ErrNoRows) holds.
+func (notFoundError) Unwrap() error {
+	return sql.ErrNoRows
+}
+
 var (
-	// ErrNotFound ...
-	ErrNotFound = errors.New("not found")
+	// ErrNotFound occurs when a query expected to return a row returns
+	// none. The runner returns it in place of sql.ErrNoRows, which it wraps.
+	ErrNotFound error = notFoundError{}
 	// ErrNotUTF8 ...
 	ErrNotUTF8 = errors.New("invalid UTF-8")
 	// ErrInvalidSliceLength ...
@@ -22,4 +36,23 @@ var (
 	// ErrInvalidOperation occurs when an invalid operation occurs like cancelling
 	// an operation without a procPID.
 	ErrInvalidOperation = errors.New("invalid operation")
+	// ErrTooManyParameters occurs when a statement has more bind parameters
+	// than Postgres allows, see MaxParameters.
+	ErrTooManyParameters = errors.New("statement exceeds the maximum of 65535 bind parameters, insert the records in batches")
+	// ErrAsOfSystemTimeUnsupported occurs when AS OF SYSTEM TIME is used
+	// with a dialect which does not support it.
+	ErrAsOfSystemTimeUnsupported = errors.New("AS OF SYSTEM TIME is not supported by the dialect")
+	// ErrRowsAffectedUnsupported occurs when the driver does not report the
+	// number of rows affected by a statement.
+	ErrRowsAffectedUnsupported = errors.New("driver does not report rows affected")
+	// ErrInvalidIdentifier occurs when an Identifier is neither a plain nor
+	// a properly quoted identifier.
+	ErrInvalidIdentifier = errors.New("invalid identifier")
+	// ErrStaleObject occurs when an optimistically locked update affects no
+	// rows because the version changed, see UpdateBuilder.OptimisticLock.
+	ErrStaleObject = errors.New("stale object, the row was changed or deleted")
 )
+
+// MaxParameters is the maximum number of bind parameters Postgres accepts
+// in a single statement.
+const MaxParameters = 65535
@@ -0,0 +1,16 @@
+package dat
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrNotFound(t *testing.T) {
+	assert.True(t, errors.Is(ErrNotFound, sql.ErrNoRows))
+	assert.True(t, errors.Is(NewQueryError(ErrNotFound, "SELECT 1", nil), ErrNotFound))
+	assert.False(t, errors.Is(sql.ErrNoRows, ErrNotFound))
+	assert.Equal(t, "not found", ErrNotFound.Error())
+}
@@ -1,27 +1,89 @@
 package dat
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
 
 // Result serves the same purpose as sql.Result. Defining
 // it for the package avoids tight coupling with database/sql.
+// RowsAffected is set by Exec for every builder, Exec returns
+// ErrRowsAffectedUnsupported rather than 0 if the driver does not report it.
 type Result struct {
 	LastInsertID int64
 	RowsAffected int64
 }
 
+// Rows streams the rows of a query, see Execer.Iterate.
+type Rows interface {
+	// Next prepares the next row for Scan, returning false when done.
+	Next() bool
+	// Scan scans the current row into a struct pointer or into one
+	// destination per column.
+	Scan(dest ...interface{}) error
+	// Err returns the error which ended the iteration.
+	Err() error
+	// Close closes the rows.
+	Close() error
+}
+
 // Execer is any object that executes and queries SQL.
 type Execer interface {
 	Cache(id string, ttl time.Duration, invalidate bool) Execer
+	CacheTags(tags ...string) Execer
 	Timeout(time.Duration) Execer
+	Idempotent() Execer
+	ExpectRows(n int) Execer
 	Interpolate() (string, []interface{}, error)
 	Exec() (*Result, error)
+	ExecRows() (int64, error)
 
 	QueryScalar(destinations ...interface{}) error
+	QueryInt64() (int64, error)
+	QueryFloat64() (float64, error)
+	QueryString() (string, error)
+	QueryBool() (bool, error)
+	QueryInt64Ptr() (*int64, error)
+	QueryFloat64Ptr() (*float64, error)
+	QueryStringPtr() (*string, error)
+	QueryBoolPtr() (*bool, error)
 	QuerySlice(dest interface{}) error
 	QueryStruct(dest interface{}) error
+	QueryStructOrNil(dest interface{}) error
 	QueryStructs(dest interface{}) error
 	QueryObject(dest interface{}) error
 	QueryJSON() ([]byte, error)
+	QueryJSONAgg(dest *json.RawMessage) error
+	QueryMap(dest *map[string]interface{}) error
+	QueryMaps(dest *[]map[string]interface{}) error
+	Iterate() (Rows, error)
+	Explain(analyze bool) (string, error)
+	ExplainJSON(analyze bool) ([]byte, error)
+
+	ExecContext(ctx context.Context) (*Result, error)
+	ExecRowsContext(ctx context.Context) (int64, error)
+	QueryScalarContext(ctx context.Context, destinations ...interface{}) error
+	QueryInt64Context(ctx context.Context) (int64, error)
+	QueryFloat64Context(ctx context.Context) (float64, error)
+	QueryStringContext(ctx context.Context) (string, error)
+	QueryBoolContext(ctx context.Context) (bool, error)
+	QueryInt64PtrContext(ctx context.Context) (*int64, error)
+	QueryFloat64PtrContext(ctx context.Context) (*float64, error)
+	QueryStringPtrContext(ctx context.Context) (*string, error)
+	QueryBoolPtrContext(ctx context.Context) (*bool, error)
+	QuerySliceContext(ctx context.Context, dest interface{}) error
+	QueryStructContext(ctx context.Context, dest interface{}) error
+	QueryStructOrNilContext(ctx context.Context, dest interface{}) error
+	QueryStructsContext(ctx context.Context, dest interface{}) error
+	QueryObjectContext(ctx context.Context, dest interface{}) error
+	QueryJSONContext(ctx context.Context) ([]byte, error)
+	QueryJSONAggContext(ctx context.Context, dest *json.RawMessage) error
+	QueryMapContext(ctx context.Context, dest *map[string]interface{}) error
+	QueryMapsContext(ctx context.Context, dest *[]map[string]interface{}) error
+	IterateContext(ctx context.Context) (Rows, error)
+	ExplainContext(ctx context.Context, analyze bool) (string, error)
+	ExplainJSONContext(ctx context.Context, analyze bool) ([]byte, error)
 }
 
 const panicExecerMsg = "dat builders are disconnected, use sqlx-runner package"
@@ -38,15 +100,38 @@ func (nop *panicExecer) Cache(id string, ttl time.Duration, invalidate bool) Exe
 	panic(panicExecerMsg)
 }
 
+func (nop *panicExecer) CacheTags(tags ...string) Execer {
+	panic(panicExecerMsg)
+}
+
 func (nop *panicExecer) Timeout(time.Duration) Execer {
 	panic(panicExecerMsg)
 }
 
+func (nop *panicExecer) Idempotent() Execer {
+	panic(panicExecerMsg)
+}
+
+// ExpectRows panics when ExpectRows is called.
+func (nop *panicExecer) ExpectRows(n int) Execer {
+	panic(panicExecerMsg)
+}
+
 // Exec panics when Exec is called.
 func (nop *panicExecer) Exec() (*Result, error) {
 	panic(panicExecerMsg)
 }
 
+// ExecRows panics when ExecRows is called.
+func (nop *panicExecer) ExecRows() (int64, error) {
+	panic(panicExecerMsg)
+}
+
+// ExecRowsContext panics when ExecRowsContext is called.
+func (nop *panicExecer) ExecRowsContext(ctx context.Context) (int64, error) {
+	panic(panicExecerMsg)
+}
+
 func (nop *panicExecer) Interpolate() (string, []interface{}, error) {
 	panic(panicExecerMsg)
 }
@@ -56,6 +141,46 @@ func (nop *panicExecer) QueryScalar(destinations ...interface{}) error {
 	panic(panicExecerMsg)
 }
 
+// QueryInt64 panics when QueryInt64 is called.
+func (nop *panicExecer) QueryInt64() (int64, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryInt64Ptr panics when QueryInt64Ptr is called.
+func (nop *panicExecer) QueryInt64Ptr() (*int64, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryFloat64 panics when QueryFloat64 is called.
+func (nop *panicExecer) QueryFloat64() (float64, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryFloat64Ptr panics when QueryFloat64Ptr is called.
+func (nop *panicExecer) QueryFloat64Ptr() (*float64, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryString panics when QueryString is called.
+func (nop *panicExecer) QueryString() (string, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryStringPtr panics when QueryStringPtr is called.
+func (nop *panicExecer) QueryStringPtr() (*string, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryBool panics when QueryBool is called.
+func (nop *panicExecer) QueryBool() (bool, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryBoolPtr panics when QueryBoolPtr is called.
+func (nop *panicExecer) QueryBoolPtr() (*bool, error) {
+	panic(panicExecerMsg)
+}
+
 // QuerySlice panics when QuerySlice is called.
 func (nop *panicExecer) QuerySlice(dest interface{}) error {
 	panic(panicExecerMsg)
@@ -66,6 +191,11 @@ func (nop *panicExecer) QueryStruct(dest interface{}) error {
 	panic(panicExecerMsg)
 }
 
+// QueryStructOrNil panics when QueryStructOrNil is called.
+func (nop *panicExecer) QueryStructOrNil(dest interface{}) error {
+	panic(panicExecerMsg)
+}
+
 // QueryStructs panics when QueryStructs is called.
 func (nop *panicExecer) QueryStructs(dest interface{}) error {
 	panic(panicExecerMsg)
@@ -80,3 +210,143 @@ func (nop *panicExecer) QueryObject(dest interface{}) error {
 func (nop *panicExecer) QueryJSON() ([]byte, error) {
 	panic(panicExecerMsg)
 }
+
+// QueryJSONAgg panics when QueryJSONAgg is called.
+func (nop *panicExecer) QueryJSONAgg(dest *json.RawMessage) error {
+	panic(panicExecerMsg)
+}
+
+// QueryMap panics when QueryMap is called.
+func (nop *panicExecer) QueryMap(dest *map[string]interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// QueryMaps panics when QueryMaps is called.
+func (nop *panicExecer) QueryMaps(dest *[]map[string]interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// ExecContext panics when ExecContext is called.
+func (nop *panicExecer) ExecContext(ctx context.Context) (*Result, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryScalarContext panics when QueryScalarContext is called.
+func (nop *panicExecer) QueryScalarContext(ctx context.Context, destinations ...interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// QueryInt64Context panics when QueryInt64Context is called.
+func (nop *panicExecer) QueryInt64Context(ctx context.Context) (int64, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryInt64PtrContext panics when QueryInt64PtrContext is called.
+func (nop *panicExecer) QueryInt64PtrContext(ctx context.Context) (*int64, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryFloat64Context panics when QueryFloat64Context is called.
+func (nop *panicExecer) QueryFloat64Context(ctx context.Context) (float64, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryFloat64PtrContext panics when QueryFloat64PtrContext is called.
+func (nop *panicExecer) QueryFloat64PtrContext(ctx context.Context) (*float64, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryStringContext panics when QueryStringContext is called.
+func (nop *panicExecer) QueryStringContext(ctx context.Context) (string, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryStringPtrContext panics when QueryStringPtrContext is called.
+func (nop *panicExecer) QueryStringPtrContext(ctx context.Context) (*string, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryBoolContext panics when QueryBoolContext is called.
+func (nop *panicExecer) QueryBoolContext(ctx context.Context) (bool, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryBoolPtrContext panics when QueryBoolPtrContext is called.
+func (nop *panicExecer) QueryBoolPtrContext(ctx context.Context) (*bool, error) {
+	panic(panicExecerMsg)
+}
+
+// QuerySliceContext panics when QuerySliceContext is called.
+func (nop *panicExecer) QuerySliceContext(ctx context.Context, dest interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// QueryStructContext panics when QueryStructContext is called.
+func (nop *panicExecer) QueryStructContext(ctx context.Context, dest interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// QueryStructOrNilContext panics when QueryStructOrNilContext is called.
+func (nop *panicExecer) QueryStructOrNilContext(ctx context.Context, dest interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// QueryStructsContext panics when QueryStructsContext is called.
+func (nop *panicExecer) QueryStructsContext(ctx context.Context, dest interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// QueryObjectContext panics when QueryObjectContext is called.
+func (nop *panicExecer) QueryObjectContext(ctx context.Context, dest interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// QueryJSONContext panics when QueryJSONContext is called.
+func (nop *panicExecer) QueryJSONContext(ctx context.Context) ([]byte, error) {
+	panic(panicExecerMsg)
+}
+
+// QueryJSONAggContext panics when QueryJSONAggContext is called.
+func (nop *panicExecer) QueryJSONAggContext(ctx context.Context, dest *json.RawMessage) error {
+	panic(panicExecerMsg)
+}
+
+// QueryMapContext panics when QueryMapContext is called.
+func (nop *panicExecer) QueryMapContext(ctx context.Context, dest *map[string]interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// QueryMapsContext panics when QueryMapsContext is called.
+func (nop *panicExecer) QueryMapsContext(ctx context.Context, dest *[]map[string]interface{}) error {
+	panic(panicExecerMsg)
+}
+
+// Iterate panics when Iterate is called.
+func (nop *panicExecer) Iterate() (Rows, error) {
+	panic(panicExecerMsg)
+}
+
+// IterateContext panics when IterateContext is called.
+func (nop *panicExecer) IterateContext(ctx context.Context) (Rows, error) {
+	panic(panicExecerMsg)
+}
+
+// Explain panics when Explain is called.
+func (nop *panicExecer) Explain(analyze bool) (string, error) {
+	panic(panicExecerMsg)
+}
+
+// ExplainJSON panics when ExplainJSON is called.
+func (nop *panicExecer) ExplainJSON(analyze bool) ([]byte, error) {
+	panic(panicExecerMsg)
+}
+
+// ExplainContext panics when ExplainContext is called.
+func (nop *panicExecer) ExplainContext(ctx context.Context, analyze bool) (string, error) {
+	panic(panicExecerMsg)
+}
+
+// ExplainJSONContext panics when ExplainJSONContext is called.
+func (nop *panicExecer) ExplainJSONContext(ctx context.Context, analyze bool) ([]byte, error) {
+	panic(panicExecerMsg)
+}
@@ -0,0 +1,56 @@
+package dat
+
+// FilterBuilder accumulates WHERE conditions, eg from the optional params of
+// a request, to apply to a builder with Filter.
+type FilterBuilder struct {
+	whereFragments []*whereFragment
+}
+
+// NewFilter creates an empty FilterBuilder.
+func NewFilter() *FilterBuilder {
+	return &FilterBuilder{}
+}
+
+// Where appends a condition for the given string and args or map of
+// column/value pairs.
+func (f *FilterBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *FilterBuilder {
+	f.whereFragments = append(f.whereFragments, newWhereFragment(whereSQLOrMap, args))
+	return f
+}
+
+// WhereIf appends a condition only if cond is true. The args are evaluated
+// either way.
+func (f *FilterBuilder) WhereIf(cond bool, whereSQLOrMap interface{}, args ...interface{}) *FilterBuilder {
+	if cond {
+		f.Where(whereSQLOrMap, args...)
+	}
+	return f
+}
+
+// Len returns the number of conditions.
+func (f *FilterBuilder) Len() int {
+	return len(f.whereFragments)
+}
+
+// ToSQL ANDs the conditions so the filter can be nested in Or. An empty
+// filter produces TRUE.
+func (f *FilterBuilder) ToSQL() (string, []interface{}) {
+	if len(f.whereFragments) == 0 {
+		return "TRUE", nil
+	}
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+
+	var args []interface{}
+	var pos int64 = 1
+	writeAndFragmentsToSQL(buf, f.whereFragments, &args, &pos)
+	return buf.String(), args
+}
+
+// filterFragments returns the conditions of f for a builder's WHERE.
+func filterFragments(whereFragments []*whereFragment, f *FilterBuilder) []*whereFragment {
+	if f == nil {
+		return whereFragments
+	}
+	return append(whereFragments, f.whereFragments...)
+}
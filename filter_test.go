@@ -0,0 +1,46 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhereIf(t *testing.T) {
+	sql, args := Select("a").From("b").
+		WhereIf(true, "c = $1", 1).
+		WhereIf(false, "d = $1", 2).
+		WhereIf(true, Eq{"e": 3}).
+		ToSQL()
+	assert.Equal(t, `SELECT a FROM b WHERE (c = $1) AND ("e" = $2)`, sql)
+	assert.Equal(t, []interface{}{1, 3}, args)
+
+	sql, args = Update("a").Set("b", 1).WhereIf(false, "c = $1", 2).ToSQL()
+	assert.Equal(t, `UPDATE "a" SET "b" = $1`, sql)
+	assert.Equal(t, []interface{}{1}, args)
+}
+
+func TestFilter(t *testing.T) {
+	status, orgID, query := "active", int64(0), "mario"
+	f := NewFilter().
+		WhereIf(status != "", "status = $1", status).
+		WhereIf(orgID != 0, Eq{"org_id": orgID}).
+		WhereIf(query != "", "name ILIKE $1 OR email ILIKE $1", "%"+query+"%")
+	assert.Equal(t, 2, f.Len())
+
+	sql, args := Select("a").From("b").Where("c = $1", 1).Filter(f).ToSQL()
+	assert.Equal(t, "SELECT a FROM b WHERE (c = $1) AND (status = $2) AND (name ILIKE $3 OR email ILIKE $3)", sql)
+	assert.Equal(t, []interface{}{1, "active", "%mario%"}, args)
+
+	sql, args = DeleteFrom("b").Filter(f).ToSQL()
+	assert.Equal(t, "DELETE FROM b WHERE (status = $1) AND (name ILIKE $2 OR email ILIKE $2)", sql)
+	assert.Equal(t, []interface{}{"active", "%mario%"}, args)
+
+	sql, args = Select("a").From("b").Where(Or(f, "admin")).ToSQL()
+	assert.Equal(t, "SELECT a FROM b WHERE (((status = $1) AND (name ILIKE $2 OR email ILIKE $2)) OR (admin))", sql)
+	assert.Equal(t, []interface{}{"active", "%mario%"}, args)
+
+	sql, args = Select("a").From("b").Filter(NewFilter()).ToSQL()
+	assert.Equal(t, "SELECT a FROM b", sql)
+	assert.Empty(t, args)
+}
@@ -1,15 +1,16 @@
 module github.com/casualjim/dat
 
-go 1.12
+go 1.15
 
 require (
 	github.com/MichaelTJones/walk v0.0.0-20161122175330-4748e29d5718 // indirect
 	github.com/cenkalti/backoff v2.1.1+incompatible
 	github.com/garyburd/redigo v1.6.0
 	github.com/howeyc/gopass v0.0.0-20170109162249-bf9dde6d0d2c // indirect
+	github.com/jackc/pgconn v1.6.1
+	github.com/jackc/pgx/v4 v4.7.0
 	github.com/jmoiron/sqlx v1.2.0
-	github.com/lib/pq v1.1.1
-	github.com/mattn/go-colorable v0.1.1 // indirect
+	github.com/lib/pq v1.3.0
 	github.com/mgutz/ansi v0.0.0-20170206155736-9520e82c474b
 	github.com/mgutz/jo v1.1.0
 	github.com/mgutz/minimist v0.0.0-20151219120022-39eb8cf573ca // indirect
@@ -18,10 +19,7 @@ require (
 	github.com/nozzle/throttler v0.0.0-20180817012639-2ea982251481 // indirect
 	github.com/pmylund/go-cache v2.1.0+incompatible
 	github.com/satori/go.uuid v1.2.0
-	github.com/stretchr/testify v1.3.0
-	go.uber.org/atomic v1.4.0 // indirect
-	go.uber.org/multierr v1.1.0 // indirect
+	github.com/stretchr/testify v1.5.1
 	go.uber.org/zap v1.10.0
-	golang.org/x/crypto v0.0.0-20190426145343-a29dc8fdc734 // indirect
 	gopkg.in/godo.v2 v2.0.9
 )
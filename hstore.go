@@ -0,0 +1,96 @@
+package dat
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq/hstore"
+)
+
+// Hstore is a map bound to and scanned from a Postgres hstore column, eg
+//
+//	Set("meta", dat.Hstore(map[string]string{"color": "red"}))
+//
+// NULL values of scanned keys become empty strings.
+type Hstore map[string]string
+
+var hstoreReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// Value implements driver.Valuer, writing keys in sorted order.
+func (h Hstore) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		buf.WriteRune('"')
+		buf.WriteString(hstoreReplacer.Replace(k))
+		buf.WriteString(`"=>"`)
+		buf.WriteString(hstoreReplacer.Replace(h[k]))
+		buf.WriteRune('"')
+	}
+	return buf.String(), nil
+}
+
+// SQLType implements SQLTyper.
+func (h Hstore) SQLType() string {
+	return "hstore"
+}
+
+// Scan implements sql.Scanner.
+func (h *Hstore) Scan(src interface{}) error {
+	var b []byte
+	switch t := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		b = t
+	case string:
+		b = []byte(t)
+	default:
+		return fmt.Errorf("Cannot scan %T into Hstore", src)
+	}
+
+	var parsed hstore.Hstore
+	if err := parsed.Scan(b); err != nil {
+		return err
+	}
+	m := make(Hstore, len(parsed.Map))
+	for k, v := range parsed.Map {
+		m[k] = v.String
+	}
+	*h = m
+	return nil
+}
+
+// HstoreGet returns the SQL to get the value of key from an hstore column,
+// eg HstoreGet("meta", "color") returns meta -> 'color'. Use it within a
+// condition
+//
+//	Where(dat.HstoreGet("meta", "color")+" = $1", "red")
+func HstoreGet(column string, key string) string {
+	var buf bytes.Buffer
+	buf.WriteString(column)
+	buf.WriteString(" -> ")
+	Dialect.WriteStringLiteral(&buf, key)
+	return buf.String()
+}
+
+// HstoreHasKey creates a condition which is true when the hstore column
+// contains key, eg meta ? $1.
+func HstoreHasKey(column string, key string) *Expression {
+	return Expr(column+" ? $1", key)
+}
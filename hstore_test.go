@@ -0,0 +1,46 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHstoreValue(t *testing.T) {
+	h := Hstore{"b": `say "hi"`, "a": `back\slash`, "c": ""}
+	v, err := h.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, `"a"=>"back\\slash","b"=>"say \"hi\"","c"=>""`, v)
+
+	v, err = Hstore(nil).Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestHstoreInterpolate(t *testing.T) {
+	sql, args, err := Interpolate("UPDATE a SET meta = $1", []interface{}{Hstore{"k": "it's"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `UPDATE a SET meta = '"k"=>"it''s"'::hstore`, sql)
+	assert.Empty(t, args)
+}
+
+func TestHstoreScan(t *testing.T) {
+	var h Hstore
+	assert.NoError(t, h.Scan([]byte(`"a"=>"back\\slash", "b"=>"say \"hi\"", "c"=>NULL`)))
+	assert.Equal(t, Hstore{"a": `back\slash`, "b": `say "hi"`, "c": ""}, h)
+
+	assert.NoError(t, h.Scan(nil))
+	assert.Nil(t, h)
+
+	assert.Error(t, h.Scan(1))
+}
+
+func TestHstoreOperators(t *testing.T) {
+	sql, args := Select("id").
+		From("a").
+		Where(HstoreGet("meta", "color")+" = $1", "red").
+		Where(HstoreHasKey("meta", "size")).
+		ToSQL()
+	assert.Equal(t, `SELECT id FROM a WHERE (meta -> 'color' = $1) AND (meta ? $2)`, sql)
+	assert.Equal(t, []interface{}{"red", "size"}, args)
+}
@@ -0,0 +1,50 @@
+package dat
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/casualjim/dat/common"
+)
+
+// rePlainIdent matches an unquoted, optionally qualified, identifier.
+var rePlainIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)*$`)
+
+// QuoteIdent quotes name as a single identifier with the active Dialect,
+// escaping embedded quotes, eg order => "order".
+func QuoteIdent(name string) string {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	Dialect.WriteIdentifier(buf, name)
+	return buf.String()
+}
+
+// Ident quotes each part of a qualified identifier, eg
+// Ident("public", "order") => "public"."order". The result may be used
+// wherever SQL is accepted, eg From(dat.Ident("order")).
+func Ident(parts ...string) string {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	writeIdentParts(buf, parts)
+	return buf.String()
+}
+
+func writeIdentParts(buf common.BufferWriter, parts []string) {
+	for i, part := range parts {
+		if i > 0 {
+			buf.WriteRune('.')
+		}
+		Dialect.WriteIdentifier(buf, part)
+	}
+}
+
+// writeIdentOrSQL writes s quoted when AutoQuoteIdentifiers is enabled and
+// s is a plain identifier. Anything else, eg an alias or expression, is
+// written verbatim.
+func writeIdentOrSQL(buf common.BufferWriter, s string) {
+	if AutoQuoteIdentifiers && rePlainIdent.MatchString(s) {
+		writeIdentParts(buf, strings.Split(s, "."))
+		return
+	}
+	buf.WriteString(s)
+}
@@ -0,0 +1,37 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	assert.Equal(t, `"order"`, QuoteIdent("order"))
+	assert.Equal(t, `"my ""odd"" name"`, QuoteIdent(`my "odd" name`))
+	assert.Equal(t, `"public"."order"`, Ident("public", "order"))
+
+	sql, _ := Select("id").From(Ident("order")).ToSQL()
+	assert.Equal(t, `SELECT id FROM "order"`, sql)
+}
+
+func TestAutoQuoteIdentifiers(t *testing.T) {
+	AutoQuoteIdentifiers = true
+	defer func() { AutoQuoteIdentifiers = false }()
+
+	sql, args := Select("id", "user", "count(*)", "o.total").
+		From("public.order").
+		Where("id = $1", 1).
+		ToSQL()
+	assert.Equal(t, `SELECT "id", "user", count(*), "o"."total" FROM "public"."order" WHERE (id = $1)`, sql)
+	assert.Exactly(t, []interface{}{1}, args)
+
+	sql, _ = Select("*").From("order o").ToSQL()
+	assert.Equal(t, `SELECT * FROM order o`, sql)
+
+	sql, _ = DeleteFrom("user").Where("id = $1", 1).ToSQL()
+	assert.Equal(t, `DELETE FROM "user" WHERE (id = $1)`, sql)
+
+	sql, _ = InsertInto("user").Columns("name").Values("mario").ToSQL()
+	assert.Equal(t, `INSERT INTO "user" ("name") VALUES ($1)`, sql)
+}
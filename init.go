@@ -4,10 +4,33 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/casualjim/dat/common"
 	"go.uber.org/zap"
 )
 
-var logger *zap.Logger
+var namedLogger = common.NewNamedLogger("dat")
+
+// logger returns the package logger.
+func logger() *zap.Logger {
+	return namedLogger.Get()
+}
+
+// SetLogger replaces the logger used by dat and its runners at runtime. By
+// default the global zap logger at the time of logging is used. Pass nil to
+// revert to the default.
+func SetLogger(l *zap.Logger) {
+	common.SetLogger(l)
+}
+
+// SetSugaredLogger replaces the logger used by dat and its runners with the
+// desugared form of l.
+func SetSugaredLogger(l *zap.SugaredLogger) {
+	if l == nil {
+		common.SetLogger(nil)
+		return
+	}
+	common.SetLogger(l.Desugar())
+}
 
 // Strict tells dat to raise errors
 var Strict = false
@@ -15,6 +38,24 @@ var Strict = false
 // EnableInterpolation enables or disable interpolation
 var EnableInterpolation = false
 
+// InterpolateTimeUTC converts interpolated times to UTC. By default times
+// are interpolated with the offset of their location.
+var InterpolateTimeUTC = false
+
+// AutoQuoteIdentifiers quotes plain table and column names given to
+// Select, SelectDoc, InsertInto and DeleteFrom, eg order => "order".
+// Aliases and expressions are written verbatim.
+var AutoQuoteIdentifiers = false
+
+// EnableLimitPlaceholders sends LIMIT and OFFSET values as bind arguments
+// rather than inlining them, so paginated queries share the same SQL text.
+var EnableLimitPlaceholders = false
+
+// MaxLoggedSQLLength truncates the SQL and args of logged queries to the
+// given number of bytes, eg to keep insert-many statements out of log
+// pipelines. The executed SQL is untouched. 0 disables truncation.
+var MaxLoggedSQLLength = 0
+
 // maxLookup is the max lookup index for predefined lookup tables
 const maxLookup = 100
 
@@ -52,6 +93,4 @@ func init() {
 		itoaTab[i] = strconv.Itoa(i)
 		identifierTab[i] = fmt.Sprintf("dat%d", i)
 	}
-
-	logger = zap.L().Named("dat")
 }
@@ -25,6 +25,7 @@ type InsectBuilder struct {
 	table          string
 	cols           []string
 	isBlacklist    bool
+	blacklist      []string
 	vals           []interface{}
 	record         interface{}
 	returnings     []string
@@ -34,7 +35,7 @@ type InsectBuilder struct {
 // NewInsectBuilder creates a new InsectBuilder for the given table.
 func NewInsectBuilder(table string) *InsectBuilder {
 	if table == "" {
-		logger.Error("Insect requires a table name.")
+		logger().Error("Insect requires a table name.")
 		return nil
 	}
 	return &InsectBuilder{table: table, isInterpolated: EnableInterpolation}
@@ -46,10 +47,11 @@ func (b *InsectBuilder) Columns(columns ...string) *InsectBuilder {
 }
 
 // Blacklist defines a blacklist of columns and should only be used
-// in conjunction with Record.
+// in conjunction with Record. Blacklisted columns are removed from the
+// whitelist, which defaults to all columns of the record.
 func (b *InsectBuilder) Blacklist(columns ...string) *InsectBuilder {
 	b.isBlacklist = true
-	b.cols = columns
+	b.blacklist = columns
 	return b
 }
 
@@ -85,27 +87,24 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}) {
 		panic("no table specified")
 	}
 	lenCols := len(b.cols)
-	if lenCols == 0 {
+	if lenCols == 0 && !b.isBlacklist {
 		panic("no columns specified")
 	}
 	if len(b.vals) == 0 && b.record == nil {
 		panic("no values or records specified")
 	}
 
-	if b.record == nil && b.cols[0] == "*" {
-		panic(`"*" can only be used in conjunction with Record`)
-	}
 	if b.record == nil && b.isBlacklist {
 		panic(`Blacklist can only be used in conjunction with Record`)
 	}
-
-	// reflect fields removing blacklisted columns
-	if b.record != nil && b.isBlacklist {
-		b.cols = reflectExcludeColumns(b.record, b.cols)
+	if b.record == nil && b.cols[0] == "*" {
+		panic(`"*" can only be used in conjunction with Record`)
 	}
-	// reflect all fields
-	if b.record != nil && b.cols[0] == "*" {
-		b.cols = reflectColumns(b.record)
+
+	// reflect fields, all when whitelisted with "*", removing blacklisted
+	// columns
+	if b.record != nil && (b.isBlacklist || b.cols[0] == "*") {
+		b.cols = recordColumns(b.record, b.cols, b.blacklist)
 	}
 
 	whereAdded := false
@@ -173,7 +172,7 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}) {
 		writePlaceholders(buf, len(args), ",", 1)
 	} else {
 		writePlaceholders(buf, len(b.vals), ",", len(args)+1)
-		args = append(args, b.vals...)
+		args = append(args, redactRow(b.cols, b.vals)...)
 	}
 
 	buf.WriteString(" WHERE NOT EXISTS (SELECT 1 FROM sel) RETURNING ")
@@ -1,9 +1,6 @@
 package dat
 
-import (
-	"bytes"
-	"reflect"
-)
+import "reflect"
 
 // InsertBuilder contains the clauses for an INSERT statement
 type InsertBuilder struct {
@@ -13,30 +10,52 @@ type InsertBuilder struct {
 	table          string
 	cols           []string
 	isBlacklist    bool
+	blacklist      []string
 	vals           [][]interface{}
 	records        []interface{}
 	returnings     []string
+	conflict       *ConflictBuilder
+	inserted       *bool
+}
+
+// InsertedColumn is the column returned by statements of builders using
+// ReturningInserted.
+const InsertedColumn = "dat__inserted"
+
+// InsertedReturner is implemented by builders whose statement returns
+// whether the row was inserted, see InsertBuilder.ReturningInserted.
+type InsertedReturner interface {
+	// InsertedDest returns the destination of the inserted flag, nil if
+	// not requested.
+	InsertedDest() *bool
 }
 
 // NewInsertBuilder creates a new InsertBuilder for the given table.
 func NewInsertBuilder(table string) *InsertBuilder {
 	if table == "" {
-		logger.Error("InsertInto requires a table name.")
+		logger().Error("InsertInto requires a table name.")
 		return nil
 	}
 	return &InsertBuilder{table: table, isInterpolated: EnableInterpolation}
 }
 
+// Into sets the table to insert into, eg a partition of the original table.
+func (b *InsertBuilder) Into(table string) *InsertBuilder {
+	b.table = table
+	return b
+}
+
 // Columns appends columns to insert in the statement
 func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
 	return b.Whitelist(columns...)
 }
 
 // Blacklist defines a blacklist of columns and should only be used
-// in conjunction with Record.
+// in conjunction with Record. Blacklisted columns are removed from the
+// whitelist, which defaults to all columns of the record.
 func (b *InsertBuilder) Blacklist(columns ...string) *InsertBuilder {
 	b.isBlacklist = true
-	b.cols = columns
+	b.blacklist = columns
 	return b
 }
 
@@ -59,12 +78,40 @@ func (b *InsertBuilder) Record(record interface{}) *InsertBuilder {
 	return b
 }
 
+// Records pulls in values to match Columns from each element of records,
+// which must be a slice. All records are inserted with a single
+// multi-row statement.
+func (b *InsertBuilder) Records(records interface{}) *InsertBuilder {
+	v := reflect.Indirect(reflect.ValueOf(records))
+	if v.Kind() != reflect.Slice {
+		panic("Records requires a slice")
+	}
+	for i := 0; i < v.Len(); i++ {
+		b.records = append(b.records, v.Index(i).Interface())
+	}
+	return b
+}
+
 // Returning sets the columns for the RETURNING clause
 func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
 	b.returnings = columns
 	return b
 }
 
+// ReturningInserted returns whether the row was inserted rather than updated
+// by ON CONFLICT DO UPDATE, which the runner stores in inserted when
+// scanning the row with QueryStruct or QueryScalar. If no row is returned,
+// eg on DO NOTHING, inserted is false and no error is returned.
+func (b *InsertBuilder) ReturningInserted(inserted *bool) *InsertBuilder {
+	b.inserted = inserted
+	return b
+}
+
+// InsertedDest returns the destination set by ReturningInserted.
+func (b *InsertBuilder) InsertedDest() *bool {
+	return b.inserted
+}
+
 // Pair adds a key/value pair to the statement
 func (b *InsertBuilder) Pair(column string, value interface{}) *InsertBuilder {
 	b.cols = append(b.cols, column)
@@ -81,61 +128,69 @@ func (b *InsertBuilder) Pair(column string, value interface{}) *InsertBuilder {
 }
 
 // ToSQL serialized the InsertBuilder to a SQL string
-// It returns the string with placeholders and a slice of query arguments
+// It returns the string with placeholders and a slice of query arguments.
+// It panics with ErrTooManyParameters if the records exceed MaxParameters,
+// Interpolate and the runner return the error instead.
 func (b *InsertBuilder) ToSQL() (string, []interface{}) {
+	sql, args, err := b.toSQL()
+	if err != nil {
+		panic(err)
+	}
+	return sql, args
+}
+
+// toSQL is ToSQL returning ErrTooManyParameters as an error.
+func (b *InsertBuilder) toSQL() (string, []interface{}, error) {
 	if len(b.table) == 0 {
 		panic("no table specified")
 	}
 	lenCols := len(b.cols)
 	lenRecords := len(b.records)
-	if lenCols == 0 {
+	if lenCols == 0 && !b.isBlacklist {
 		panic("no columns specified")
 	}
 	if len(b.vals) == 0 && lenRecords == 0 {
 		panic("no values or records specified")
 	}
 
-	if lenRecords == 0 && b.cols[0] == "*" {
-		panic(`"*" can only be used in conjunction with Record`)
-	}
-
 	if lenRecords == 0 && b.isBlacklist {
 		panic(`Blacklist can only be used in conjunction with Record`)
 	}
-
-	// reflect fields removing blacklisted columns
-	if lenRecords > 0 && b.isBlacklist {
-		b.cols = reflectExcludeColumns(b.records[0], b.cols)
+	if lenRecords == 0 && b.cols[0] == "*" {
+		panic(`"*" can only be used in conjunction with Record`)
 	}
-	// reflect all fields
-	if lenRecords > 0 && b.cols[0] == "*" {
-		b.cols = reflectColumns(b.records[0])
+
+	// reflect fields, all when whitelisted with "*", removing blacklisted
+	// columns
+	if lenRecords > 0 && (b.isBlacklist || b.cols[0] == "*") {
+		b.cols = recordColumns(b.records[0], b.cols, b.blacklist)
 	}
 
-	var sql bytes.Buffer
-	var args []interface{}
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	args := make([]interface{}, 0, len(b.cols)*(len(b.vals)+lenRecords))
 
-	sql.WriteString("INSERT INTO ")
-	sql.WriteString(b.table)
-	sql.WriteString(" (")
+	buf.WriteString("INSERT INTO ")
+	writeIdentOrSQL(buf, b.table)
+	buf.WriteString(" (")
 
 	for i, c := range b.cols {
 		if i > 0 {
-			sql.WriteRune(',')
+			buf.WriteRune(',')
 		}
-		Dialect.WriteIdentifier(&sql, c)
+		Dialect.WriteIdentifier(buf, c)
 	}
-	sql.WriteString(") VALUES ")
+	buf.WriteString(") VALUES ")
 
 	start := 1
 	// Go thru each value we want to insert. Write the placeholders, and collect args
 	for i, row := range b.vals {
 		if i > 0 {
-			sql.WriteRune(',')
+			buf.WriteRune(',')
 		}
-		buildPlaceholders(&sql, start, len(row))
+		buildPlaceholders(buf, start, len(row))
 
-		for _, v := range row {
+		for _, v := range redactRow(b.cols, row) {
 			args = append(args, v)
 			start++
 		}
@@ -145,7 +200,7 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}) {
 	// Go thru the records. Write the placeholders, and do reflection on the records to extract args
 	for i, rec := range b.records {
 		if i > 0 || anyVals {
-			sql.WriteRune(',')
+			buf.WriteRune(',')
 		}
 
 		ind := reflect.Indirect(reflect.ValueOf(rec))
@@ -153,22 +208,41 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}) {
 		if err != nil {
 			panic(err.Error())
 		}
-		buildPlaceholders(&sql, start, len(vals))
-		for _, v := range vals {
+		buildPlaceholders(buf, start, len(vals))
+		for _, v := range redactRow(b.cols, vals) {
 			args = append(args, v)
 			start++
 		}
 	}
 
+	if b.conflict != nil {
+		pos := int64(start)
+		b.conflict.writeSQL(buf, &args, &pos)
+	}
+
+	// interpolated statements have no bind parameters
+	if len(args) > MaxParameters && !b.isInterpolated {
+		return "", nil, ErrTooManyParameters
+	}
+
 	// Go thru the returning clauses
 	for i, c := range b.returnings {
 		if i == 0 {
-			sql.WriteString(" RETURNING ")
+			buf.WriteString(" RETURNING ")
+		} else {
+			buf.WriteRune(',')
+		}
+		Dialect.WriteIdentifier(buf, c)
+	}
+	if b.inserted != nil {
+		if len(b.returnings) == 0 {
+			buf.WriteString(" RETURNING ")
 		} else {
-			sql.WriteRune(',')
+			buf.WriteRune(',')
 		}
-		Dialect.WriteIdentifier(&sql, c)
+		// xmax is 0 for a newly inserted row version
+		buf.WriteString("(xmax = 0) AS " + InsertedColumn)
 	}
 
-	return sql.String(), args
+	return buf.String(), args, nil
 }
@@ -18,6 +18,7 @@ type someRecord struct {
 }
 
 func BenchmarkInsertValuesSql(b *testing.B) {
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -26,6 +27,7 @@ func BenchmarkInsertValuesSql(b *testing.B) {
 }
 
 func BenchmarkInsertRecordsSql(b *testing.B) {
+	b.ReportAllocs()
 	obj := someRecord{1, 99, false}
 
 	b.ResetTimer()
@@ -35,6 +37,36 @@ func BenchmarkInsertRecordsSql(b *testing.B) {
 	}
 }
 
+func BenchmarkInsertRecordsStarSql(b *testing.B) {
+	b.ReportAllocs()
+	obj := someRecord{1, 99, false}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		InsertInto("alpha").Whitelist("*").Record(obj).ToSQL()
+	}
+}
+
+// BenchmarkInsertManyRecordsInterpolated builds and interpolates a batch
+// insert, the statement with the most buffer writes and placeholders.
+func BenchmarkInsertManyRecordsInterpolated(b *testing.B) {
+	b.ReportAllocs()
+	objs := make([]someRecord, 100)
+	for i := range objs {
+		objs[i] = someRecord{i, 99, i%2 == 0}
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sql, args := InsertInto("alpha").Columns("something_id", "user_id", "other").Records(objs).ToSQL()
+		if _, _, err := Interpolate(sql, args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestInsertSingleToSql(t *testing.T) {
 	sql, args := InsertInto("a").Columns("b", "c").Values(1, 2).ToSQL()
 
@@ -64,6 +96,27 @@ func TestInsertRecordsToSql(t *testing.T) {
 	checkSliceEqual(t, args, []interface{}{1, 88, false, 2, 99, true})
 }
 
+func TestInsertRecordsSliceToSql(t *testing.T) {
+	objs := []*someRecord{{1, 88, false}, {2, 99, true}, {3, 77, false}}
+	sql, args := InsertInto("a").Columns("something_id", "user_id", "other").Records(objs).ToSQL()
+
+	assert.Equal(t, sql, quoteSQL("INSERT INTO a (%s,%s,%s) VALUES ($1,$2,$3),($4,$5,$6),($7,$8,$9)", "something_id", "user_id", "other"))
+	checkSliceEqual(t, args, []interface{}{1, 88, false, 2, 99, true, 3, 77, false})
+
+	assert.Panics(t, func() {
+		InsertInto("a").Columns("something_id").Records(objs[0])
+	})
+}
+
+func TestInsertTooManyParameters(t *testing.T) {
+	objs := make([]someRecord, MaxParameters/3+1)
+	b := InsertInto("a").Columns("something_id", "user_id", "other").Records(objs)
+
+	_, _, err := b.Interpolate()
+	assert.Equal(t, ErrTooManyParameters, err)
+	assert.PanicsWithValue(t, ErrTooManyParameters, func() { b.ToSQL() })
+}
+
 func TestInsertWhitelist(t *testing.T) {
 	objs := []someRecord{{1, 88, false}, {2, 99, true}}
 	sql, args := InsertInto("a").
@@ -98,6 +151,17 @@ func TestInsertBlacklist(t *testing.T) {
 	})
 }
 
+func TestInsertWhitelistAndBlacklist(t *testing.T) {
+	rec := someRecord{1, 88, false}
+	sql, args := InsertInto("a").Whitelist("*").Blacklist("something_id").Record(rec).ToSQL()
+	assert.Equal(t, `INSERT INTO a ("user_id","other") VALUES ($1,$2)`, sql)
+	checkSliceEqual(t, []interface{}{88, false}, args)
+
+	sql, args = InsertInto("a").Whitelist("something_id", "other").Blacklist("other").Record(rec).ToSQL()
+	assert.Equal(t, `INSERT INTO a ("something_id") VALUES ($1)`, sql)
+	checkSliceEqual(t, []interface{}{1}, args)
+}
+
 func TestInsertDuplicateColumns(t *testing.T) {
 	type A struct {
 		Status string `db:"status"`
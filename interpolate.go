@@ -1,8 +1,8 @@
 package dat
 
 import (
-	"bytes"
 	"database/sql/driver"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
@@ -42,6 +42,35 @@ func isFloat(k reflect.Kind) bool {
 //   - booleans
 //   - times
 var typeOfTime = reflect.TypeOf(time.Time{})
+var typeOfValuer = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// isNil determines if v is nil or a nil pointer, map or slice.
+func isNil(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// isNilPointer determines if v is nil or a nil pointer.
+func isNilPointer(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// SQLTyper is implemented by values which are interpolated with a type
+// cast, eg a Postgres enum 'happy'::mood. Implement driver.Valuer to
+// convert the value itself.
+type SQLTyper interface {
+	SQLType() string
+}
 
 // Interpolate takes a SQL string with placeholders and a list of arguments to
 // replace them with. Returns a blank string and error if the number of placeholders
@@ -50,21 +79,16 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 	// Get the number of arguments to add to this query
 	lenVals := len(vals)
 
-	// if there are any []byte types, just pass it through to save memory allocations
-	for i := 0; i < lenVals; i++ {
-		if _, ok := vals[i].([]byte); ok {
-			return sql, vals, nil
-		} else if _, ok := vals[i].(*[]byte); ok {
-			return sql, vals, nil
-		}
+	logError := func() {
+		logger().Error("Interpolation error", zap.Error(ErrArgumentMismatch),
+			zap.String("sql", TruncateLogged(sql)), zap.String("args", TruncateLogged(fmt.Sprint(vals))))
 	}
 
 	// If our query is blank and has no args return early
 	// Args with a blank query is an error
-	lg := logger.With(zap.Error(ErrArgumentMismatch), zap.String("sql", sql), zap.Any("args", vals))
 	if sql == "" {
 		if lenVals != 0 {
-			lg.Error("Interpolation error")
+			logError()
 			return "", nil, ErrArgumentMismatch
 		}
 		return "", nil, nil
@@ -77,14 +101,14 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 		// No args for a query with place holders is an error
 		if lenVals == 0 {
 			if hasPlaceholders {
-				lg.Error("Interpolation error")
+				logError()
 				return "", nil, ErrArgumentMismatch
 			}
 			return sql, nil, nil
 		}
 
 		if lenVals > 0 && !hasPlaceholders {
-			lg.Error("Interpolation error")
+			logError()
 			return "", nil, ErrArgumentMismatch
 		}
 
@@ -96,18 +120,35 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 	var accumulateDigits bool
-	var digits bytes.Buffer
+	// digits of the current placeholder are sql[digitsStart:digitsEnd]
+	var digitsStart, digitsEnd int
 
 	newPlaceholderIndex := 0
 	var newArgs []interface{}
 
-	var writeValue = func(pos int) error {
+	var writeValue = func(pos int) (err error) {
 		if pos < 0 || pos >= lenVals {
 			return ErrArgumentMismatch
 		}
 
 		v := vals[pos]
 
+		// nil and nil pointers are always NULL, even when the pointer
+		// type is a Valuer or Expressioner
+		if isNilPointer(v) {
+			buf.WriteString("NULL")
+			return nil
+		}
+
+		if typer, ok := v.(SQLTyper); ok && !isNil(v) {
+			defer func() {
+				if t := typer.SQLType(); err == nil && t != "" {
+					buf.WriteString("::")
+					buf.WriteString(t)
+				}
+			}()
+		}
+
 		// mark any arguments not handled with a new placeholder
 		// and the arg to the new arguments slice
 		var passthroughArg = func(values ...interface{}) {
@@ -116,7 +157,30 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			writePlaceholder(buf, newPlaceholderIndex)
 		}
 
-		if val, ok := v.(UnsafeString); ok {
+		// secrets are sent as args so they are logged redacted
+		if _, ok := v.(SecretArg); ok {
+			passthroughArg(v)
+			return nil
+		}
+
+		if p, ok := v.(*[]byte); ok {
+			if p == nil {
+				buf.WriteString("NULL")
+				return nil
+			}
+			v = *p
+		}
+
+		if b, ok := v.([]byte); ok {
+			if b == nil {
+				buf.WriteString("NULL")
+			} else if w, ok := Dialect.(BytesWriter); ok {
+				w.WriteBytesLiteral(buf, b)
+			} else {
+				passthroughArg(v)
+			}
+			return nil
+		} else if val, ok := v.(UnsafeString); ok {
 			buf.WriteString(string(val))
 			return nil
 		} else if _, ok := v.(JSON); ok {
@@ -129,8 +193,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			passthroughArg(v)
 			return nil
 		} else if valuer, ok := v.(Expressioner); ok {
-			valueOfV := reflect.ValueOf(v)
-			if valueOfV.IsNil() {
+			if isNil(v) {
 				buf.WriteString("NULL")
 				return nil
 			}
@@ -146,8 +209,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			}
 			return nil
 		} else if valuer, ok := v.(Interpolator); ok {
-			valueOfV := reflect.ValueOf(v)
-			if valueOfV.IsNil() {
+			if isNil(v) {
 				buf.WriteString("NULL")
 				return nil
 			}
@@ -163,6 +225,11 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			if err != nil {
 				return err
 			}
+			// the driver knows the column type of a valuer's binary value
+			if _, ok := val.([]byte); ok {
+				passthroughArg(val)
+				return nil
+			}
 			v = val
 		}
 
@@ -209,6 +276,9 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 		} else if kindOfV == reflect.Struct {
 			if typeOfV := valueOfV.Type(); typeOfV == typeOfTime {
 				t := valueOfV.Interface().(time.Time)
+				if InterpolateTimeUTC {
+					t = t.UTC()
+				}
 				Dialect.WriteFormattedTime(buf, t)
 			} else {
 				return ErrInvalidValue
@@ -224,7 +294,21 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			}
 
 			buf.WriteRune('(')
-			if isInt(kindOfSubtype) {
+			if subtype.Implements(typeOfValuer) {
+				for i := 0; i < sliceLen; i++ {
+					if i > 0 {
+						buf.WriteRune(',')
+					}
+					s, args, err := Interpolate("$1", []interface{}{valueOfV.Index(i).Interface()})
+					if err != nil {
+						return err
+					}
+					if len(args) > 0 {
+						return ErrInvalidSliceValue
+					}
+					buf.WriteString(s)
+				}
+			} else if isInt(kindOfSubtype) {
 				for i := 0; i < sliceLen; i++ {
 					if i > 0 {
 						buf.WriteRune(',')
@@ -267,7 +351,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 	for i, r := range sql {
 		if accumulateDigits {
 			if '0' <= r && r <= '9' {
-				digits.WriteRune(r)
+				digitsEnd = i + 1
 				if i < lenSQL-1 {
 					continue
 				}
@@ -275,7 +359,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 				done = true
 			}
 
-			digitsStr := digits.String()
+			digitsStr := sql[digitsStart:digitsEnd]
 			// can be empty $ is followed by a non-digit
 			if digitsStr == "" {
 				buf.WriteRune('$')
@@ -302,7 +386,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 		}
 
 		if r == '$' && i < lenSQL-1 {
-			digits.Reset()
+			digitsStart, digitsEnd = i+1, i+1
 			accumulateDigits = true
 			continue
 		}
@@ -313,8 +397,24 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 	return buf.String(), newArgs, nil
 }
 
+// errBuilder is a Builder which may fail to build due to the data or the
+// database rather than the code, eg an insert of too many records. toSQL
+// returns such failures as an error where ToSQL panics.
+type errBuilder interface {
+	toSQL() (string, []interface{}, error)
+}
+
 func interpolate(builder Builder) (string, []interface{}, error) {
-	sql, args := builder.ToSQL()
+	var sql string
+	var args []interface{}
+	if b, ok := builder.(errBuilder); ok {
+		var err error
+		if sql, args, err = b.toSQL(); err != nil {
+			return "", nil, err
+		}
+	} else {
+		sql, args = builder.ToSQL()
+	}
 	if builder.IsInterpolated() {
 		return Interpolate(sql, args)
 	}
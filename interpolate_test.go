@@ -2,15 +2,18 @@ package dat
 
 import (
 	"database/sql/driver"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/casualjim/dat/postgres"
 	"github.com/lib/pq"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func BenchmarkInterpolate(b *testing.B) {
+	b.ReportAllocs()
 	// Do some allocations outside the loop so they don't affect the results
 	argEq1 := Eq{"f": 2, "x": "hi"}
 	argEq2 := map[string]interface{}{"g": 3}
@@ -92,6 +95,24 @@ func TestInterpolateEscapeStrings(t *testing.T) {
 	assert.Equal(t, "SELECT * FROM x WHERE a = 'hello' AND b = '\"pg''s world\" \\\b\f\n\r\t\x1a'", str)
 }
 
+func TestInterpolateDollarQuoting(t *testing.T) {
+	Dialect = &postgres.Postgres{DollarQuoting: true}
+	defer func() { Dialect = postgres.New() }()
+
+	tag := postgres.GetPgDollarTag()
+	str, _, err := Interpolate("SELECT $1, $2", []interface{}{"hello", `{"name": "pg's"}`})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 'hello', "+tag+`{"name": "pg's"}`+tag, str)
+
+	// content colliding with the tag picks a new tag
+	val := "it's " + strings.TrimSuffix(tag, "$")
+	str, _, err = Interpolate("SELECT $1", []interface{}{val})
+	assert.NoError(t, err)
+	newTag := postgres.GetPgDollarTag()
+	assert.NotEqual(t, tag, newTag)
+	assert.Equal(t, "SELECT "+newTag+val+newTag, str)
+}
+
 func TestInterpolateSlices(t *testing.T) {
 	args := []interface{}{[]int{1}, []int{1, 2, 3}, []uint32{5, 6, 7}, []string{"wat", "ok"}}
 
@@ -167,6 +188,27 @@ func TestInterpolatingTime(t *testing.T) {
 	assert.Equal(t, "SELECT * FROM x WHERE a = NULL AND b = '0001-01-01T00:00:00Z' AND c = '2004-01-01T01:01:01.000000001Z'", str)
 }
 
+func TestInterpolatingTimeLocation(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+30*60)
+	tim := time.Date(2004, time.January, 1, 1, 1, 1, 0, loc)
+
+	str, _, err := Interpolate("SELECT $1", []interface{}{tim})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '2004-01-01T01:01:01+05:30'", str)
+
+	InterpolateTimeUTC = true
+	defer func() { InterpolateTimeUTC = false }()
+	str, _, err = Interpolate("SELECT $1", []interface{}{tim})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '2003-12-31T19:31:01Z'", str)
+
+	SetDialect(&postgres.Postgres{CastTimestamptz: true})
+	defer SetDialect(postgres.New())
+	str, _, err = Interpolate("SELECT $1", []interface{}{tim})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '2003-12-31T19:31:01Z'::timestamptz", str)
+}
+
 func TestInterpolateErrors(t *testing.T) {
 	_, _, err := Interpolate("SELECT * FROM x WHERE a = $1 AND b = $2", []interface{}{1})
 	assert.Equal(t, err, ErrArgumentMismatch)
@@ -240,3 +282,76 @@ func TestInterpolateExpression(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "select * from fruits where true and kind = 'apple' and NULL", sql)
 }
+
+func TestInterpolateBytes(t *testing.T) {
+	b := []byte{0xde, 0xad, 0x00, 0xbe, 0xef}
+	var nilBytes []byte
+	var nilPtr *[]byte
+
+	sql, args, err := Interpolate("INSERT INTO blobs (a, b, c, d) VALUES ($1, $2, $3, $4)", []interface{}{b, &b, nilBytes, nilPtr})
+	assert.NoError(t, err)
+	assert.Equal(t, `INSERT INTO blobs (a, b, c, d) VALUES ('\xdead00beef'::bytea, '\xdead00beef'::bytea, NULL, NULL)`, sql)
+	assert.Empty(t, args)
+
+	// other values are still interpolated alongside bytes
+	sql, args, err = Interpolate("SELECT $1, $2", []interface{}{[]byte{}, "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT '\x'::bytea, 'a'`, sql)
+	assert.Empty(t, args)
+}
+
+type binaryValuer []byte
+
+func (v binaryValuer) Value() (driver.Value, error) {
+	return []byte(v), nil
+}
+
+func TestInterpolateValuerBytes(t *testing.T) {
+	sql, args, err := Interpolate("SELECT $1, $2", []interface{}{binaryValuer(`{"a":1}`), 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT $1, 1", sql)
+	assert.Equal(t, []interface{}{[]byte(`{"a":1}`)}, args)
+}
+
+// mood is a Postgres enum, CREATE TYPE mood AS ENUM ('sad', 'ok', 'happy')
+type mood int
+
+const (
+	moodSad mood = iota
+	moodOK
+	moodHappy
+)
+
+func (m mood) Value() (driver.Value, error) {
+	return [...]string{"sad", "ok", "happy"}[m], nil
+}
+
+func (m mood) SQLType() string {
+	return "mood"
+}
+
+// color has a Valuer but no cast
+type color string
+
+func (c color) Value() (driver.Value, error) {
+	return strings.ToUpper(string(c)), nil
+}
+
+func TestInterpolateValuerTypes(t *testing.T) {
+	sql, args, err := Interpolate("SELECT * FROM people WHERE mood = $1 AND color = $2", []interface{}{moodHappy, color("red")})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM people WHERE mood = 'happy'::mood AND color = 'RED'", sql)
+	assert.Empty(t, args)
+
+	sql, _, err = Interpolate("SELECT * FROM people WHERE mood IN $1 AND color IN $2", []interface{}{[]mood{moodSad, moodOK}, []color{"red", "blue"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM people WHERE mood IN ('sad'::mood,'ok'::mood) AND color IN ('RED','BLUE')", sql)
+}
+
+func TestInterpolateNilPointers(t *testing.T) {
+	var s *NullString
+	var n *int64
+	sql, _, err := Interpolate("SELECT * FROM people WHERE name = $1 AND age = $2 AND email = $3", []interface{}{nil, n, s})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM people WHERE name = NULL AND age = NULL AND email = NULL", sql)
+}
@@ -0,0 +1,102 @@
+package dat
+
+import "github.com/casualjim/dat/common"
+
+// joinClause is a JOIN of table, or of a source with args such as a LATERAL
+// subquery, with an optional ON condition.
+type joinClause struct {
+	kind   string
+	table  string
+	source *Expression
+	on     *Expression
+}
+
+// Join appends an INNER JOIN of table ON the condition on. Placeholders in
+// args are relative to the condition and are renumbered in statement order.
+//
+//	Select("u.*").From("users u").Join("posts p", "p.user_id = u.id AND p.state = $1", "published")
+func (b *SelectBuilder) Join(table, on string, args ...interface{}) *SelectBuilder {
+	return b.join("INNER JOIN", table, on, args)
+}
+
+// LeftJoin appends a LEFT JOIN of table ON the condition on, see Join.
+func (b *SelectBuilder) LeftJoin(table, on string, args ...interface{}) *SelectBuilder {
+	return b.join("LEFT JOIN", table, on, args)
+}
+
+// RightJoin appends a RIGHT JOIN of table ON the condition on, see Join.
+func (b *SelectBuilder) RightJoin(table, on string, args ...interface{}) *SelectBuilder {
+	return b.join("RIGHT JOIN", table, on, args)
+}
+
+// FullJoin appends a FULL JOIN of table ON the condition on, see Join.
+func (b *SelectBuilder) FullJoin(table, on string, args ...interface{}) *SelectBuilder {
+	return b.join("FULL JOIN", table, on, args)
+}
+
+// CrossJoin appends a CROSS JOIN of table.
+func (b *SelectBuilder) CrossJoin(table string) *SelectBuilder {
+	b.joins = append(b.joins, &joinClause{kind: "CROSS JOIN", table: table})
+	return b
+}
+
+// JoinLateral appends an INNER JOIN LATERAL of the subquery aliased as alias
+// ON the condition on, which defaults to true when empty. The subquery may
+// reference columns of preceding FROM items and its args are merged in
+// statement order.
+//
+//	Select("u.id", "p.title").From("users u").
+//		JoinLateral(Select("title").From("posts").Where("user_id = u.id").OrderBy("id DESC").Limit(3), "p", "")
+func (b *SelectBuilder) JoinLateral(subquery Fragment, alias, on string, args ...interface{}) *SelectBuilder {
+	return b.joinLateral("INNER JOIN", subquery, alias, on, args)
+}
+
+// LeftJoinLateral appends a LEFT JOIN LATERAL of the subquery, see
+// JoinLateral.
+func (b *SelectBuilder) LeftJoinLateral(subquery Fragment, alias, on string, args ...interface{}) *SelectBuilder {
+	return b.joinLateral("LEFT JOIN", subquery, alias, on, args)
+}
+
+func (b *SelectBuilder) joinLateral(kind string, subquery Fragment, alias, on string, args []interface{}) *SelectBuilder {
+	if alias == "" {
+		panic(kind + " LATERAL requires an alias")
+	}
+	if on == "" {
+		on = "true"
+	}
+	sql, subArgs := subquery.ToSQL()
+	b.joins = append(b.joins, &joinClause{
+		kind:   kind,
+		source: Expr("LATERAL ("+sql+") AS "+QuoteIdent(alias), subArgs...),
+		on:     Expr(on, args...),
+	})
+	return b
+}
+
+func (b *SelectBuilder) join(kind, table, on string, args []interface{}) *SelectBuilder {
+	if on == "" {
+		panic(kind + " requires an ON condition")
+	}
+	b.joins = append(b.joins, &joinClause{kind: kind, table: table, on: Expr(on, args...)})
+	return b
+}
+
+// writeJoinsToSQL writes the JOIN clauses, remapping the placeholders of
+// their conditions.
+func writeJoinsToSQL(buf common.BufferWriter, joins []*joinClause, args *[]interface{}, pos *int64) {
+	for _, j := range joins {
+		buf.WriteRune(' ')
+		buf.WriteString(j.kind)
+		buf.WriteRune(' ')
+		if j.source != nil {
+			j.source.WriteRelativeArgs(buf, args, pos)
+		} else {
+			writeIdentOrSQL(buf, j.table)
+		}
+		if j.on != nil {
+			buf.WriteString(" ON (")
+			j.on.WriteRelativeArgs(buf, args, pos)
+			buf.WriteRune(')')
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectJoins(t *testing.T) {
+	sql, args := Select("u.name", "p.title").
+		From("users u").
+		Join("posts p", "p.user_id = u.id AND p.state = $1", "published").
+		LeftJoin("comments c", "c.post_id = p.id AND c.spam = $1", false).
+		RightJoin("tags t", "t.post_id = p.id").
+		FullJoin("likes l", "l.post_id = p.id AND l.kind IN ($1, $2)", "up", "down").
+		CrossJoin("settings").
+		Where("u.active = $1", true).
+		ToSQL()
+
+	assert.Equal(t, stripWS(`
+		SELECT u.name, p.title FROM users u
+		INNER JOIN posts p ON (p.user_id = u.id AND p.state = $1)
+		LEFT JOIN comments c ON (c.post_id = p.id AND c.spam = $2)
+		RIGHT JOIN tags t ON (t.post_id = p.id)
+		FULL JOIN likes l ON (l.post_id = p.id AND l.kind IN ($3, $4))
+		CROSS JOIN settings
+		WHERE (u.active = $5)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"published", false, "up", "down", true}, args)
+}
+
+func TestSelectJoinArgsAfterColumns(t *testing.T) {
+	sql, args := Select("u.id").
+		ColumnExpr(Expr("coalesce(u.nick, $1)", "anon"), "nick").
+		From("users u").
+		Join("posts p", "p.user_id = u.id AND p.id > $1", 10).
+		Where("u.id = $1", 1).
+		ToSQL()
+
+	assert.Equal(t, `SELECT u.id, coalesce(u.nick, $1) AS nick FROM users u INNER JOIN posts p ON (p.user_id = u.id AND p.id > $2) WHERE (u.id = $3)`, sql)
+	assert.Equal(t, []interface{}{"anon", 10, 1}, args)
+}
+
+func TestSelectJoinRequiresOn(t *testing.T) {
+	assert.Panics(t, func() { Select("*").From("a").Join("b", "") })
+}
+
+func TestSelectDocJoin(t *testing.T) {
+	sql, args := SelectDoc("u.id").
+		From("users u").
+		Join("posts p", "p.user_id = u.id AND p.state = $1", "published").
+		Where("u.id = $1", 1).
+		ToSQL()
+
+	assert.Equal(t, stripWS(`
+		SELECT row_to_json(dat__item.*)
+		FROM (
+			SELECT u.id FROM users u
+			INNER JOIN posts p ON (p.user_id = u.id AND p.state = $1)
+			WHERE (u.id = $2)
+		) as dat__item`), stripWS(sql))
+	assert.Equal(t, []interface{}{"published", 1}, args)
+}
+
+func TestSelectJoinLateral(t *testing.T) {
+	latest := Select("title").
+		From("posts").
+		Where("user_id = u.id AND state = $1", "published").
+		OrderBy("id DESC").
+		Limit(3)
+
+	sql, args := Select("u.name", "p.title").
+		From("users u").
+		Join("orgs o", "o.id = u.org_id AND o.kind = $1", "paid").
+		LeftJoinLateral(latest, "p", "").
+		JoinLateral(Select("count(*) AS n").From("comments").Where("user_id = u.id AND spam = $1", false), "c", "c.n > $1", 5).
+		Where("u.active = $1", true).
+		ToSQL()
+
+	assert.Equal(t, stripWS(`
+		SELECT u.name, p.title FROM users u
+		INNER JOIN orgs o ON (o.id = u.org_id AND o.kind = $1)
+		LEFT JOIN LATERAL (SELECT title FROM posts WHERE (user_id = u.id AND state = $2) ORDER BY id DESC LIMIT 3) AS "p" ON (true)
+		INNER JOIN LATERAL (SELECT count(*) AS n FROM comments WHERE (user_id = u.id AND spam = $3)) AS "c" ON (c.n > $4)
+		WHERE (u.active = $5)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"paid", "published", false, 5, true}, args)
+}
@@ -0,0 +1,65 @@
+package dat
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// JSONGet returns the SQL to extract the text at path from a json or jsonb
+// column, eg JSONGet("data", "a", "b") returns data #>> '{a,b}'. Use it
+// within a condition
+//
+//		Where(dat.JSONGet("data", "a", "b")+" = $1", "x")
+func JSONGet(column string, path ...string) string {
+	var buf bytes.Buffer
+	buf.WriteString(column)
+	buf.WriteString(" #>> ")
+	Dialect.WriteStringLiteral(&buf, textArrayLiteral(path))
+	return buf.String()
+}
+
+// JSONContains creates a condition which is true when the jsonb column
+// contains value, eg data @> $1::jsonb. The value is marshalled to JSON
+// unless it is already JSON, json.RawMessage or []byte.
+func JSONContains(column string, value interface{}) *Expression {
+	return Expr(column+" @> $1::jsonb", jsonArg(value))
+}
+
+// JSONExists creates a condition which is true when key is a top level key
+// of the jsonb column, eg data ? $1.
+func JSONExists(column string, key string) *Expression {
+	return Expr(column+" ? $1", key)
+}
+
+func jsonArg(value interface{}) JSON {
+	switch v := value.(type) {
+	case JSON:
+		return v
+	case json.RawMessage:
+		return JSON(v)
+	case []byte:
+		return JSON(v)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	return JSON(b)
+}
+
+// textArrayLiteral returns the text[] literal for elements, eg {a,"b c"}.
+func textArrayLiteral(elements []string) string {
+	var buf bytes.Buffer
+	buf.WriteRune('{')
+	for i, e := range elements {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		buf.WriteRune('"')
+		buf.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(e))
+		buf.WriteRune('"')
+	}
+	buf.WriteRune('}')
+	return buf.String()
+}
@@ -0,0 +1,34 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONGet(t *testing.T) {
+	assert.Equal(t, `data #>> '{"a","b"}'`, JSONGet("data", "a", "b"))
+	assert.Equal(t, `data #>> '{"it''s","say \"hi\""}'`, JSONGet("data", "it's", `say "hi"`))
+
+	sql, args := Select("id").
+		From("docs").
+		Where("id > $1", 1).
+		Where(JSONGet("data", "a", "b")+" = $1", "x").
+		ToSQL()
+	assert.Equal(t, `SELECT id FROM docs WHERE (id > $1) AND (data #>> '{"a","b"}' = $2)`, sql)
+	assert.Equal(t, []interface{}{1, "x"}, args)
+}
+
+func TestJSONContainsAndExists(t *testing.T) {
+	sql, args := Select("id").
+		From("docs").
+		Where("id > $1", 1).
+		Where(JSONContains("data", map[string]interface{}{"status": "active"})).
+		Where(JSONExists("data", "tags")).
+		ToSQL()
+	assert.Equal(t, "SELECT id FROM docs WHERE (id > $1) AND (data @> $2::jsonb) AND (data ? $3)", sql)
+	assert.Equal(t, []interface{}{1, JSON(`{"status":"active"}`), "tags"}, args)
+
+	e := JSONContains("data", JSONFromString(`[1,2]`))
+	assert.Equal(t, []interface{}{JSON(`[1,2]`)}, e.Args)
+}
@@ -1,9 +1,13 @@
 package kvs
 
-import "go.uber.org/zap"
+import (
+	"github.com/casualjim/dat/common"
+	"go.uber.org/zap"
+)
 
-var logger *zap.Logger
+var namedLogger = common.NewNamedLogger("dat:kvs")
 
-func init() {
-	logger = zap.L().Named("dat:kvs")
+// logger returns the package logger.
+func logger() *zap.Logger {
+	return namedLogger.Get()
 }
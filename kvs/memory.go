@@ -31,8 +31,8 @@ func NewMemoryKeyValueStore(cleanupInterval time.Duration) *MemoryKeyValueStore
 
 // Set sets a key with time-to-live.
 func (store *MemoryKeyValueStore) Set(key, value string, ttl time.Duration) error {
-	if ttl < store.cleanupInterval {
-		logger.Warn("The cleanupInterval setting for in-memory key-value store is longer than the TTL of this operation, which means its effective TTL is based on the cleanupInterval")
+	if ttl != TTLNever && ttl < store.cleanupInterval {
+		logger().Warn("The cleanupInterval setting for in-memory key-value store is longer than the TTL of this operation, which means its effective TTL is based on the cleanupInterval")
 	}
 	store.Cache.Set(key, value, ttl)
 	return nil
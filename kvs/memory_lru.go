@@ -0,0 +1,140 @@
+package kvs
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// MemoryStoreOption configures a MemoryStore.
+type MemoryStoreOption func(*MemoryStore)
+
+// WithMaxEntries limits the store to n entries, evicting the least recently
+// used entry when full. n <= 0 means unbounded.
+func WithMaxEntries(n int) MemoryStoreOption {
+	return func(store *MemoryStore) {
+		store.maxEntries = n
+	}
+}
+
+// WithDefaultTTL sets the time-to-live of entries set with a ttl of 0.
+func WithDefaultTTL(ttl time.Duration) MemoryStoreOption {
+	return func(store *MemoryStore) {
+		store.defaultTTL = ttl
+	}
+}
+
+// MemoryStore is an in-memory KeyValueStore with LRU eviction. Expired
+// entries are removed when read or evicted. The zero value is an
+// unbounded store without a default TTL.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	defaultTTL time.Duration
+	lru        *list.List
+	entries    map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an instance of MemoryStore, eg
+//
+//	kvs.NewMemoryStore(kvs.WithMaxEntries(10000), kvs.WithDefaultTTL(5*time.Minute))
+func NewMemoryStore(options ...MemoryStoreOption) *MemoryStore {
+	store := &MemoryStore{}
+	for _, option := range options {
+		option(store)
+	}
+	return store
+}
+
+// Set sets a key with time-to-live. A ttl of 0 uses the default TTL, use
+// TTLNever to never expire.
+func (store *MemoryStore) Set(key, value string, ttl time.Duration) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if ttl == 0 {
+		ttl = store.defaultTTL
+	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if store.entries == nil {
+		store.lru = list.New()
+		store.entries = map[string]*list.Element{}
+	}
+	if el, ok := store.entries[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		store.lru.MoveToFront(el)
+		return nil
+	}
+
+	store.entries[key] = store.lru.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for store.maxEntries > 0 && store.lru.Len() > store.maxEntries {
+		store.removeElement(store.lru.Back())
+	}
+	return nil
+}
+
+// Get retrieves a value given key. ErrNotFound is returned if the key does
+// not exist or has expired.
+func (store *MemoryStore) Get(key string) (string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	el, ok := store.entries[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		store.removeElement(el)
+		return "", ErrNotFound
+	}
+	store.lru.MoveToFront(el)
+	return entry.value, nil
+}
+
+// Del deletes value given key.
+func (store *MemoryStore) Del(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if el, ok := store.entries[key]; ok {
+		store.removeElement(el)
+	}
+	return nil
+}
+
+// FlushDB clears all keys.
+func (store *MemoryStore) FlushDB() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.lru = nil
+	store.entries = nil
+	return nil
+}
+
+// Len returns the number of entries including expired entries which have
+// not been removed yet.
+func (store *MemoryStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return len(store.entries)
+}
+
+func (store *MemoryStore) removeElement(el *list.Element) {
+	store.lru.Remove(el)
+	delete(store.entries, el.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,57 @@
+package kvs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreMaxEntries(t *testing.T) {
+	store := NewMemoryStore(WithMaxEntries(2))
+	store.Set("a", "1", TTLNever)
+	store.Set("b", "2", TTLNever)
+
+	// a becomes most recently used so b is evicted
+	v, err := store.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", v)
+	store.Set("c", "3", TTLNever)
+
+	_, err = store.Get("b")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, 2, store.Len())
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	store := NewMemoryStore(WithDefaultTTL(10 * time.Millisecond))
+	store.Set("default", "1", 0)
+	store.Set("never", "2", TTLNever)
+	store.Set("long", "3", time.Hour)
+
+	time.Sleep(20 * time.Millisecond)
+	_, err := store.Get("default")
+	assert.Equal(t, ErrNotFound, err)
+
+	v, err := store.Get("never")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", v)
+	v, err = store.Get("long")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", v)
+}
+
+func TestMemoryStoreZeroValue(t *testing.T) {
+	var store MemoryStore
+	_, err := store.Get("a")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.NoError(t, store.Set("a", "1", 0))
+	v, err := store.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", v)
+
+	assert.NoError(t, store.Del("a"))
+	assert.NoError(t, store.FlushDB())
+	assert.Equal(t, 0, store.Len())
+}
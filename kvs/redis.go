@@ -1,18 +1,35 @@
 package kvs
 
 import (
+	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/garyburd/redigo/redis"
 	"go.uber.org/zap"
 )
 
+// RedisTimeout is the connect, read and write timeout of connections
+// created by NewRedisStore.
+var RedisTimeout = time.Second
+
+// RedisRetryAfter is how long a RedisStore stops contacting Redis after
+// failing to reach it. Get reports a miss meanwhile so queries continue
+// against the database.
+var RedisRetryAfter = 5 * time.Second
+
+// ErrUnavailable is returned by Set while Redis is unreachable.
+var ErrUnavailable = errors.New("Key value store unavailable")
+
 func newRedisPool(host, password string) *redis.Pool {
 	return &redis.Pool{
 		MaxIdle:     3,
 		IdleTimeout: 240 * time.Second,
 		Dial: func() (redis.Conn, error) {
-			c, err := redis.Dial("tcp", host)
+			c, err := redis.Dial("tcp", host,
+				redis.DialConnectTimeout(RedisTimeout),
+				redis.DialReadTimeout(RedisTimeout),
+				redis.DialWriteTimeout(RedisTimeout))
 			if err != nil {
 				return nil, err
 			}
@@ -40,7 +57,7 @@ func NewDefaultRedisStore() (KeyValueStore, error) {
 
 // NewRedisStore creates a new instance of RedisTokenStore.
 func NewRedisStore(ns string, host string, password string) (*RedisStore, error) {
-	logger.Info("Creating redis pool", zap.String("ns", ns), zap.String("host", host), zap.Bool("usingPassword", password == ""))
+	logger().Info("Creating redis pool", zap.String("ns", ns), zap.String("host", host), zap.Bool("usingPassword", password == ""))
 	pool := newRedisPool(host, password)
 	return NewRedisStoreFromPool(ns, pool), nil
 }
@@ -54,10 +71,37 @@ func NewRedisStoreFromPool(ns string, pool *redis.Pool) *RedisStore {
 type RedisStore struct {
 	pool *redis.Pool
 	ns   string
+
+	// downUntil is the UnixNano time until which Redis is not contacted
+	downUntil int64
+}
+
+// isDown determines if Redis was recently unreachable.
+func (rs *RedisStore) isDown() bool {
+	return time.Now().UnixNano() < atomic.LoadInt64(&rs.downUntil)
+}
+
+// checkErr marks Redis as down for RedisRetryAfter if err is a connection
+// failure rather than an error reply.
+func (rs *RedisStore) checkErr(err error) error {
+	if err == nil || err == redis.ErrNil {
+		return err
+	}
+	if _, ok := err.(redis.Error); ok {
+		return err
+	}
+	if !rs.isDown() {
+		logger().Warn("Redis is unreachable, bypassing cache", zap.Duration("retryAfter", RedisRetryAfter), zap.Error(err))
+	}
+	atomic.StoreInt64(&rs.downUntil, time.Now().Add(RedisRetryAfter).UnixNano())
+	return err
 }
 
 // Set sets a key's value with TTL. Use cache.TTLNever to never expire.
 func (rs *RedisStore) Set(key, value string, ttl time.Duration) error {
+	if rs.isDown() {
+		return ErrUnavailable
+	}
 	conn := rs.pool.Get()
 	defer conn.Close()
 	var err error
@@ -69,17 +113,21 @@ func (rs *RedisStore) Set(key, value string, ttl time.Duration) error {
 	} else {
 		_, err = conn.Do("SET", key, value, "PX", ttl.Nanoseconds()/NanosecondsPerMillisecond)
 	}
-	return err
+	return rs.checkErr(err)
 }
 
-// Get gets
+// Get gets a key's value. ErrNotFound is returned if the key does not exist
+// or Redis is unreachable.
 func (rs *RedisStore) Get(key string) (string, error) {
+	if rs.isDown() {
+		return "", ErrNotFound
+	}
 	conn := rs.pool.Get()
 	defer conn.Close()
 
 	key = rs.ns + key
 	s, err := redis.String(conn.Do("GET", key))
-	if err == redis.ErrNil {
+	if err = rs.checkErr(err); err == redis.ErrNil || rs.isDown() {
 		return "", ErrNotFound
 	} else if err != nil {
 		return "", err
@@ -87,13 +135,14 @@ func (rs *RedisStore) Get(key string) (string, error) {
 	return s, nil
 }
 
-// Del deletes a key
+// Del deletes a key. It is attempted even while Redis is marked
+// unreachable, a lost delete would leave a stale value once Redis is back.
 func (rs *RedisStore) Del(key string) error {
 	conn := rs.pool.Get()
 	defer conn.Close()
 	key = rs.ns + key
 	_, err := conn.Do("DEL", key)
-	return err
+	return rs.checkErr(err)
 }
 
 // FlushDB removes all keys.
@@ -101,5 +150,5 @@ func (rs *RedisStore) FlushDB() error {
 	conn := rs.pool.Get()
 	defer conn.Close()
 	_, err := conn.Do("FLUSHDB")
-	return err
+	return rs.checkErr(err)
 }
@@ -0,0 +1,41 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSetLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	SetLogger(zap.New(core))
+	defer SetLogger(nil)
+
+	assert.Nil(t, NewSelectBuilder())
+	assert.Equal(t, 1, logs.FilterMessage("Select requires 1 or more columns").Len())
+	assert.Equal(t, "dat", logs.All()[0].LoggerName)
+}
+
+func TestSetSugaredLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	SetSugaredLogger(zap.New(core).Sugar())
+	defer SetLogger(nil)
+
+	assert.Nil(t, NewUpdateBuilder(""))
+	assert.Equal(t, 1, logs.FilterMessage("Update requires a table name").Len())
+}
+
+func TestLoggedSQLTruncated(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	SetLogger(zap.New(core))
+	defer SetLogger(nil)
+	MaxLoggedSQLLength = 10
+	defer func() { MaxLoggedSQLLength = 0 }()
+
+	_, _, err := Interpolate("", []interface{}{"a long value"})
+	assert.Equal(t, ErrArgumentMismatch, err)
+	assert.Equal(t, "[a long va...(truncated, 14 total)", logs.All()[0].ContextMap()["args"])
+}
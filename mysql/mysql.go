@@ -0,0 +1,68 @@
+// Package mysql is the MySQL dialect. It renders SQL for MySQL compatible
+// databases, eg to generate portable fixtures, the runner only executes
+// Postgres.
+package mysql
+
+import (
+	"strings"
+	"time"
+
+	"github.com/casualjim/dat/common"
+)
+
+// MySQL is the MySQL dialect.
+type MySQL struct{}
+
+// New returns a new MySQL dialect.
+func New() *MySQL {
+	return &MySQL{}
+}
+
+// WriteStringLiteral writes a quoted string, escaping apostrophes and
+// backslashes which MySQL treats as escape characters by default.
+func (md *MySQL) WriteStringLiteral(buf common.BufferWriter, val string) {
+	buf.WriteRune('\'')
+	if strings.ContainsAny(val, `'\`) {
+		for _, char := range val {
+			switch char {
+			case '\'':
+				buf.WriteString(`''`)
+			case '\\':
+				buf.WriteString(`\\`)
+			default:
+				buf.WriteRune(char)
+			}
+		}
+	} else {
+		buf.WriteString(val)
+	}
+	buf.WriteRune('\'')
+}
+
+// WriteIdentifier writes an identifier quoted with backticks.
+func (md *MySQL) WriteIdentifier(buf common.BufferWriter, ident string) {
+	if ident == "" {
+		panic("Identifier is empty string")
+	}
+	if ident == "*" {
+		buf.WriteString(ident)
+		return
+	}
+
+	buf.WriteRune('`')
+	buf.WriteString(strings.Replace(ident, "`", "``", -1))
+	buf.WriteRune('`')
+}
+
+// WriteFormattedTime writes t in UTC as a DATETIME literal with microseconds
+// since DATETIME has no time zone.
+func (md *MySQL) WriteFormattedTime(buf common.BufferWriter, t time.Time) {
+	buf.WriteRune('\'')
+	buf.WriteString(t.UTC().Format("2006-01-02 15:04:05.999999"))
+	buf.WriteRune('\'')
+}
+
+// WritePlaceholder writes the ? placeholder.
+func (md *MySQL) WritePlaceholder(buf common.BufferWriter, n int) {
+	buf.WriteRune('?')
+}
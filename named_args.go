@@ -0,0 +1,139 @@
+package dat
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// NamedArgs holds the values of :name parameters in raw SQL, eg
+//
+//		DB.SQL("SELECT * FROM users WHERE org = :org AND status = :status",
+//			dat.NamedArgs{"org": 5, "status": "active"})
+//
+// Named parameters are rewritten to positional placeholders in first-seen
+// order, a repeated name reuses its placeholder. Casts (::) and names
+// within quotes, comments or dollar-quoted bodies ($$ ... $$) are left as
+// is.
+type NamedArgs map[string]interface{}
+
+// bindNamedArgs rewrites the :name parameters of sql to $n placeholders and
+// returns the positional args.
+func bindNamedArgs(sql string, named NamedArgs) (string, []interface{}) {
+	var buf bytes.Buffer
+	var args []interface{}
+	positions := map[string]int{}
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"':
+			// copy quoted literal or identifier
+			end := i + 1
+			for end < len(sql) && sql[end] != c {
+				end++
+			}
+			if end < len(sql) {
+				end++
+			}
+			buf.WriteString(sql[i:end])
+			i = end - 1
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			// copy comment to end of line
+			end := i
+			for end < len(sql) && sql[end] != '\n' {
+				end++
+			}
+			buf.WriteString(sql[i:end])
+			i = end - 1
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := blockCommentEnd(sql, i)
+			buf.WriteString(sql[i:end])
+			i = end - 1
+		case c == '$' && (i == 0 || !isNameChar(sql[i-1])) && dollarTag(sql[i:]) != "":
+			end := dollarQuoteEnd(sql, i)
+			buf.WriteString(sql[i:end])
+			i = end - 1
+		case c == ':' && i+1 < len(sql) && sql[i+1] == ':':
+			buf.WriteString("::")
+			i++
+		case c == ':' && i+1 < len(sql) && isNameStart(sql[i+1]) && (i == 0 || !isNameChar(sql[i-1])):
+			end := i + 1
+			for end < len(sql) && isNameChar(sql[end]) {
+				end++
+			}
+			name := sql[i+1 : end]
+			pos, ok := positions[name]
+			if !ok {
+				value, found := named[name]
+				if !found {
+					panic("missing value for named parameter :" + name)
+				}
+				args = append(args, value)
+				pos = len(args)
+				positions[name] = pos
+			}
+			buf.WriteRune('$')
+			buf.WriteString(strconv.Itoa(pos))
+			i = end - 1
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String(), args
+}
+
+// blockCommentEnd returns the index after the /* */ comment starting at i,
+// Postgres block comments nest.
+func blockCommentEnd(sql string, i int) int {
+	depth := 0
+	for i < len(sql) {
+		switch {
+		case strings.HasPrefix(sql[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(sql[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(sql)
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string, eg $$ or
+// $body$, if s starts with one. $1 is a placeholder, not a tag.
+func dollarTag(s string) string {
+	for end := 1; end < len(s); end++ {
+		switch c := s[end]; {
+		case c == '$':
+			return s[:end+1]
+		case end == 1 && !isNameStart(c), !isNameChar(c):
+			return ""
+		}
+	}
+	return ""
+}
+
+// dollarQuoteEnd returns the index after the dollar-quoted string starting
+// at i.
+func dollarQuoteEnd(sql string, i int) int {
+	tag := dollarTag(sql[i:])
+	end := strings.Index(sql[i+len(tag):], tag)
+	if end < 0 {
+		return len(sql)
+	}
+	return i + len(tag) + end + len(tag)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
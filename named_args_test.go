@@ -0,0 +1,50 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedArgs(t *testing.T) {
+	sql, args := SQL("SELECT * FROM users WHERE org = :org AND status = :status OR parent_org = :org",
+		NamedArgs{"org": 5, "status": "active", "unused": 1}).ToSQL()
+
+	assert.Equal(t, "SELECT * FROM users WHERE org = $1 AND status = $2 OR parent_org = $1", sql)
+	assert.Equal(t, []interface{}{5, "active"}, args)
+}
+
+func TestNamedArgsIgnoresCastsAndLiterals(t *testing.T) {
+	sql, args := SQL(`SELECT ':skip', ":skip", arr[lo:hi], :n::int -- :skip
+		FROM t WHERE x = :n`, NamedArgs{"n": 1}).ToSQL()
+
+	assert.Equal(t, `SELECT ':skip', ":skip", arr[lo:hi], $1::int -- :skip
+		FROM t WHERE x = $1`, sql)
+	assert.Equal(t, []interface{}{1}, args)
+}
+
+func TestNamedArgsIgnoresBlockCommentsAndDollarQuotes(t *testing.T) {
+	sql, args := SQL(`SELECT /* :skip /* nested :skip */ :skip */ :n, $$ :skip $$, $fn$ it's :skip $fn$
+		FROM t WHERE x = :n AND y = $1`, NamedArgs{"n": 1}).ToSQL()
+
+	assert.Equal(t, `SELECT /* :skip /* nested :skip */ :skip */ $1, $$ :skip $$, $fn$ it's :skip $fn$
+		FROM t WHERE x = $1 AND y = $1`, sql)
+	assert.Equal(t, []interface{}{1}, args)
+}
+
+func TestNamedArgsWhere(t *testing.T) {
+	sql, args := Select("id").
+		From("users").
+		Where("id = $1", 10).
+		Where("org = :org OR owner = :org", NamedArgs{"org": 5}).
+		ToSQL()
+
+	assert.Equal(t, "SELECT id FROM users WHERE (id = $1) AND (org = $2 OR owner = $2)", sql)
+	assert.Equal(t, []interface{}{10, 5}, args)
+}
+
+func TestNamedArgsMissing(t *testing.T) {
+	assert.Panics(t, func() {
+		SQL("SELECT :a", NamedArgs{})
+	})
+}
@@ -0,0 +1,89 @@
+package dat
+
+import "time"
+
+// PartitionGranularity is the time span covered by a single partition.
+type PartitionGranularity int
+
+const (
+	// PartitionDaily routes to one partition per day, eg events_2024_01_31.
+	PartitionDaily PartitionGranularity = iota
+	// PartitionMonthly routes to one partition per month, eg events_2024_01.
+	PartitionMonthly
+	// PartitionYearly routes to one partition per year, eg events_2024.
+	PartitionYearly
+)
+
+// PartitionRouter maps a time partition key to the name of the partition
+// table of a table partitioned by range.
+type PartitionRouter struct {
+	// Table is the parent table.
+	Table string
+	// Granularity is the span covered by each partition.
+	Granularity PartitionGranularity
+	// Layout is the time layout of the suffix appended to Table, see
+	// time.Format. It defaults to "2006_01_02", "2006_01" or "2006" based
+	// on Granularity.
+	Layout string
+	// Location is the time zone partition boundaries are computed in. It
+	// defaults to UTC.
+	Location *time.Location
+}
+
+// NewPartitionRouter creates a PartitionRouter for table using the default
+// layout for granularity.
+func NewPartitionRouter(table string, granularity PartitionGranularity) *PartitionRouter {
+	if table == "" {
+		panic("PartitionRouter requires a table name")
+	}
+	return &PartitionRouter{Table: table, Granularity: granularity}
+}
+
+// Name returns the name of the partition containing t.
+func (r *PartitionRouter) Name(t time.Time) string {
+	from, _ := r.Bounds(t)
+	return r.Table + "_" + from.Format(r.layout())
+}
+
+// Bounds returns the inclusive lower and exclusive upper bound of the
+// partition containing t, eg for FOR VALUES FROM (from) TO (to).
+func (r *PartitionRouter) Bounds(t time.Time) (from, to time.Time) {
+	t = t.In(r.location())
+	switch r.Granularity {
+	case PartitionDaily:
+		from = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return from, from.AddDate(0, 0, 1)
+	case PartitionMonthly:
+		from = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return from, from.AddDate(0, 1, 0)
+	case PartitionYearly:
+		from = time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+		return from, from.AddDate(1, 0, 0)
+	}
+	panic("unknown partition granularity")
+}
+
+// Route sets the table of b to the partition containing t.
+func (r *PartitionRouter) Route(b *InsertBuilder, t time.Time) *InsertBuilder {
+	return b.Into(r.Name(t))
+}
+
+func (r *PartitionRouter) layout() string {
+	if r.Layout != "" {
+		return r.Layout
+	}
+	switch r.Granularity {
+	case PartitionDaily:
+		return "2006_01_02"
+	case PartitionYearly:
+		return "2006"
+	}
+	return "2006_01"
+}
+
+func (r *PartitionRouter) location() *time.Location {
+	if r.Location != nil {
+		return r.Location
+	}
+	return time.UTC
+}
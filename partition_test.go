@@ -0,0 +1,60 @@
+package dat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionRouterMonthly(t *testing.T) {
+	r := NewPartitionRouter("events", PartitionMonthly)
+
+	assert.Equal(t, "events_2024_01", r.Name(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "events_2024_01", r.Name(time.Date(2024, 1, 31, 23, 59, 59, 999999999, time.UTC)))
+	assert.Equal(t, "events_2024_02", r.Name(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "events_2024_12", r.Name(time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC)))
+
+	from, to := r.Bounds(time.Date(2024, 12, 15, 8, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), to)
+}
+
+func TestPartitionRouterLocation(t *testing.T) {
+	r := NewPartitionRouter("events", PartitionMonthly)
+	// 2024-02-01 01:00 in UTC+2 is still January in UTC
+	east := time.FixedZone("UTC+2", 2*60*60)
+	ts := time.Date(2024, 2, 1, 1, 0, 0, 0, east)
+	assert.Equal(t, "events_2024_01", r.Name(ts))
+
+	r.Location = east
+	assert.Equal(t, "events_2024_02", r.Name(ts))
+}
+
+func TestPartitionRouterGranularity(t *testing.T) {
+	ts := time.Date(2024, 2, 29, 18, 30, 0, 0, time.UTC)
+
+	daily := NewPartitionRouter("events", PartitionDaily)
+	assert.Equal(t, "events_2024_02_29", daily.Name(ts))
+	from, to := daily.Bounds(ts)
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), to)
+
+	yearly := NewPartitionRouter("events", PartitionYearly)
+	assert.Equal(t, "events_2024", yearly.Name(ts))
+
+	custom := &PartitionRouter{Table: "events", Granularity: PartitionMonthly, Layout: "y2006m01"}
+	assert.Equal(t, "events_y2024m02", custom.Name(ts))
+}
+
+func TestPartitionRouterRoute(t *testing.T) {
+	r := NewPartitionRouter("events", PartitionMonthly)
+	ts := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	sql, args := r.Route(InsertInto("events"), ts).
+		Columns("name", "at").
+		Values("signup", ts).
+		ToSQL()
+
+	assert.Equal(t, `INSERT INTO events_2024_01 ("name","at") VALUES ($1,$2)`, sql)
+	assert.Equal(t, []interface{}{"signup", ts}, args)
+}
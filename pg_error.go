@@ -0,0 +1,120 @@
+package dat
+
+import (
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
+)
+
+// SQLSTATE codes of integrity constraint violations and deadlocks.
+const (
+	codeNotNullViolation    = "23502"
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
+	codeCheckViolation      = "23514"
+	codeDeadlockDetected    = "40P01"
+)
+
+// PgError is the detail of an error reported by Postgres.
+type PgError struct {
+	Code       string
+	Message    string
+	Detail     string
+	Hint       string
+	Schema     string
+	Table      string
+	Column     string
+	Constraint string
+	// Where is the context of the error, eg the tuple being updated when a
+	// deadlock was detected.
+	Where string
+}
+
+func (e *PgError) Error() string {
+	return "pq: " + e.Message
+}
+
+// AsPgError returns the Postgres error of err, which may be wrapped in a
+// QueryError, eg to turn a unique violation of users_email_key into an
+// "email taken" error. Errors of lib/pq and pgx are supported. It returns
+// false if err is not a Postgres error.
+func AsPgError(err error) (*PgError, bool) {
+	var pe *PgError
+	if errors.As(err, &pe) {
+		return pe, true
+	}
+	var pqe *pq.Error
+	if errors.As(err, &pqe) {
+		return &PgError{
+			Code:       string(pqe.Code),
+			Message:    pqe.Message,
+			Detail:     pqe.Detail,
+			Hint:       pqe.Hint,
+			Schema:     pqe.Schema,
+			Table:      pqe.Table,
+			Column:     pqe.Column,
+			Constraint: pqe.Constraint,
+			Where:      pqe.Where,
+		}, true
+	}
+	var pgxe *pgconn.PgError
+	if errors.As(err, &pgxe) {
+		return &PgError{
+			Code:       pgxe.Code,
+			Message:    pgxe.Message,
+			Detail:     pgxe.Detail,
+			Hint:       pgxe.Hint,
+			Schema:     pgxe.SchemaName,
+			Table:      pgxe.TableName,
+			Column:     pgxe.ColumnName,
+			Constraint: pgxe.ConstraintName,
+			Where:      pgxe.Where,
+		}, true
+	}
+	return nil, false
+}
+
+// IsUniqueViolation determines if err is a unique_violation.
+func IsUniqueViolation(err error) bool {
+	return hasCode(err, codeUniqueViolation)
+}
+
+// IsForeignKeyViolation determines if err is a foreign_key_violation.
+func IsForeignKeyViolation(err error) bool {
+	return hasCode(err, codeForeignKeyViolation)
+}
+
+// IsCheckViolation determines if err is a check_violation.
+func IsCheckViolation(err error) bool {
+	return hasCode(err, codeCheckViolation)
+}
+
+// IsNotNullViolation determines if err is a not_null_violation.
+func IsNotNullViolation(err error) bool {
+	return hasCode(err, codeNotNullViolation)
+}
+
+// IsDeadlock determines if err is a deadlock_detected.
+func IsDeadlock(err error) bool {
+	return hasCode(err, codeDeadlockDetected)
+}
+
+// DeadlockDetail returns the processes and locks involved in the deadlock
+// reported by err, eg "Process 1234 waits for ShareLock on transaction 567;
+// blocked by process 890." It returns "" if err is not a deadlock_detected.
+func DeadlockDetail(err error) string {
+	pe, ok := AsPgError(err)
+	if !ok || pe.Code != codeDeadlockDetected {
+		return ""
+	}
+	if pe.Where != "" {
+		return pe.Detail + "\n" + pe.Where
+	}
+	return pe.Detail
+}
+
+func hasCode(err error, code string) bool {
+	pe, ok := AsPgError(err)
+	return ok && pe.Code == code
+}
@@ -0,0 +1,110 @@
+package dat
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsPgError(t *testing.T) {
+	err := NewQueryError(&pq.Error{
+		Code:       "23505",
+		Message:    `duplicate key value violates unique constraint "users_email_key"`,
+		Detail:     "Key (email)=(a@b.com) already exists.",
+		Table:      "users",
+		Constraint: "users_email_key",
+	}, "INSERT INTO users (email) VALUES ($1)", nil)
+
+	pe, ok := AsPgError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "23505", pe.Code)
+		assert.Equal(t, "users", pe.Table)
+		assert.Equal(t, "users_email_key", pe.Constraint)
+		assert.Equal(t, "Key (email)=(a@b.com) already exists.", pe.Detail)
+	}
+	assert.True(t, IsUniqueViolation(err))
+	assert.False(t, IsForeignKeyViolation(err))
+
+	_, ok = AsPgError(errors.New("boom"))
+	assert.False(t, ok)
+	assert.False(t, IsUniqueViolation(nil))
+}
+
+func TestPgErrorViolations(t *testing.T) {
+	assert.True(t, IsForeignKeyViolation(&pq.Error{Code: "23503"}))
+	assert.True(t, IsCheckViolation(&pq.Error{Code: "23514"}))
+	assert.True(t, IsNotNullViolation(&pq.Error{Code: "23502"}))
+	assert.False(t, IsNotNullViolation(&pq.Error{Code: "23505"}))
+}
+
+func TestDeadlockDetail(t *testing.T) {
+	err := NewQueryError(&pq.Error{
+		Code:    "40P01",
+		Message: "deadlock detected",
+		Detail:  "Process 12 waits for ShareLock on transaction 34; blocked by process 56.",
+		Where:   `while updating tuple (0,1) in relation "people"`,
+	}, "UPDATE people SET name = $1", nil)
+
+	assert.True(t, IsDeadlock(err))
+	assert.Equal(t, "Process 12 waits for ShareLock on transaction 34; blocked by process 56.\n"+
+		`while updating tuple (0,1) in relation "people"`, DeadlockDetail(err))
+	pe, _ := AsPgError(err)
+	assert.Equal(t, `while updating tuple (0,1) in relation "people"`, pe.Where)
+
+	assert.False(t, IsDeadlock(&pq.Error{Code: "40001"}))
+	assert.Equal(t, "", DeadlockDetail(&pq.Error{Code: "40001", Detail: "x"}))
+	assert.Equal(t, "", DeadlockDetail(errors.New("boom")))
+}
+
+func TestAsPgErrorPgx(t *testing.T) {
+	err := NewQueryError(&pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23503",
+		Message:        `insert or update on table "posts" violates foreign key constraint "posts_user_id_fkey"`,
+		TableName:      "posts",
+		ConstraintName: "posts_user_id_fkey",
+	}, "INSERT INTO posts (user_id) VALUES ($1)", nil)
+	assert.Equal(t, "23503", err.Code)
+
+	pe, ok := AsPgError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "23503", pe.Code)
+		assert.Equal(t, "posts", pe.Table)
+		assert.Equal(t, "posts_user_id_fkey", pe.Constraint)
+		assert.Contains(t, pe.Message, "violates foreign key constraint")
+	}
+	assert.True(t, IsForeignKeyViolation(err))
+}
+
+func TestAsPgErrorPgxDriver(t *testing.T) {
+	if os.Getenv("DAT_DRIVER") == "" {
+		t.Skip("env DAT_DRIVER is not set")
+	}
+	db, err := sql.Open("pgx", os.Getenv("DAT_DSN"))
+	assert.NoError(t, err)
+	defer db.Close()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	defer tx.Rollback()
+
+	_, err = tx.Exec("CREATE TEMP TABLE pgx_errors (id int CONSTRAINT pgx_errors_pkey PRIMARY KEY)")
+	assert.NoError(t, err)
+	_, err = tx.Exec("INSERT INTO pgx_errors VALUES (1), (1)")
+	err = NewQueryError(err, "INSERT INTO pgx_errors VALUES (1), (1)", nil)
+	assert.Equal(t, "23505", err.(*QueryError).Code)
+
+	pe, ok := AsPgError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "23505", pe.Code)
+		assert.Equal(t, "pgx_errors", pe.Table)
+		assert.Equal(t, "pgx_errors_pkey", pe.Constraint)
+		assert.Equal(t, "Key (id)=(1) already exists.", pe.Detail)
+	}
+	assert.True(t, IsUniqueViolation(err))
+}
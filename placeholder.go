@@ -0,0 +1,59 @@
+package dat
+
+import "strconv"
+
+// rewritePlaceholders rewrites the $N placeholders of sql with pw, ordering
+// args by appearance. Placeholders within quotes and comments are left as
+// is.
+func rewritePlaceholders(sql string, args []interface{}, pw PlaceholderWriter) (string, []interface{}, error) {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	ordered := make([]interface{}, 0, len(args))
+
+	lenSQL := len(sql)
+	for i := 0; i < lenSQL; i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < lenSQL && sql[end] != c {
+				end++
+			}
+			if end == lenSQL {
+				end--
+			}
+			buf.WriteString(sql[i : end+1])
+			i = end
+		case c == '-' && i+1 < lenSQL && sql[i+1] == '-':
+			end := i
+			for end < lenSQL && sql[end] != '\n' {
+				end++
+			}
+			buf.WriteString(sql[i:end])
+			i = end - 1
+		case c == '$' && i+1 < lenSQL && isDigit(sql[i+1]) && (i == 0 || !isIdentChar(sql[i-1])):
+			end := i + 1
+			for end < lenSQL && isDigit(sql[end]) {
+				end++
+			}
+			n, _ := strconv.Atoi(sql[i+1 : end])
+			if n < 1 || n > len(args) {
+				return "", nil, ErrArgumentMismatch
+			}
+			ordered = append(ordered, args[n-1])
+			pw.WritePlaceholder(buf, len(ordered))
+			i = end - 1
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String(), ordered, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
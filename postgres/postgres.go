@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"bytes"
+	"encoding/hex"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -32,11 +33,42 @@ func randomizePgDollarTag() {
 
 // GetPgDollarTag returns the current Postgres string dollar quoting tag.
 func GetPgDollarTag() string {
+	pgDollarMutex.Lock()
+	defer pgDollarMutex.Unlock()
 	return pgDollarTag
 }
 
+// dollarTag returns a dollar quoting tag which cannot terminate val early,
+// or "" if none was found.
+func dollarTag(val string) string {
+	tag := GetPgDollarTag()
+	// a trailing "$abc" would join the closing "$abc$" tag
+	for i := 0; strings.Contains(val+"$", tag); i++ {
+		if i == 3 {
+			return ""
+		}
+		randomizePgDollarTag()
+		tag = GetPgDollarTag()
+	}
+	return tag
+}
+
+const nulCharMsg = "postgres doesn't support NULL char in text, see http://stackoverflow.com/questions/1347646/postgres-error-on-insert-error-invalid-byte-sequence-for-encoding-utf8-0x0"
+
 // Postgres is the PostgeSQL dialect.
-type Postgres struct{}
+type Postgres struct {
+	// DollarQuoting writes string literals containing an apostrophe as
+	// dollar quoted strings, eg $abc$it's$abc$, rather than doubling each
+	// apostrophe. Long strings are always dollar quoted.
+	DollarQuoting bool
+	// CastTimestamptz casts interpolated times to timestamptz, eg
+	// '2004-01-01T01:01:01+05:30'::timestamptz, where Postgres cannot infer
+	// the type of the literal such as SELECT $1. Comparing with or storing
+	// into a timestamptz column keeps the instant without the cast, while
+	// the cast shifts times stored into a timestamp column to the server
+	// timezone.
+	CastTimestamptz bool
+}
 
 // New returns a new Postgres dialect.
 func New() *Postgres {
@@ -55,40 +87,43 @@ func (pd *Postgres) WriteStringLiteral(buf common.BufferWriter, val string) {
 		return
 	}
 
-	hasTag := true
-
 	// don't use double dollar quote strings unless the string is long enough
-	if len(val) > 64 {
-		// if pgDollarTag unique tag is in string, try to create a new one (only once though)
-		hasTag = strings.Contains(val, pgDollarTag)
-		if hasTag {
-			randomizePgDollarTag()
-			hasTag = strings.Contains(val, pgDollarTag)
+	if len(val) > 64 || (pd.DollarQuoting && strings.Contains(val, "'")) {
+		if tag := dollarTag(val); tag != "" {
+			if strings.IndexByte(val, 0) >= 0 {
+				panic(nulCharMsg)
+			}
+			buf.WriteString(tag)
+			buf.WriteString(val)
+			buf.WriteString(tag)
+			return
 		}
 	}
 
-	if hasTag {
-		buf.WriteRune('\'')
-		if strings.Contains(val, "'") {
-			for _, char := range val {
-				// apos
-				if char == '\'' {
-					buf.WriteString(`''`)
-				} else if char == 0 {
-					panic("postgres doesn't support NULL char in text, see http://stackoverflow.com/questions/1347646/postgres-error-on-insert-error-invalid-byte-sequence-for-encoding-utf8-0x0")
-				} else {
-					buf.WriteRune(char)
-				}
+	buf.WriteRune('\'')
+	if strings.Contains(val, "'") {
+		for _, char := range val {
+			// apos
+			if char == '\'' {
+				buf.WriteString(`''`)
+			} else if char == 0 {
+				panic(nulCharMsg)
+			} else {
+				buf.WriteRune(char)
 			}
-		} else {
-			buf.WriteString(val)
 		}
-		buf.WriteRune('\'')
 	} else {
-		buf.WriteString(pgDollarTag)
 		buf.WriteString(val)
-		buf.WriteString(pgDollarTag)
 	}
+	buf.WriteRune('\'')
+}
+
+// WriteBytesLiteral writes b as a hex format bytea literal, eg
+// '\x00ff'::bytea.
+func (pd *Postgres) WriteBytesLiteral(buf common.BufferWriter, b []byte) {
+	buf.WriteString(`'\x`)
+	buf.WriteString(hex.EncodeToString(b))
+	buf.WriteString(`'::bytea`)
 }
 
 // WriteIdentifier writes escaped identifier.
@@ -102,15 +137,20 @@ func (pd *Postgres) WriteIdentifier(buf common.BufferWriter, ident string) {
 	}
 
 	buf.WriteRune('"')
-	buf.WriteString(ident)
+	if strings.Contains(ident, `"`) {
+		buf.WriteString(strings.Replace(ident, `"`, `""`, -1))
+	} else {
+		buf.WriteString(ident)
+	}
 	buf.WriteRune('"')
 }
 
-// WriteFormattedTime formats t into a format postgres understands.
+// WriteFormattedTime formats t into a literal which keeps the offset of t's
+// location, so it is compared with timestamptz columns as the same instant
+// regardless of the server timezone. See CastTimestamptz.
 // Taken with gratitude from pq: https://github.com/lib/pq/blob/b269bd035a727d6c1081f76e7a239a1b00674c40/encode.go#L403
 func (pd *Postgres) WriteFormattedTime(buf common.BufferWriter, t time.Time) {
 	buf.WriteRune('\'')
-	defer buf.WriteRune('\'')
 	// XXX: This doesn't currently deal with infinity values
 
 	// Need to send dates before 0001 A.D. with " BC" suffix, instead of the
@@ -142,4 +182,8 @@ func (pd *Postgres) WriteFormattedTime(buf common.BufferWriter, t time.Time) {
 	if bc {
 		buf.WriteString(" BC")
 	}
+	buf.WriteRune('\'')
+	if pd.CastTimestamptz {
+		buf.WriteString("::timestamptz")
+	}
 }
@@ -0,0 +1,69 @@
+package dat
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// IncludeArgsInErrors includes the args of a failed statement in its
+// QueryError. It is off by default so secrets bound as args do not leak
+// into logs and API responses.
+var IncludeArgsInErrors bool
+
+// QueryError is the error returned by runners when the database fails to
+// execute a statement.
+type QueryError struct {
+	// SQL is the statement as sent to the database, interpolated if
+	// interpolation is enabled.
+	SQL string
+	// Args are the bind args of the statement, nil unless
+	// IncludeArgsInErrors is set.
+	Args []interface{}
+	// Code is the SQLSTATE of the error, empty if the driver does not report
+	// it.
+	Code string
+	// Err is the driver error.
+	Err error
+}
+
+// NewQueryError creates a QueryError for the driver error err which
+// occurred executing sql with args.
+func NewQueryError(err error, sql string, args []interface{}) *QueryError {
+	qe := &QueryError{SQL: sql, Code: sqlState(err), Err: err}
+	if IncludeArgsInErrors {
+		qe.Args = args
+	}
+	return qe
+}
+
+func (e *QueryError) Error() string {
+	if e.Args != nil {
+		return fmt.Sprintf("%v; sql: %s; args: %v", e.Err, e.SQL, e.Args)
+	}
+	return fmt.Sprintf("%v; sql: %s", e.Err, e.SQL)
+}
+
+// Unwrap returns the driver error.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the driver error of a QueryError, otherwise err.
+func Cause(err error) error {
+	if qe, ok := err.(*QueryError); ok {
+		return qe.Err
+	}
+	return err
+}
+
+// sqlState returns the SQLSTATE of a pq or pgx error.
+func sqlState(err error) string {
+	switch e := err.(type) {
+	case *pq.Error:
+		return string(e.Code)
+	case interface{ SQLState() string }:
+		return e.SQLState()
+	}
+	return ""
+}
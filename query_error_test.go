@@ -0,0 +1,39 @@
+package dat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryError(t *testing.T) {
+	pe := &pq.Error{Code: "23505", Message: "duplicate key"}
+	err := NewQueryError(pe, "INSERT INTO users (email) VALUES ($1)", []interface{}{"a@b.com"})
+
+	assert.Equal(t, "23505", err.Code)
+	assert.Nil(t, err.Args)
+	assert.Equal(t, "pq: duplicate key; sql: INSERT INTO users (email) VALUES ($1)", err.Error())
+	assert.Exactly(t, pe, err.Unwrap())
+	assert.Exactly(t, pe, Cause(err))
+
+	boom := errors.New("boom")
+	assert.Exactly(t, boom, Cause(boom))
+	assert.Equal(t, "", NewQueryError(boom, "SELECT 1", nil).Code)
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "state " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestQueryErrorArgs(t *testing.T) {
+	IncludeArgsInErrors = true
+	defer func() { IncludeArgsInErrors = false }()
+
+	err := NewQueryError(sqlStateError("23503"), "DELETE FROM users WHERE id = $1", []interface{}{1})
+	assert.Equal(t, "23503", err.Code)
+	assert.Equal(t, []interface{}{1}, err.Args)
+	assert.Equal(t, "state 23503; sql: DELETE FROM users WHERE id = $1; args: [1]", err.Error())
+}
@@ -0,0 +1,200 @@
+package dat
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RangeValue is a Postgres range, eg '[1,10)'::int4range. A nil bound is
+// unbounded. Scanned bounds are the text of the bound.
+type RangeValue struct {
+	// Type is the range type, eg int4range or tstzrange.
+	Type string
+	// Lower and Upper are the bounds, nil when unbounded.
+	Lower, Upper interface{}
+	// LowerInc and UpperInc determine if the bounds are inclusive.
+	LowerInc, UpperInc bool
+	// Empty is true for the empty range.
+	Empty bool
+}
+
+// Range creates a range from lower to upper. bounds is one of "[)", "[]",
+// "()" or "(]", where brackets are inclusive. The range type is inferred
+// from the bounds: int4range for int and int32, int8range for int64,
+// numrange for floats and tstzrange for time.Time. Use As for other types.
+func Range(lower, upper interface{}, bounds string) *RangeValue {
+	if len(bounds) != 2 || !strings.ContainsAny(bounds[:1], "[(") || !strings.ContainsAny(bounds[1:], "])") {
+		panic(fmt.Sprintf("Range bounds must be one of [), [], () or (], got %q", bounds))
+	}
+	r := &RangeValue{
+		Lower:    lower,
+		Upper:    upper,
+		LowerInc: bounds[0] == '[',
+		UpperInc: bounds[1] == ']',
+	}
+	r.Type = rangeType(lower)
+	if r.Type == "" {
+		r.Type = rangeType(upper)
+	}
+	return r
+}
+
+// As sets the range type, eg tsrange or daterange.
+func (r *RangeValue) As(rangeType string) *RangeValue {
+	r.Type = rangeType
+	return r
+}
+
+func rangeType(v interface{}) string {
+	switch v.(type) {
+	case int, int32, int16, int8, uint16, uint8:
+		return "int4range"
+	case int64, uint32, uint, uint64:
+		return "int8range"
+	case float32, float64:
+		return "numrange"
+	case time.Time:
+		return "tstzrange"
+	}
+	return ""
+}
+
+// Value implements driver.Valuer.
+func (r RangeValue) Value() (driver.Value, error) {
+	if r.Empty {
+		return "empty", nil
+	}
+	var buf bytes.Buffer
+	if r.LowerInc {
+		buf.WriteRune('[')
+	} else {
+		buf.WriteRune('(')
+	}
+	if err := writeRangeBound(&buf, r.Lower); err != nil {
+		return nil, err
+	}
+	buf.WriteRune(',')
+	if err := writeRangeBound(&buf, r.Upper); err != nil {
+		return nil, err
+	}
+	if r.UpperInc {
+		buf.WriteRune(']')
+	} else {
+		buf.WriteRune(')')
+	}
+	return buf.String(), nil
+}
+
+func writeRangeBound(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case nil:
+	case time.Time:
+		buf.WriteRune('"')
+		buf.WriteString(t.Format("2006-01-02 15:04:05.999999999Z07:00"))
+		buf.WriteRune('"')
+	case string:
+		buf.WriteRune('"')
+		buf.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(t))
+		buf.WriteRune('"')
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprintf(buf, "%d", t)
+	case float32:
+		buf.WriteString(strconv.FormatFloat(float64(t), 'f', -1, 32))
+	case float64:
+		buf.WriteString(strconv.FormatFloat(t, 'f', -1, 64))
+	default:
+		return fmt.Errorf("Range does not support bounds of type %T", v)
+	}
+	return nil
+}
+
+// SQLType implements SQLTyper.
+func (r RangeValue) SQLType() string {
+	return r.Type
+}
+
+// Scan implements sql.Scanner. The bounds are scanned as strings.
+func (r *RangeValue) Scan(src interface{}) error {
+	var s string
+	switch t := src.(type) {
+	case []byte:
+		s = string(t)
+	case string:
+		s = t
+	default:
+		return fmt.Errorf("Cannot scan %T into RangeValue", src)
+	}
+
+	*r = RangeValue{Type: r.Type}
+	if s == "empty" {
+		r.Empty = true
+		return nil
+	}
+	if len(s) < 3 {
+		return fmt.Errorf("Invalid range %q", s)
+	}
+	r.LowerInc = s[0] == '['
+	r.UpperInc = s[len(s)-1] == ']'
+
+	bounds, err := splitRangeBounds(s[1 : len(s)-1])
+	if err != nil {
+		return fmt.Errorf("Invalid range %q: %v", s, err)
+	}
+	r.Lower, r.Upper = bounds[0], bounds[1]
+	return nil
+}
+
+// splitRangeBounds splits the text between the brackets of a range into
+// its bounds, unquoting them. An empty unquoted bound is nil.
+func splitRangeBounds(s string) ([2]interface{}, error) {
+	var bounds [2]interface{}
+	var buf bytes.Buffer
+	i, quoted, inQuote, escaped := 0, false, false, false
+	for _, c := range s {
+		switch {
+		case escaped:
+			buf.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = true
+			inQuote = !inQuote
+		case c == ',' && !inQuote:
+			if i > 0 {
+				return bounds, fmt.Errorf("too many bounds")
+			}
+			if buf.Len() > 0 || quoted {
+				bounds[i] = buf.String()
+			}
+			buf.Reset()
+			i, quoted = 1, false
+		default:
+			buf.WriteRune(c)
+		}
+	}
+	if i != 1 {
+		return bounds, fmt.Errorf("missing bound")
+	}
+	if buf.Len() > 0 || quoted {
+		bounds[1] = buf.String()
+	}
+	return bounds, nil
+}
+
+// RangeOverlap creates a condition which is true when the range column
+// overlaps r, eg during && $1.
+func RangeOverlap(column string, r *RangeValue) *Expression {
+	return Expr(column+" && $1", r)
+}
+
+// RangeContains creates a condition which is true when the range column
+// contains value, eg during @> $1. value is either a range or an element,
+// which may need a cast if it is not interpolated.
+func RangeContains(column string, value interface{}) *Expression {
+	return Expr(column+" @> $1", value)
+}
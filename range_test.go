@@ -0,0 +1,51 @@
+package dat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeInterpolate(t *testing.T) {
+	start := time.Date(2019, 1, 2, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	sql, args, err := Interpolate("SELECT $1, $2, $3, $4, $5", []interface{}{
+		Range(1, 10, "[)"),
+		Range(int64(5), nil, "(]"),
+		Range(1.5, 2.25, "[]"),
+		Range(start, end, "[)"),
+		Range("2019-01-01", "2019-02-01", "[)").As("daterange"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT '[1,10)'::int4range, '(5,]'::int8range, '[1.5,2.25]'::numrange, '["2019-01-02 09:00:00Z","2019-01-02 10:00:00Z")'::tstzrange, '["2019-01-01","2019-02-01")'::daterange`, sql)
+	assert.Empty(t, args)
+
+	assert.Panics(t, func() { Range(1, 2, "[[") })
+}
+
+func TestRangeScan(t *testing.T) {
+	var r RangeValue
+	assert.NoError(t, r.Scan([]byte(`[1,10)`)))
+	assert.Equal(t, RangeValue{Lower: "1", Upper: "10", LowerInc: true}, r)
+
+	assert.NoError(t, r.Scan([]byte(`("2019-01-02 09:00:00+00",]`)))
+	assert.Equal(t, RangeValue{Lower: "2019-01-02 09:00:00+00", UpperInc: true}, r)
+
+	assert.NoError(t, r.Scan("empty"))
+	assert.True(t, r.Empty)
+
+	assert.Error(t, r.Scan([]byte(`[1)`)))
+	assert.Error(t, r.Scan(1))
+}
+
+func TestRangeOperators(t *testing.T) {
+	sql, args := Select("id").
+		From("bookings").
+		Where(RangeOverlap("during", Range(1, 5, "[)"))).
+		Where(RangeContains("during", 3)).
+		ToSQL()
+	assert.Equal(t, `SELECT id FROM bookings WHERE (during && $1) AND (during @> $2)`, sql)
+	assert.Equal(t, []interface{}{Range(1, 5, "[)"), 3}, args)
+}
@@ -9,8 +9,14 @@ type RawBuilder struct {
 	args           []interface{}
 }
 
-// NewRawBuilder creates a new RawBuilder for the given SQL string and arguments
+// NewRawBuilder creates a new RawBuilder for the given SQL string and arguments.
+// The arguments may be a single NamedArgs for SQL using :name parameters.
 func NewRawBuilder(sql string, args ...interface{}) *RawBuilder {
+	if len(args) == 1 {
+		if named, ok := args[0].(NamedArgs); ok {
+			sql, args = bindNamedArgs(sql, named)
+		}
+	}
 	return &RawBuilder{sql: sql, args: args, isInterpolated: EnableInterpolation}
 }
 
@@ -334,7 +334,9 @@ func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc func(string)
 				continue
 			}
 
-			// bfs search of anonymous embedded structs
+			// bfs search of anonymous embedded structs. mgutz: named struct
+			// fields only, an untagged struct field is not a column, nor
+			// are its fields
 			if f.Anonymous {
 				pp := tq.pp
 				if tag != "" {
@@ -350,7 +352,8 @@ func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc func(string)
 				}
 				fi.Children = make([]*FieldInfo, nChildren)
 				queue = append(queue, typeQueue{Deref(f.Type), &fi, pp})
-			} else if fi.Zero.Kind() == reflect.Struct || (fi.Zero.Kind() == reflect.Ptr && fi.Zero.Type().Elem().Kind() == reflect.Struct) {
+			} else if _, isJSON := fi.Options["json"]; fi.Name != "" && !isJSON && (fi.Zero.Kind() == reflect.Struct || (fi.Zero.Kind() == reflect.Ptr && fi.Zero.Type().Elem().Kind() == reflect.Struct)) {
+				// mgutz: a json column is a single value
 				fi.Index = apnd(tq.fi.Index, fieldPos)
 				fi.Children = make([]*FieldInfo, Deref(f.Type).NumField())
 				queue = append(queue, typeQueue{Deref(f.Type), &fi, fi.Path})
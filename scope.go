@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"regexp"
 	"strings"
+
+	"github.com/casualjim/dat/common"
 )
 
 // M is a generic map from string to interface{}
@@ -35,6 +37,16 @@ func NewScope(sql string, fields M) *MapScope {
 	return &MapScope{SQL: sql, Fields: fields}
 }
 
+// SoftDelete creates a scope which excludes rows where column is set, e.g.
+//
+//	notDeleted := dat.SoftDelete("deleted_at")
+//	DB.Select("*").From("posts").ScopeMap(notDeleted, nil)
+//
+// Use Unscoped on a builder to include deleted rows.
+func SoftDelete(column string) *MapScope {
+	return NewScope("WHERE "+column+" IS NULL", nil)
+}
+
 // Clone creates a clone of scope and merges fields.
 func (scope *MapScope) mergeClone(fields M) *MapScope {
 	newm := M{}
@@ -90,6 +102,25 @@ func escapeScopeTable(sql string, table string) string {
 	return strings.Replace(sql, ":TABLE", quoted, -1)
 }
 
+// writeScope writes the non-WHERE portion of scope to buf and returns
+// fragments with the scope's WHERE condition appended. fragments is never
+// modified so the builder may be serialized more than once.
+func writeScope(buf common.BufferWriter, scope Scope, table string, fragments []*whereFragment) []*whereFragment {
+	if scope == nil {
+		return fragments
+	}
+	sql, args := scope.ToSQL(table)
+	sql, where := splitWhere(sql)
+	buf.WriteString(sql)
+	where = strings.TrimSpace(where)
+	if where == "" {
+		return fragments
+	}
+	merged := make([]*whereFragment, len(fragments), len(fragments)+1)
+	copy(merged, fragments)
+	return append(merged, newWhereFragment(where, args))
+}
+
 var reWhereClause = regexp.MustCompile(`\s*(WHERE|where)\b`)
 
 // splitWhere splits a query on the word WHERE
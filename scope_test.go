@@ -0,0 +1,44 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftDeleteScope(t *testing.T) {
+	notDeleted := SoftDelete("deleted_at")
+
+	b := Select("id").From("posts").UseScope(notDeleted).Where("user_id = $1", 1)
+	sql, args := b.ToSQL()
+	assert.Equal(t, "SELECT id FROM posts WHERE (user_id = $1) AND (deleted_at IS NULL)", sql)
+	assert.Exactly(t, []interface{}{1}, args)
+
+	// serializing again must not duplicate the scope condition
+	sql, _ = b.ToSQL()
+	assert.Equal(t, "SELECT id FROM posts WHERE (user_id = $1) AND (deleted_at IS NULL)", sql)
+
+	sql, args = b.Unscoped().ToSQL()
+	assert.Equal(t, "SELECT id FROM posts WHERE (user_id = $1)", sql)
+	assert.Exactly(t, []interface{}{1}, args)
+
+	sql, args = Update("posts").Set("title", "new").UseScope(notDeleted).Where("id = $1", 2).ToSQL()
+	assert.Equal(t, `UPDATE "posts" SET "title" = $1 WHERE (id = $2) AND (deleted_at IS NULL)`, sql)
+	assert.Exactly(t, []interface{}{"new", 2}, args)
+
+	sql, args = DeleteFrom("posts").ScopeMap(notDeleted, nil).Where("id = $1", 3).ToSQL()
+	assert.Equal(t, "DELETE FROM posts WHERE (id = $1) AND (deleted_at IS NULL)", sql)
+	assert.Exactly(t, []interface{}{3}, args)
+}
+
+func TestScopeWithWhere(t *testing.T) {
+	byAuthor := NewScope("WHERE author_id = :author", M{"author": 0})
+
+	sql, args := Update("posts").Set("state", "archived").ScopeMap(byAuthor, M{"author": 10}).Where("id = $1", 2).ToSQL()
+	assert.Equal(t, `UPDATE "posts" SET "state" = $1 WHERE (id = $2) AND (author_id = $3)`, sql)
+	assert.Exactly(t, []interface{}{"archived", 2, 10}, args)
+
+	sql, args = DeleteFrom("posts").ScopeMap(byAuthor, M{"author": 10}).Unscoped().Where("id = $1", 2).ToSQL()
+	assert.Equal(t, "DELETE FROM posts WHERE (id = $1)", sql)
+	assert.Exactly(t, []interface{}{2}, args)
+}
@@ -0,0 +1,89 @@
+package dat
+
+import (
+	"database/sql/driver"
+	"strings"
+)
+
+// redactedValue replaces secrets in logs and errors.
+const redactedValue = "***"
+
+// SecretArg is a sensitive arg, see Secret.
+type SecretArg struct {
+	value interface{}
+}
+
+// Secret wraps a sensitive arg, eg a password hash or token. It is sent to
+// the database unchanged but logged as ***. Secrets are never interpolated.
+func Secret(value interface{}) SecretArg {
+	return SecretArg{value: value}
+}
+
+// Value implements driver.Valuer, converting the wrapped value.
+func (s SecretArg) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(s.value)
+}
+
+func (s SecretArg) String() string {
+	return redactedValue
+}
+
+// GoString redacts the value when formatted with %#v.
+func (s SecretArg) GoString() string {
+	return redactedValue
+}
+
+var redactedColumns = map[string]bool{}
+
+// RedactColumns marks columns as sensitive. Their values given to the
+// insert, update and upsert builders or in where maps are wrapped with
+// Secret. Call it at startup, it is not safe to use concurrently with
+// builders.
+func RedactColumns(columns ...string) {
+	for _, c := range columns {
+		redactedColumns[strings.ToLower(c)] = true
+	}
+}
+
+// isRedacted returns whether column, which may be qualified with its
+// table, is redacted.
+func isRedacted(column string) bool {
+	if len(redactedColumns) == 0 {
+		return false
+	}
+	if i := strings.LastIndexByte(column, '.'); i >= 0 {
+		column = column[i+1:]
+	}
+	return redactedColumns[strings.ToLower(strings.Trim(column, `"`))]
+}
+
+// redact wraps v with Secret if column is redacted. Expressions and nil
+// values are never secret.
+func redact(column string, v interface{}) interface{} {
+	if !isRedacted(column) {
+		return v
+	}
+	switch v.(type) {
+	case SecretArg, Expressioner, UnsafeString:
+		return v
+	}
+	if isNilPointer(v) {
+		return v
+	}
+	return Secret(v)
+}
+
+// redactRow wraps the values of redacted columns of a row with Secret.
+func redactRow(columns []string, row []interface{}) []interface{} {
+	if len(redactedColumns) == 0 {
+		return row
+	}
+	redacted := make([]interface{}, len(row))
+	for i, v := range row {
+		if i < len(columns) {
+			v = redact(columns[i], v)
+		}
+		redacted[i] = v
+	}
+	return redacted
+}
@@ -0,0 +1,44 @@
+package dat
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret(t *testing.T) {
+	s := Secret("hash")
+	v, err := s.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Value("hash"), v)
+	assert.Equal(t, "[1 ***]", fmt.Sprintf("%v", []interface{}{1, s}))
+	assert.Equal(t, "***", fmt.Sprintf("%#v", s))
+}
+
+func TestSecretNotInterpolated(t *testing.T) {
+	sql, args, err := Interpolate("SELECT * FROM users WHERE email = $1 AND token = $2", []interface{}{"a@b.c", Secret("t0k3n")})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE email = 'a@b.c' AND token = $1", sql)
+	assert.Equal(t, []interface{}{Secret("t0k3n")}, args)
+}
+
+func TestRedactColumns(t *testing.T) {
+	RedactColumns("Password")
+	defer func() { redactedColumns = map[string]bool{} }()
+
+	_, args := NewInsertBuilder("users").Columns("email", "password").Values("a@b.c", "hash").ToSQL()
+	assert.Equal(t, []interface{}{"a@b.c", Secret("hash")}, args)
+
+	_, args = NewUpdateBuilder("users").Set("password", "hash").Set("updated_at", NOW).Where(Eq{"users.password": "old"}).ToSQL()
+	assert.Equal(t, []interface{}{Secret("hash"), NOW, Secret("old")}, args)
+
+	sql, args := NewSelectBuilder("id").From("users").Where(Eq{"password": []string{"a", "b"}}).ToSQL()
+	assert.Equal(t, `SELECT id FROM users WHERE ("password" IN ($1,$2))`, sql)
+	assert.Equal(t, []interface{}{Secret("a"), Secret("b")}, args)
+
+	var nilPassword *string
+	_, args = NewUpdateBuilder("users").Set("password", nilPassword).Where("id = $1", 1).ToSQL()
+	assert.Equal(t, []interface{}{nilPassword, 1}, args)
+}
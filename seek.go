@@ -0,0 +1,196 @@
+package dat
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// SeekDirection is the direction of keyset pagination, see Seek.
+type SeekDirection int
+
+const (
+	// After seeks the rows after the cursor in ascending order.
+	After SeekDirection = iota
+	// Before seeks the rows before the cursor in descending order.
+	Before
+)
+
+// reSeekColumn matches an optionally qualified column, whose parts may be
+// quoted, eg p.created_at or "p"."createdAt".
+var reSeekColumn = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_$]*|"[^"]+")(\.([A-Za-z_][A-Za-z0-9_$]*|"[^"]+"))*$`)
+
+// seek is the keyset of a SelectBuilder
+type seek struct {
+	columns []string
+	last    []interface{}
+	dir     SeekDirection
+}
+
+// Seek paginates by key rather than OFFSET, which is slow on deep pages.
+// The rows after the cursor last in the order of columns, eg "created_at,
+// id", are selected and ordered by columns ahead of any OrderBy. last is the
+// value of a single column or a []interface{} for several, compared as a row
+// value (created_at, id) > ($1, $2). A nil last selects the first page. Use
+// Limit to set the page size and NextSeek to get the cursor of the next page.
+//
+// The columns must be plain columns sorted in the direction of dir, not
+// expressions or columns with ASC or DESC, and the last column unique, eg
+// the primary key. Since a row value comparison never matches NULL, rows
+// with a NULL column are excluded from every page with IS NOT NULL.
+func (b *SelectBuilder) Seek(columns string, last interface{}, dir SeekDirection) *SelectBuilder {
+	s := &seek{dir: dir}
+	for _, c := range strings.Split(columns, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			if !reSeekColumn.MatchString(c) {
+				panic(fmt.Sprintf("Seek requires plain columns, got %q", c))
+			}
+			s.columns = append(s.columns, c)
+		}
+	}
+	if len(s.columns) == 0 {
+		panic("Seek requires 1 or more columns")
+	}
+	if last != nil {
+		if values, ok := last.([]interface{}); ok {
+			s.last = values
+		} else {
+			s.last = []interface{}{last}
+		}
+		if len(s.last) != len(s.columns) {
+			panic(fmt.Sprintf("Seek got %d values for %d columns", len(s.last), len(s.columns)))
+		}
+		for _, v := range s.last {
+			if isNilPointer(v) {
+				panic("Seek values cannot be NULL")
+			}
+		}
+	}
+	b.seek = s
+	return b
+}
+
+// NextSeek returns the cursor of the page after rows, a slice of the
+// structs selected with Seek, to pass as last to Seek. It is a single value
+// or a []interface{} for several columns, nil if there is no next page
+// since rows has fewer rows than the LIMIT. A NULL value is an error.
+func (b *SelectBuilder) NextSeek(rows interface{}) (interface{}, error) {
+	if b.seek == nil {
+		return nil, errors.New("NextSeek requires Seek")
+	}
+	v := reflect.Indirect(reflect.ValueOf(rows))
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("NextSeek requires a slice of structs, got %T", rows)
+	}
+	n := v.Len()
+	if n == 0 || b.limitValid && uint64(n) < b.limitCount {
+		return nil, nil
+	}
+
+	last := reflect.Indirect(v.Index(n - 1))
+	if last.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("NextSeek requires a slice of structs, got %T", rows)
+	}
+	tm := structMapFor(last.Type())
+	values := make([]interface{}, len(b.seek.columns))
+	for i, column := range b.seek.columns {
+		// p.created_at => created_at
+		name := column
+		if j := strings.LastIndexByte(name, '.'); j >= 0 {
+			name = name[j+1:]
+		}
+		fi, ok := tm.Names[strings.Trim(name, `"`)]
+		if !ok {
+			return nil, fmt.Errorf("Could not find struct tag in type %s: `db:\"%s\"`", last.Type().Name(), name)
+		}
+		values[i] = fieldValue(last, fi.Index)
+		if isNullValue(values[i]) {
+			return nil, fmt.Errorf("NextSeek cannot page after NULL %s", column)
+		}
+	}
+	if len(values) == 1 {
+		return values[0], nil
+	}
+	return values, nil
+}
+
+// isNullValue determines if v is written as NULL.
+func isNullValue(v interface{}) bool {
+	if isNilPointer(v) {
+		return true
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		return err == nil && dv == nil
+	}
+	return false
+}
+
+// seekFragments returns the WHERE and ORDER BY fragments of the statement
+// including the keyset.
+func (b *SelectBuilder) seekFragments() ([]*whereFragment, []*whereFragment) {
+	if b.seek == nil {
+		return b.whereFragments, b.orderBys
+	}
+	whereFragments := append(b.whereFragments[:len(b.whereFragments):len(b.whereFragments)], b.seek.notNullFragment())
+	if f := b.seek.whereFragment(); f != nil {
+		whereFragments = append(whereFragments, f)
+	}
+	return whereFragments, append(b.seek.orderBys(), b.orderBys...)
+}
+
+// notNullFragment returns the condition excluding rows with a NULL column,
+// which would be skipped after the first page.
+func (s *seek) notNullFragment() *whereFragment {
+	conditions := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		conditions[i] = c + " IS NOT NULL"
+	}
+	return &whereFragment{Condition: strings.Join(conditions, " AND ")}
+}
+
+// whereFragment returns the condition selecting the rows after the cursor,
+// nil on the first page.
+func (s *seek) whereFragment() *whereFragment {
+	if s.last == nil {
+		return nil
+	}
+	op := " > "
+	if s.dir == Before {
+		op = " < "
+	}
+	if len(s.columns) == 1 {
+		return &whereFragment{Condition: s.columns[0] + op + "$1", Values: s.last}
+	}
+
+	var sb strings.Builder
+	sb.WriteRune('(')
+	sb.WriteString(strings.Join(s.columns, ", "))
+	sb.WriteString(")")
+	sb.WriteString(op)
+	sb.WriteRune('(')
+	for i := range s.last {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		writePlaceholder(&sb, i+1)
+	}
+	sb.WriteRune(')')
+	return &whereFragment{Condition: sb.String(), Values: s.last}
+}
+
+// orderBys returns the ORDER BY of the keyset.
+func (s *seek) orderBys() []*whereFragment {
+	order := ""
+	if s.dir == Before {
+		order = " DESC"
+	}
+	orderBys := make([]*whereFragment, len(s.columns))
+	for i, c := range s.columns {
+		orderBys[i] = &whereFragment{Condition: c + order}
+	}
+	return orderBys
+}
@@ -0,0 +1,110 @@
+package dat
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectSeekFirstPage(t *testing.T) {
+	sql, args := Select("id", "title").From("posts").Where("user_id = $1", 1).Seek("id", nil, After).Limit(10).ToSQL()
+	assert.Equal(t, "SELECT id, title FROM posts WHERE (user_id = $1) AND (id IS NOT NULL) ORDER BY id LIMIT 10", sql)
+	assert.Equal(t, []interface{}{1}, args)
+}
+
+func TestSelectSeek(t *testing.T) {
+	sql, args := Select("id", "title").
+		From("posts").
+		Where("user_id = $1", 1).
+		Seek("id", 42, After).
+		OrderBy("title").
+		Limit(10).
+		ToSQL()
+	assert.Equal(t, "SELECT id, title FROM posts WHERE (user_id = $1) AND (id IS NOT NULL) AND (id > $2) ORDER BY id, title LIMIT 10", sql)
+	assert.Equal(t, []interface{}{1, 42}, args)
+}
+
+func TestSelectSeekMultipleColumnsDescending(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	sql, args := Select("id", "created_at").
+		From("posts").
+		Seek("created_at, id", []interface{}{at, 42}, Before).
+		Limit(10).
+		ToSQL()
+	assert.Equal(t, "SELECT id, created_at FROM posts WHERE (created_at IS NOT NULL AND id IS NOT NULL) AND ((created_at, id) < ($1, $2)) ORDER BY created_at DESC, id DESC LIMIT 10", sql)
+	assert.Equal(t, []interface{}{at, 42}, args)
+}
+
+func TestSelectSeekManyColumns(t *testing.T) {
+	columns := make([]string, maxLookup+1)
+	last := make([]interface{}, maxLookup+1)
+	for i := range columns {
+		columns[i] = "c" + strconv.Itoa(i)
+		last[i] = i
+	}
+	sql, args := Select("id").From("wide").Seek(strings.Join(columns, ", "), last, After).ToSQL()
+	assert.Contains(t, sql, " > ($1, $2, ")
+	assert.Contains(t, sql, ", $100, $101)")
+	assert.Equal(t, last, args)
+}
+
+func TestSelectDocSeek(t *testing.T) {
+	sql, args := SelectDoc("id").From("posts").Seek("id", 42, After).ToSQL()
+	assert.Equal(t, "SELECT row_to_json(dat__item.*) FROM ( SELECT id FROM posts WHERE (id IS NOT NULL) AND (id > $1) ORDER BY id) as dat__item", sql)
+	assert.Equal(t, []interface{}{42}, args)
+}
+
+func TestSelectSeekInvalid(t *testing.T) {
+	assert.Panics(t, func() { Select("id").From("posts").Seek("created_at, id", 1, After) })
+	assert.Panics(t, func() { Select("id").From("posts").Seek("id", []interface{}{(*int)(nil)}, After) })
+	assert.Panics(t, func() { Select("id").From("posts").Seek(" ", nil, After) })
+	assert.Panics(t, func() { Select("id").From("posts").Seek("created_at DESC, id", nil, After) })
+	assert.Panics(t, func() { Select("id").From("posts").Seek("coalesce(published_at, created_at), id", nil, After) })
+	assert.NotPanics(t, func() { Select("id").From("posts p").Seek(`p."createdAt", p.id`, nil, After) })
+}
+
+func TestSelectNextSeek(t *testing.T) {
+	type Post struct {
+		ID        int64     `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	posts := []*Post{{ID: 1}, {ID: 2, CreatedAt: at}}
+
+	b := Select("id", "created_at").From("posts p").Seek("p.created_at, p.id", nil, Before).Limit(2)
+	next, err := b.NextSeek(posts)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{at, int64(2)}, next)
+
+	next, err = Select("id").From("posts").Seek("id", nil, After).NextSeek(&posts)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), next)
+
+	// a short page is the last
+	next, err = Select("id").From("posts").Seek("id", nil, After).Limit(3).NextSeek(posts)
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+
+	_, err = Select("id").From("posts").Seek("title", nil, After).NextSeek(posts)
+	assert.Error(t, err)
+	_, err = Select("id").From("posts").NextSeek(posts)
+	assert.Error(t, err)
+}
+
+func TestSelectNextSeekNull(t *testing.T) {
+	type Post struct {
+		ID          int64          `db:"id"`
+		PublishedAt *time.Time     `db:"published_at"`
+		Title       sql.NullString `db:"title"`
+	}
+	posts := []Post{{ID: 1}}
+
+	_, err := Select("id").From("posts").Seek("published_at, id", nil, After).NextSeek(posts)
+	assert.Error(t, err)
+	_, err = Select("id").From("posts").Seek("title, id", nil, After).NextSeek(posts)
+	assert.Error(t, err)
+}
@@ -1,5 +1,11 @@
 package dat
 
+import (
+	"strings"
+
+	"github.com/casualjim/dat/common"
+)
+
 // SelectBuilder contains the clauses for a SELECT statement
 type SelectBuilder struct {
 	Execer
@@ -7,24 +13,48 @@ type SelectBuilder struct {
 	isDistinct      bool
 	distinctColumns []string
 	isInterpolated  bool
+	arrayBinding    bool
 	columns         []string
+	columnExprs     map[int]*Expression
 	fors            []string
 	table           string
+	derived         *derivedTable
+	joins           []*joinClause
 	whereFragments  []*whereFragment
 	groupBys        []string
 	havingFragments []*whereFragment
 	orderBys        []*whereFragment
 	limitCount      uint64
 	limitValid      bool
+	limitParam      bool
 	offsetCount     uint64
 	offsetValid     bool
+	offsetParam     bool
 	scope           Scope
+	with            *WithBuilder
+	asOfSystemTime  string
+	windows         []*namedWindow
+	compounds       []*compoundSelect
+	seek            *seek
+	totalCount      *int64
+}
+
+// TotalCountColumn is the column added to the statement of a SelectBuilder
+// using WithTotalCount.
+const TotalCountColumn = "dat__total_count"
+
+// TotalCounter is implemented by builders whose statement returns the total
+// number of rows, see SelectBuilder.WithTotalCount.
+type TotalCounter interface {
+	// TotalCountDest returns the destination of the total count, nil if
+	// not requested.
+	TotalCountDest() *int64
 }
 
 // NewSelectBuilder creates a new SelectBuilder for the given columns
 func NewSelectBuilder(columns ...string) *SelectBuilder {
 	if len(columns) == 0 || columns[0] == "" {
-		logger.Error("Select requires 1 or more columns")
+		logger().Error("Select requires 1 or more columns")
 		return nil
 	}
 	return &SelectBuilder{columns: columns, isInterpolated: EnableInterpolation}
@@ -33,29 +63,96 @@ func NewSelectBuilder(columns ...string) *SelectBuilder {
 // Columns adds additional select columns to the builder.
 func (b *SelectBuilder) Columns(columns ...string) *SelectBuilder {
 	if len(columns) == 0 || columns[0] == "" {
-		logger.Error("Select requires 1 or more columns")
+		logger().Error("Select requires 1 or more columns")
 		return nil
 	}
 	b.columns = append(b.columns, columns...)
 	return b
 }
 
+// ColumnExpr adds a select column from an expression whose args are
+// included in the statement, eg the result of Over. The column is aliased
+// when alias is not empty.
+func (b *SelectBuilder) ColumnExpr(expr *Expression, alias string) *SelectBuilder {
+	if b.columnExprs == nil {
+		b.columnExprs = map[int]*Expression{}
+	}
+	if alias != "" {
+		expr = Expr(expr.Sql+" AS "+alias, expr.Args...)
+	}
+	b.columnExprs[len(b.columns)] = expr
+	b.columns = append(b.columns, expr.Sql)
+	return b
+}
+
 // Distinct marks the statement as a DISTINCT SELECT
 func (b *SelectBuilder) Distinct() *SelectBuilder {
+	if len(b.distinctColumns) > 0 {
+		distinctConflict()
+	}
 	b.isDistinct = true
 	return b
 }
 
 // DistinctOn sets the columns for DISTINCT ON
 func (b *SelectBuilder) DistinctOn(columns ...string) *SelectBuilder {
+	if b.isDistinct && len(b.distinctColumns) == 0 {
+		distinctConflict()
+	}
 	b.isDistinct = true
 	b.distinctColumns = columns
 	return b
 }
 
-// From sets the table to SELECT FROM. JOINs may also be defined here.
+// With adds a named query to the WITH clause preceding the statement.
+func (b *SelectBuilder) With(name string, sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	if b.with == nil {
+		b.with = &WithBuilder{}
+	}
+	b.with.With(name, sqlOrBuilder, args...)
+	return b
+}
+
+// WithRecursive adds a named query to the WITH RECURSIVE clause preceding
+// the statement.
+func (b *SelectBuilder) WithRecursive(name string, sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	if b.with == nil {
+		b.with = &WithBuilder{}
+	}
+	b.with.WithRecursive(name, sqlOrBuilder, args...)
+	return b
+}
+
+const distinctConflictMsg = "Distinct and DistinctOn are mutually exclusive, using DISTINCT ON"
+
+// distinctConflict panics in Strict mode, otherwise it warns that DISTINCT
+// ON is used in place of DISTINCT.
+func distinctConflict() {
+	if Strict {
+		panic(distinctConflictMsg)
+	}
+	logger().Warn(distinctConflictMsg)
+}
+
+// From sets the table to SELECT FROM. JOINs may also be defined here, though
+// Join and friends keep their args in order.
 func (b *SelectBuilder) From(from string) *SelectBuilder {
 	b.table = from
+	b.derived = nil
+	return b
+}
+
+// FromDerived selects from subquery aliased as alias, a derived table, eg
+//
+//	Select("t.user_id").FromDerived(Select("user_id", "count(*) AS n").From("posts").GroupBy("user_id"), "t").Where("t.n > $1", 10)
+//
+// The subquery args are merged ahead of the outer WHERE args.
+func (b *SelectBuilder) FromDerived(subquery Fragment, alias string) *SelectBuilder {
+	if alias == "" {
+		panic("FromDerived requires an alias")
+	}
+	b.table = alias
+	b.derived = &derivedTable{subquery: subquery, alias: alias}
 	return b
 }
 
@@ -65,12 +162,85 @@ func (b *SelectBuilder) For(options ...string) *SelectBuilder {
 	return b
 }
 
+// ForUpdate adds FOR UPDATE to SELECT.
+func (b *SelectBuilder) ForUpdate() *SelectBuilder {
+	return b.For("UPDATE")
+}
+
+// ForNoKeyUpdate adds FOR NO KEY UPDATE to SELECT.
+func (b *SelectBuilder) ForNoKeyUpdate() *SelectBuilder {
+	return b.For("NO KEY UPDATE")
+}
+
+// ForShare adds FOR SHARE to SELECT.
+func (b *SelectBuilder) ForShare() *SelectBuilder {
+	return b.For("SHARE")
+}
+
+// ForKeyShare adds FOR KEY SHARE to SELECT.
+func (b *SelectBuilder) ForKeyShare() *SelectBuilder {
+	return b.For("KEY SHARE")
+}
+
+// Of restricts the FOR clause to the given tables or aliases.
+func (b *SelectBuilder) Of(tables ...string) *SelectBuilder {
+	if len(b.fors) == 0 {
+		panic("Of requires a FOR clause")
+	}
+	fors := []string{b.fors[0], "OF " + strings.Join(tables, ", ")}
+	for _, s := range b.fors[1:] {
+		if !strings.HasPrefix(s, "OF ") {
+			fors = append(fors, s)
+		}
+	}
+	b.fors = fors
+	return b
+}
+
+// NoWait adds NOWAIT to the FOR clause.
+func (b *SelectBuilder) NoWait() *SelectBuilder {
+	return b.lockWait("NOWAIT")
+}
+
+// SkipLocked adds SKIP LOCKED to the FOR clause.
+func (b *SelectBuilder) SkipLocked() *SelectBuilder {
+	return b.lockWait("SKIP LOCKED")
+}
+
+// lockWait sets the wait policy of the FOR clause, which must come last.
+func (b *SelectBuilder) lockWait(policy string) *SelectBuilder {
+	if len(b.fors) == 0 {
+		panic(policy + " requires a FOR clause")
+	}
+	fors := b.fors[:1:1]
+	for _, s := range b.fors[1:] {
+		if s != "NOWAIT" && s != "SKIP LOCKED" {
+			fors = append(fors, s)
+		}
+	}
+	b.fors = append(fors, policy)
+	return b
+}
+
 // ScopeMap uses a predefined scope in place of WHERE.
 func (b *SelectBuilder) ScopeMap(mapScope *MapScope, m M) *SelectBuilder {
 	b.scope = mapScope.mergeClone(m)
 	return b
 }
 
+// UseScope uses scope, which may be shared across builders, in place of WHERE.
+func (b *SelectBuilder) UseScope(scope Scope) *SelectBuilder {
+	b.scope = scope
+	return b
+}
+
+// Unscoped removes any scope from the statement, e.g. to include soft
+// deleted rows.
+func (b *SelectBuilder) Unscoped() *SelectBuilder {
+	b.scope = nil
+	return b
+}
+
 // Scope uses a predefined scope in place of WHERE.
 func (b *SelectBuilder) Scope(sql string, args ...interface{}) *SelectBuilder {
 	b.scope = ScopeFunc(func(table string) (string, []interface{}) {
@@ -79,6 +249,37 @@ func (b *SelectBuilder) Scope(sql string, args ...interface{}) *SelectBuilder {
 	return b
 }
 
+// AsOfSystemTime reads the table as of a past time, eg '-10s', with the AS
+// OF SYSTEM TIME clause. expr is written verbatim. If the dialect does not
+// support it, Interpolate and the runner return
+// ErrAsOfSystemTimeUnsupported while ToSQL panics with it.
+func (b *SelectBuilder) AsOfSystemTime(expr string) *SelectBuilder {
+	b.asOfSystemTime = expr
+	return b
+}
+
+// checkAsOfSystemTime returns ErrAsOfSystemTimeUnsupported if the AS OF
+// SYSTEM TIME clause is set and the dialect does not support it.
+func (b *SelectBuilder) checkAsOfSystemTime() error {
+	if b.asOfSystemTime == "" {
+		return nil
+	}
+	if _, ok := Dialect.(AsOfSystemTimeWriter); !ok {
+		return ErrAsOfSystemTimeUnsupported
+	}
+	return nil
+}
+
+// writeAsOfSystemTime writes the AS OF SYSTEM TIME clause if set.
+func (b *SelectBuilder) writeAsOfSystemTime(buf common.BufferWriter) {
+	if err := b.checkAsOfSystemTime(); err != nil {
+		panic(err)
+	}
+	if b.asOfSystemTime != "" {
+		Dialect.(AsOfSystemTimeWriter).WriteAsOfSystemTime(buf, b.asOfSystemTime)
+	}
+}
+
 // Where appends a WHERE clause to the statement for the given string and args
 // or map of column/value pairs
 func (b *SelectBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *SelectBuilder {
@@ -86,18 +287,41 @@ func (b *SelectBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *S
 	return b
 }
 
+// WhereIf appends a WHERE clause only if cond is true, eg for an optional
+// filter. The args are evaluated either way.
+func (b *SelectBuilder) WhereIf(cond bool, whereSQLOrMap interface{}, args ...interface{}) *SelectBuilder {
+	if cond {
+		b.Where(whereSQLOrMap, args...)
+	}
+	return b
+}
+
+// Filter appends the conditions of f to the WHERE clause.
+func (b *SelectBuilder) Filter(f *FilterBuilder) *SelectBuilder {
+	b.whereFragments = filterFragments(b.whereFragments, f)
+	return b
+}
+
 // GroupBy appends a column to group the statement
 func (b *SelectBuilder) GroupBy(group string) *SelectBuilder {
 	b.groupBys = append(b.groupBys, group)
 	return b
 }
 
-// Having appends a HAVING clause to the statement
+// Having appends a HAVING clause to the statement. Placeholders in args are
+// relative to the clause and are renumbered after the WHERE args.
 func (b *SelectBuilder) Having(whereSQLOrMap interface{}, args ...interface{}) *SelectBuilder {
 	b.havingFragments = append(b.havingFragments, newWhereFragment(whereSQLOrMap, args))
 	return b
 }
 
+// Window appends a named window definition to the WINDOW clause. Reference
+// it from columns with OverWindow.
+func (b *SelectBuilder) Window(name string, w *WindowBuilder) *SelectBuilder {
+	b.windows = append(b.windows, &namedWindow{name: name, window: w})
+	return b
+}
+
 // OrderBy appends a column to ORDER the statement by
 func (b *SelectBuilder) OrderBy(whereSQLOrMap interface{}, args ...interface{}) *SelectBuilder {
 	b.orderBys = append(b.orderBys, newWhereFragment(whereSQLOrMap, args))
@@ -111,6 +335,28 @@ func (b *SelectBuilder) Limit(limit uint64) *SelectBuilder {
 	return b
 }
 
+// UseArrayBinding binds slices as a single Postgres array when enabled. A
+// slice in an Eq map becomes "column" = ANY($1), use = ANY($1) rather than
+// IN $1 in conditions. The statement text no longer depends on the length
+// of the slice.
+func (b *SelectBuilder) UseArrayBinding(enable bool) *SelectBuilder {
+	b.arrayBinding = enable
+	return b
+}
+
+// LimitCount returns the LIMIT of the statement and whether it is set.
+func (b *SelectBuilder) LimitCount() (uint64, bool) {
+	return b.limitCount, b.limitValid
+}
+
+// LimitPlaceholder sets a limit for the statement which is sent as a bind
+// argument instead of being inlined; overrides any existing LIMIT
+func (b *SelectBuilder) LimitPlaceholder(limit uint64) *SelectBuilder {
+	b.Limit(limit)
+	b.limitParam = true
+	return b
+}
+
 // Offset sets an offset for the statement; overrides any existing OFFSET
 func (b *SelectBuilder) Offset(offset uint64) *SelectBuilder {
 	b.offsetCount = offset
@@ -118,6 +364,14 @@ func (b *SelectBuilder) Offset(offset uint64) *SelectBuilder {
 	return b
 }
 
+// OffsetPlaceholder sets an offset for the statement which is sent as a bind
+// argument instead of being inlined; overrides any existing OFFSET
+func (b *SelectBuilder) OffsetPlaceholder(offset uint64) *SelectBuilder {
+	b.Offset(offset)
+	b.offsetParam = true
+	return b
+}
+
 // Paginate sets LIMIT/OFFSET for the statement based on the given page/perPage
 // Assumes page/perPage are valid. Page and perPage must be >= 1
 func (b *SelectBuilder) Paginate(page, perPage uint64) *SelectBuilder {
@@ -126,6 +380,29 @@ func (b *SelectBuilder) Paginate(page, perPage uint64) *SelectBuilder {
 	return b
 }
 
+// WithTotalCount adds a count(*) OVER () column to get the number of rows
+// matching the statement regardless of LIMIT and OFFSET along with a page.
+// The runner scans it into total rather than the destination. total is 0 if
+// the page has no rows, eg its OFFSET is past the last row.
+func (b *SelectBuilder) WithTotalCount(total *int64) *SelectBuilder {
+	b.totalCount = total
+	return b
+}
+
+// TotalCountDest returns the destination set by WithTotalCount.
+func (b *SelectBuilder) TotalCountDest() *int64 {
+	return b.totalCount
+}
+
+// toSQL is ToSQL returning ErrAsOfSystemTimeUnsupported as an error.
+func (b *SelectBuilder) toSQL() (string, []interface{}, error) {
+	if err := b.checkAsOfSystemTime(); err != nil {
+		return "", nil, err
+	}
+	sql, args := b.ToSQL()
+	return sql, args, nil
+}
+
 // ToSQL serialized the SelectBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *SelectBuilder) ToSQL() (string, []interface{}) {
@@ -135,11 +412,26 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}) {
 	if len(b.table) == 0 {
 		panic("no table specified")
 	}
+	isCompound := len(b.compounds) > 0
+	if isCompound && len(b.fors) > 0 {
+		panic("FOR locking clauses are not allowed with UNION, INTERSECT or EXCEPT")
+	}
+	if isCompound && b.totalCount != nil {
+		panic("WithTotalCount is not allowed with UNION, INTERSECT or EXCEPT")
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 	var args []interface{}
+	var placeholderStartPos int64 = 1
+
+	if b.with != nil {
+		b.with.writeSQL(buf, &args, &placeholderStartPos)
+	}
 
+	if isCompound {
+		buf.WriteRune('(')
+	}
 	buf.WriteString("SELECT ")
 
 	if b.isDistinct {
@@ -157,31 +449,21 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}) {
 		}
 	}
 
-	for i, s := range b.columns {
-		if i > 0 {
-			buf.WriteString(", ")
-		}
-		buf.WriteString(s)
-	}
+	b.writeColumns(buf, &args, &placeholderStartPos)
 
 	buf.WriteString(" FROM ")
-	buf.WriteString(b.table)
-
-	var placeholderStartPos int64 = 1
-	if b.scope != nil {
-		var where string
-		sql, args2 := b.scope.ToSQL(b.table)
-		sql, where = splitWhere(sql)
-		buf.WriteString(sql)
-		if where != "" {
-			fragment := newWhereFragment(where, args2)
-			b.whereFragments = append(b.whereFragments, fragment)
-		}
+	b.writeFrom(buf, &args, &placeholderStartPos)
+	writeJoinsToSQL(buf, b.joins, &args, &placeholderStartPos)
+	whereFragments, orderBys := b.seekFragments()
+	whereFragments = writeScope(buf, b.scope, b.table, whereFragments)
+	if b.arrayBinding {
+		whereFragments = arrayBindFragments(whereFragments)
 	}
+	b.writeAsOfSystemTime(buf)
 
-	if len(b.whereFragments) > 0 {
+	if len(whereFragments) > 0 {
 		buf.WriteString(" WHERE ")
-		writeAndFragmentsToSQL(buf, b.whereFragments, &args, &placeholderStartPos)
+		writeAndFragmentsToSQL(buf, whereFragments, &args, &placeholderStartPos)
 	}
 
 	if len(b.groupBys) > 0 {
@@ -199,21 +481,22 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}) {
 		writeAndFragmentsToSQL(buf, b.havingFragments, &args, &placeholderStartPos)
 	}
 
-	if len(b.orderBys) > 0 {
-		buf.WriteString(" ORDER BY ")
-		writeCommaFragmentsToSQL(buf, b.orderBys, &args, &placeholderStartPos)
+	if len(b.windows) > 0 {
+		writeWindowsToSQL(buf, b.windows, &args, &placeholderStartPos)
 	}
 
-	if b.limitValid {
-		buf.WriteString(" LIMIT ")
-		writeUint64(buf, b.limitCount)
+	if isCompound {
+		buf.WriteRune(')')
+		writeCompoundsToSQL(buf, b.compounds, &args, &placeholderStartPos)
 	}
 
-	if b.offsetValid {
-		buf.WriteString(" OFFSET ")
-		writeUint64(buf, b.offsetCount)
+	if len(orderBys) > 0 {
+		buf.WriteString(" ORDER BY ")
+		writeCommaFragmentsToSQL(buf, orderBys, &args, &placeholderStartPos)
 	}
 
+	b.writeLimitOffset(buf, &args, &placeholderStartPos)
+
 	// add FOR clause
 	if len(b.fors) > 0 {
 		buf.WriteString(" FOR")
@@ -225,3 +508,52 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}) {
 
 	return buf.String(), args
 }
+
+// writeFrom writes the FROM source, merging the args of a derived table.
+func (b *SelectBuilder) writeFrom(buf common.BufferWriter, args *[]interface{}, pos *int64) {
+	if b.derived != nil {
+		b.derived.expression().WriteRelativeArgs(buf, args, pos)
+		return
+	}
+	writeIdentOrSQL(buf, b.table)
+}
+
+// writeLimitOffset writes the LIMIT and OFFSET clauses either inline or as
+// placeholders, see EnableLimitPlaceholders.
+func (b *SelectBuilder) writeLimitOffset(buf common.BufferWriter, args *[]interface{}, pos *int64) {
+	if b.limitValid {
+		buf.WriteString(" LIMIT ")
+		writeUint64Arg(buf, b.limitCount, b.limitParam || EnableLimitPlaceholders, args, pos)
+	}
+
+	if b.offsetValid {
+		buf.WriteString(" OFFSET ")
+		writeUint64Arg(buf, b.offsetCount, b.offsetParam || EnableLimitPlaceholders, args, pos)
+	}
+}
+
+func writeUint64Arg(buf common.BufferWriter, n uint64, asPlaceholder bool, args *[]interface{}, pos *int64) {
+	if !asPlaceholder {
+		writeUint64(buf, n)
+		return
+	}
+	writePlaceholder(buf, int(*pos))
+	*args = append(*args, n)
+	*pos++
+}
+
+func (b *SelectBuilder) writeColumns(buf common.BufferWriter, args *[]interface{}, pos *int64) {
+	for i, s := range b.columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if e, ok := b.columnExprs[i]; ok {
+			e.WriteRelativeArgs(buf, args, pos)
+			continue
+		}
+		writeIdentOrSQL(buf, s)
+	}
+	if b.totalCount != nil {
+		buf.WriteString(", count(*) OVER () AS " + TotalCountColumn)
+	}
+}
@@ -62,6 +62,15 @@ func (b *SelectDocBuilder) One(column string, sqlOrBuilder interface{}, a ...int
 	return b
 }
 
+// toSQL is ToSQL returning ErrAsOfSystemTimeUnsupported as an error.
+func (b *SelectDocBuilder) toSQL() (string, []interface{}, error) {
+	if err := b.checkAsOfSystemTime(); err != nil {
+		return "", nil, err
+	}
+	sql, args := b.ToSQL()
+	return sql, args, nil
+}
+
 // ToSQL serialized the SelectBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *SelectDocBuilder) ToSQL() (string, []interface{}) {
@@ -71,6 +80,12 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}) {
 	if len(b.table) == 0 && b.innerSQL == nil {
 		panic("no table specified")
 	}
+	if b.totalCount != nil {
+		panic("SelectDoc does not support WithTotalCount")
+	}
+	if len(b.compounds) > 0 {
+		panic("SelectDoc does not support UNION, INTERSECT or EXCEPT")
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
@@ -118,12 +133,7 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}) {
 		}
 	}
 
-	for i, s := range b.columns {
-		if i > 0 {
-			buf.WriteString(", ")
-		}
-		buf.WriteString(s)
-	}
+	b.writeColumns(buf, &args, &placeholderStartPos)
 
 	/*
 		(
@@ -160,22 +170,19 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}) {
 		b.innerSQL.WriteRelativeArgs(buf, &args, &placeholderStartPos)
 	} else {
 		buf.WriteString(" FROM ")
-		buf.WriteString(b.table)
-
-		if b.scope != nil {
-			var where string
-			sql, args2 := b.scope.ToSQL(b.table)
-			sql, where = splitWhere(sql)
-			buf.WriteString(sql)
-			if where != "" {
-				fragment := newWhereFragment(where, args2)
-				b.whereFragments = append(b.whereFragments, fragment)
-			}
+		b.writeFrom(buf, &args, &placeholderStartPos)
+		writeJoinsToSQL(buf, b.joins, &args, &placeholderStartPos)
+
+		whereFragments, orderBys := b.seekFragments()
+		whereFragments = writeScope(buf, b.scope, b.table, whereFragments)
+		if b.arrayBinding {
+			whereFragments = arrayBindFragments(whereFragments)
 		}
+		b.writeAsOfSystemTime(buf)
 
-		if len(b.whereFragments) > 0 {
+		if len(whereFragments) > 0 {
 			buf.WriteString(" WHERE ")
-			writeAndFragmentsToSQL(buf, b.whereFragments, &args, &placeholderStartPos)
+			writeAndFragmentsToSQL(buf, whereFragments, &args, &placeholderStartPos)
 		}
 
 		// if b.scope == nil {
@@ -203,20 +210,16 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}) {
 			writeAndFragmentsToSQL(buf, b.havingFragments, &args, &placeholderStartPos)
 		}
 
-		if len(b.orderBys) > 0 {
-			buf.WriteString(" ORDER BY ")
-			writeCommaFragmentsToSQL(buf, b.orderBys, &args, &placeholderStartPos)
+		if len(b.windows) > 0 {
+			writeWindowsToSQL(buf, b.windows, &args, &placeholderStartPos)
 		}
 
-		if b.limitValid {
-			buf.WriteString(" LIMIT ")
-			writeUint64(buf, b.limitCount)
+		if len(orderBys) > 0 {
+			buf.WriteString(" ORDER BY ")
+			writeCommaFragmentsToSQL(buf, orderBys, &args, &placeholderStartPos)
 		}
 
-		if b.offsetValid {
-			buf.WriteString(" OFFSET ")
-			writeUint64(buf, b.offsetCount)
-		}
+		b.writeLimitOffset(buf, &args, &placeholderStartPos)
 
 		// add FOR clause
 		if len(b.fors) > 0 {
@@ -239,21 +242,39 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}) {
 // Columns adds additional select columns to the builder.
 func (b *SelectDocBuilder) Columns(columns ...string) *SelectDocBuilder {
 	if len(columns) == 0 || columns[0] == "" {
-		logger.Error("Select requires 1 or more columns")
+		logger().Error("Select requires 1 or more columns")
 		return nil
 	}
 	b.columns = append(b.columns, columns...)
 	return b
 }
 
+// ColumnExpr adds a select column from an expression, see SelectBuilder.ColumnExpr.
+func (b *SelectDocBuilder) ColumnExpr(expr *Expression, alias string) *SelectDocBuilder {
+	b.SelectBuilder.ColumnExpr(expr, alias)
+	return b
+}
+
+// Window appends a named window definition to the WINDOW clause.
+func (b *SelectDocBuilder) Window(name string, w *WindowBuilder) *SelectDocBuilder {
+	b.SelectBuilder.Window(name, w)
+	return b
+}
+
 // Distinct marks the statement as a DISTINCT SELECT
 func (b *SelectDocBuilder) Distinct() *SelectDocBuilder {
+	if len(b.distinctColumns) > 0 {
+		distinctConflict()
+	}
 	b.isDistinct = true
 	return b
 }
 
 // DistinctOn sets the columns for DISTINCT ON
 func (b *SelectDocBuilder) DistinctOn(columns ...string) *SelectDocBuilder {
+	if b.isDistinct && len(b.distinctColumns) == 0 {
+		distinctConflict()
+	}
 	b.isDistinct = true
 	b.distinctColumns = columns
 	return b
@@ -261,7 +282,13 @@ func (b *SelectDocBuilder) DistinctOn(columns ...string) *SelectDocBuilder {
 
 // From sets the table to SELECT FROM
 func (b *SelectDocBuilder) From(from string) *SelectDocBuilder {
-	b.table = from
+	b.SelectBuilder.From(from)
+	return b
+}
+
+// FromDerived selects from a derived table, see SelectBuilder.FromDerived.
+func (b *SelectDocBuilder) FromDerived(subquery Fragment, alias string) *SelectDocBuilder {
+	b.SelectBuilder.FromDerived(subquery, alias)
 	return b
 }
 
@@ -271,12 +298,116 @@ func (b *SelectDocBuilder) For(options ...string) *SelectDocBuilder {
 	return b
 }
 
+// ForUpdate adds FOR UPDATE to SELECT.
+func (b *SelectDocBuilder) ForUpdate() *SelectDocBuilder {
+	b.SelectBuilder.ForUpdate()
+	return b
+}
+
+// ForNoKeyUpdate adds FOR NO KEY UPDATE to SELECT.
+func (b *SelectDocBuilder) ForNoKeyUpdate() *SelectDocBuilder {
+	b.SelectBuilder.ForNoKeyUpdate()
+	return b
+}
+
+// ForShare adds FOR SHARE to SELECT.
+func (b *SelectDocBuilder) ForShare() *SelectDocBuilder {
+	b.SelectBuilder.ForShare()
+	return b
+}
+
+// ForKeyShare adds FOR KEY SHARE to SELECT.
+func (b *SelectDocBuilder) ForKeyShare() *SelectDocBuilder {
+	b.SelectBuilder.ForKeyShare()
+	return b
+}
+
+// Of restricts the FOR clause to the given tables or aliases.
+func (b *SelectDocBuilder) Of(tables ...string) *SelectDocBuilder {
+	b.SelectBuilder.Of(tables...)
+	return b
+}
+
+// NoWait adds NOWAIT to the FOR clause.
+func (b *SelectDocBuilder) NoWait() *SelectDocBuilder {
+	b.SelectBuilder.NoWait()
+	return b
+}
+
+// SkipLocked adds SKIP LOCKED to the FOR clause.
+func (b *SelectDocBuilder) SkipLocked() *SelectDocBuilder {
+	b.SelectBuilder.SkipLocked()
+	return b
+}
+
+// Join appends an INNER JOIN, see SelectBuilder.Join.
+func (b *SelectDocBuilder) Join(table, on string, args ...interface{}) *SelectDocBuilder {
+	b.SelectBuilder.Join(table, on, args...)
+	return b
+}
+
+// LeftJoin appends a LEFT JOIN, see SelectBuilder.Join.
+func (b *SelectDocBuilder) LeftJoin(table, on string, args ...interface{}) *SelectDocBuilder {
+	b.SelectBuilder.LeftJoin(table, on, args...)
+	return b
+}
+
+// RightJoin appends a RIGHT JOIN, see SelectBuilder.Join.
+func (b *SelectDocBuilder) RightJoin(table, on string, args ...interface{}) *SelectDocBuilder {
+	b.SelectBuilder.RightJoin(table, on, args...)
+	return b
+}
+
+// FullJoin appends a FULL JOIN, see SelectBuilder.Join.
+func (b *SelectDocBuilder) FullJoin(table, on string, args ...interface{}) *SelectDocBuilder {
+	b.SelectBuilder.FullJoin(table, on, args...)
+	return b
+}
+
+// CrossJoin appends a CROSS JOIN of table.
+func (b *SelectDocBuilder) CrossJoin(table string) *SelectDocBuilder {
+	b.SelectBuilder.CrossJoin(table)
+	return b
+}
+
+// JoinLateral appends an INNER JOIN LATERAL, see SelectBuilder.JoinLateral.
+func (b *SelectDocBuilder) JoinLateral(subquery Fragment, alias, on string, args ...interface{}) *SelectDocBuilder {
+	b.SelectBuilder.JoinLateral(subquery, alias, on, args...)
+	return b
+}
+
+// LeftJoinLateral appends a LEFT JOIN LATERAL, see SelectBuilder.JoinLateral.
+func (b *SelectDocBuilder) LeftJoinLateral(subquery Fragment, alias, on string, args ...interface{}) *SelectDocBuilder {
+	b.SelectBuilder.LeftJoinLateral(subquery, alias, on, args...)
+	return b
+}
+
+// AsOfSystemTime reads the table as of a past time, see
+// SelectBuilder.AsOfSystemTime.
+func (b *SelectDocBuilder) AsOfSystemTime(expr string) *SelectDocBuilder {
+	b.SelectBuilder.AsOfSystemTime(expr)
+	return b
+}
+
 // ScopeMap uses a predefined scope in place of WHERE.
 func (b *SelectDocBuilder) ScopeMap(mapScope *MapScope, m M) *SelectDocBuilder {
 	b.scope = mapScope.mergeClone(m)
 	return b
 }
 
+// UseScope uses scope, which may be shared across builders, in place of WHERE.
+func (b *SelectDocBuilder) UseScope(scope Scope) *SelectDocBuilder {
+	b.scope = scope
+	return b
+}
+
+// Unscoped removes any scope from the statement, e.g. to include soft
+// deleted rows.
+func (b *SelectDocBuilder) Unscoped() *SelectDocBuilder {
+	b.scope = nil
+	return b
+}
+
 // Scope uses a predefined scope in place of WHERE.
 func (b *SelectDocBuilder) Scope(sql string, args ...interface{}) *SelectDocBuilder {
 	b.scope = ScopeFunc(func(table string) (string, []interface{}) {
@@ -292,6 +423,21 @@ func (b *SelectDocBuilder) Where(whereSQLOrMap interface{}, args ...interface{})
 	return b
 }
 
+// WhereIf appends a WHERE clause only if cond is true, eg for an optional
+// filter. The args are evaluated either way.
+func (b *SelectDocBuilder) WhereIf(cond bool, whereSQLOrMap interface{}, args ...interface{}) *SelectDocBuilder {
+	if cond {
+		b.Where(whereSQLOrMap, args...)
+	}
+	return b
+}
+
+// Filter appends the conditions of f to the WHERE clause.
+func (b *SelectDocBuilder) Filter(f *FilterBuilder) *SelectDocBuilder {
+	b.whereFragments = filterFragments(b.whereFragments, f)
+	return b
+}
+
 // GroupBy appends a column to group the statement
 func (b *SelectDocBuilder) GroupBy(group string) *SelectDocBuilder {
 	b.groupBys = append(b.groupBys, group)
@@ -310,6 +456,19 @@ func (b *SelectDocBuilder) OrderBy(whereSQLOrMap interface{}, args ...interface{
 	return b
 }
 
+// Seek paginates by key, see SelectBuilder.Seek.
+func (b *SelectDocBuilder) Seek(columns string, last interface{}, dir SeekDirection) *SelectDocBuilder {
+	b.SelectBuilder.Seek(columns, last, dir)
+	return b
+}
+
+// UseArrayBinding binds slices as a single Postgres array when enabled, see
+// SelectBuilder.UseArrayBinding.
+func (b *SelectDocBuilder) UseArrayBinding(enable bool) *SelectDocBuilder {
+	b.arrayBinding = enable
+	return b
+}
+
 // Limit sets a limit for the statement; overrides any existing LIMIT
 func (b *SelectDocBuilder) Limit(limit uint64) *SelectDocBuilder {
 	b.limitCount = limit
@@ -317,6 +476,13 @@ func (b *SelectDocBuilder) Limit(limit uint64) *SelectDocBuilder {
 	return b
 }
 
+// LimitPlaceholder sets a limit for the statement which is sent as a bind
+// argument instead of being inlined; overrides any existing LIMIT
+func (b *SelectDocBuilder) LimitPlaceholder(limit uint64) *SelectDocBuilder {
+	b.SelectBuilder.LimitPlaceholder(limit)
+	return b
+}
+
 // Offset sets an offset for the statement; overrides any existing OFFSET
 func (b *SelectDocBuilder) Offset(offset uint64) *SelectDocBuilder {
 	b.offsetCount = offset
@@ -324,6 +490,13 @@ func (b *SelectDocBuilder) Offset(offset uint64) *SelectDocBuilder {
 	return b
 }
 
+// OffsetPlaceholder sets an offset for the statement which is sent as a bind
+// argument instead of being inlined; overrides any existing OFFSET
+func (b *SelectDocBuilder) OffsetPlaceholder(offset uint64) *SelectDocBuilder {
+	b.SelectBuilder.OffsetPlaceholder(offset)
+	return b
+}
+
 // Paginate sets LIMIT/OFFSET for the statement based on the given page/perPage
 // Assumes page/perPage are valid. Page and perPage must be >= 1
 func (b *SelectDocBuilder) Paginate(page, perPage uint64) *SelectDocBuilder {
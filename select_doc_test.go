@@ -124,7 +124,7 @@ func TestDocScopeWhere(t *testing.T) {
 			SELECT u.*, p.*
 			FROM users u
 				INNER JOIN posts p on (p.author_id = u.id)
-			WHERE (u.id = $1) AND ( p.state = $2 )
+			WHERE (u.id = $1) AND (p.state = $2)
 		) as dat__item
 	`
 	assert.Equal(t, stripWS(expected), stripWS(sql))
@@ -150,7 +150,7 @@ func TestDocDistinctOn(t *testing.T) {
 			u.*, p.*
 			FROM users u
 				INNER JOIN posts p on (p.author_id = u.id)
-			WHERE (u.id = $1) AND ( p.state = $2 )
+			WHERE (u.id = $1) AND (p.state = $2)
 		) as dat__item
 	`
 	assert.Equal(t, stripWS(expected), stripWS(sql))
@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/mgutz/str"
 )
@@ -81,6 +84,28 @@ func TestSelectFullToSql(t *testing.T) {
 	assert.Equal(t, args, []interface{}{1, "wat", 2, 3, []int{4, 5, 6}})
 }
 
+func TestSelectWithTotalCount(t *testing.T) {
+	var total int64
+	b := Select("a", "b").
+		From("c").
+		Where("d = $1", 1).
+		WithTotalCount(&total).
+		Paginate(2, 20)
+	sql, args := b.ToSQL()
+	assert.Equal(t, "SELECT a, b, count(*) OVER () AS dat__total_count FROM c WHERE (d = $1) LIMIT 20 OFFSET 20", sql)
+	assert.Equal(t, []interface{}{1}, args)
+	assert.Equal(t, &total, b.TotalCountDest())
+
+	assert.Panics(t, func() {
+		Select("a").From("c").WithTotalCount(&total).Union(Select("a").From("d")).ToSQL()
+	})
+	assert.Panics(t, func() {
+		b := SelectDoc("a").From("c")
+		b.WithTotalCount(&total)
+		b.ToSQL()
+	})
+}
+
 func TestSelectPaginateOrderDirToSql(t *testing.T) {
 	sql, args := Select("a", "b").
 		From("c").
@@ -103,6 +128,41 @@ func TestSelectPaginateOrderDirToSql(t *testing.T) {
 	assert.Equal(t, args, []interface{}{1})
 }
 
+func TestSelectLimitPlaceholderToSql(t *testing.T) {
+	sql, args := Select("a", "b").
+		From("c").
+		Where("d = $1", 1).
+		OrderBy("id").
+		LimitPlaceholder(30).
+		OffsetPlaceholder(60).
+		ToSQL()
+
+	assert.Equal(t, "SELECT a, b FROM c WHERE (d = $1) ORDER BY id LIMIT $2 OFFSET $3", sql)
+	assert.Equal(t, []interface{}{1, uint64(30), uint64(60)}, args)
+
+	sql, args = Select("a", "b").
+		From("c").
+		Where("d = $1", 1).
+		LimitPlaceholder(30).
+		OffsetPlaceholder(60).
+		ToSQL()
+	sql, args, err := Interpolate(sql, args)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT a, b FROM c WHERE (d = 1) LIMIT 30 OFFSET 60", sql)
+	assert.Equal(t, []interface{}(nil), args)
+}
+
+func TestSelectEnableLimitPlaceholders(t *testing.T) {
+	EnableLimitPlaceholders = true
+	defer func() { EnableLimitPlaceholders = false }()
+
+	sql, args := Select("a").From("c").Paginate(3, 30).ToSQL()
+
+	assert.Equal(t, "SELECT a FROM c LIMIT $1 OFFSET $2", sql)
+	assert.Equal(t, []interface{}{uint64(30), uint64(60)}, args)
+}
+
 func TestSelectNoWhereSql(t *testing.T) {
 	sql, args := Select("a", "b").From("c").ToSQL()
 
@@ -110,6 +170,17 @@ func TestSelectNoWhereSql(t *testing.T) {
 	assert.Equal(t, args, []interface{}(nil))
 }
 
+func TestSelectHavingArgs(t *testing.T) {
+	sql, args := Select("customer_id", "sum(total)").
+		From("orders").
+		Where("status = $1", "paid").
+		GroupBy("customer_id").
+		Having("sum(total) > $1", 100).
+		ToSQL()
+	assert.Equal(t, "SELECT customer_id, sum(total) FROM orders WHERE (status = $1) GROUP BY customer_id HAVING (sum(total) > $2)", sql)
+	assert.Exactly(t, []interface{}{"paid", 100}, args)
+}
+
 func TestSelectMultiHavingSql(t *testing.T) {
 	sql, args := Select("a", "b").From("c").Where("p = $1", 1).GroupBy("z").Having("z = $1", 2).Having("y = $1", 3).ToSQL()
 
@@ -169,11 +240,21 @@ func TestSelectWhereMapSql(t *testing.T) {
 
 func TestSelectWhereEqSql(t *testing.T) {
 	sql, args := Select("a").From("b").Where(Eq{"a": 1, "b": []int64{1, 2, 3}}).ToSQL()
-	if sql == quoteSQL("SELECT a FROM b WHERE (%s = $1) AND (%s IN $2)", "a", "b") {
-		assert.Equal(t, args, []interface{}{1, []int64{1, 2, 3}})
-	} else {
-		assert.Equal(t, sql, quoteSQL("SELECT a FROM b WHERE (%s IN $1) AND (%s = $2)", "b", "a"))
-		assert.Equal(t, args, []interface{}{[]int64{1, 2, 3}, 1})
+	assert.Equal(t, sql, quoteSQL("SELECT a FROM b WHERE (%s = $1) AND (%s IN $2)", "a", "b"))
+	assert.Equal(t, args, []interface{}{1, []int64{1, 2, 3}})
+
+	sql, args = Select("a").From("b").Where(Eq{"a": []int64{}, "b": 1}).ToSQL()
+	assert.Equal(t, sql, quoteSQL("SELECT a FROM b WHERE (1=0) AND (%s = $1)", "b"))
+	assert.Equal(t, args, []interface{}{1})
+}
+
+func TestSelectWhereEqSorted(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		sql, args := Select("a").From("b").
+			Where(Eq{"status": "active", "org_id": 5, "deleted_at": nil, "role": []string{"admin", "owner"}}).
+			ToSQL()
+		assert.Equal(t, quoteSQL("SELECT a FROM b WHERE (%s IS NULL) AND (%s = $1) AND (%s IN $2) AND (%s = $3)", "deleted_at", "org_id", "role", "status"), sql)
+		assert.Equal(t, []interface{}{5, []string{"admin", "owner"}, "active"}, args)
 	}
 }
 
@@ -208,7 +289,7 @@ func TestSelectVarieties(t *testing.T) {
 func TestSelectScope(t *testing.T) {
 	scope := NewScope("WHERE :TABLE.id = :id and name = :name", M{"id": 1, "name": "foo"})
 	sql, args := Select("a").From("b").ScopeMap(scope, M{"name": "mario"}).ToSQL()
-	assert.Equal(t, `SELECT a FROM b WHERE ( "b".id = $1 and name = $2)`, sql)
+	assert.Equal(t, `SELECT a FROM b WHERE ("b".id = $1 and name = $2)`, sql)
 	assert.Exactly(t, args, []interface{}{1, "mario"})
 }
 
@@ -238,7 +319,7 @@ func TestScopeWhere(t *testing.T) {
 		Where(`u.id = $1`, 1).
 		ToSQL()
 	sql = str.Clean(sql)
-	assert.Equal(t, "SELECT u.*, p.* FROM users u INNER JOIN posts p on (p.author_id = u.id) WHERE (u.id = $1) AND ( p.state = $2 )", sql)
+	assert.Equal(t, "SELECT u.*, p.* FROM users u INNER JOIN posts p on (p.author_id = u.id) WHERE (u.id = $1) AND (p.state = $2)", sql)
 	assert.Exactly(t, args, []interface{}{1, "published"})
 }
 
@@ -276,6 +357,46 @@ func TestDistinctOn(t *testing.T) {
 	assert.Exactly(t, args, []interface{}{1})
 }
 
+func TestDistinctOnWithOrderBy(t *testing.T) {
+	sql, args := Select("user_id", "title").
+		DistinctOn("user_id").
+		From("posts").
+		OrderBy("user_id, created_at DESC").
+		ToSQL()
+	assert.Equal(t, "SELECT DISTINCT ON (user_id) user_id, title FROM posts ORDER BY user_id, created_at DESC", sql)
+	assert.Empty(t, args)
+}
+
+func TestDistinctOnConflict(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	SetLogger(zap.New(core))
+	defer SetLogger(nil)
+
+	sql, _ := Select("a").Distinct().DistinctOn("a").From("b").ToSQL()
+	assert.Equal(t, "SELECT DISTINCT ON (a) a FROM b", sql)
+	sql, _ = Select("a").DistinctOn("a").Distinct().From("b").ToSQL()
+	assert.Equal(t, "SELECT DISTINCT ON (a) a FROM b", sql)
+	assert.Equal(t, 2, logs.FilterMessage(distinctConflictMsg).Len())
+
+	Strict = true
+	defer func() { Strict = false }()
+	assert.Panics(t, func() {
+		Select("a").Distinct().DistinctOn("a")
+	})
+}
+
+func TestSelectIsNull(t *testing.T) {
+	sql, args := Select("id").
+		From("posts").
+		Where("user_id = $1", 1).
+		Where(IsNull("deleted_at")).
+		Where(IsNotNull("published_at")).
+		Where("state = $1", "open").
+		ToSQL()
+	assert.Equal(t, "SELECT id FROM posts WHERE (user_id = $1) AND (deleted_at IS NULL) AND (published_at IS NOT NULL) AND (state = $2)", sql)
+	assert.Equal(t, []interface{}{1, "open"}, args)
+}
+
 func TestSelectColumns(t *testing.T) {
 	sql, args := Select("id, user_name").
 		From("users").
@@ -300,3 +421,81 @@ func TestSelectFor(t *testing.T) {
 	`), stripWS(sql))
 	assert.Exactly(t, []interface{}{1000}, args)
 }
+
+func TestSelectLocking(t *testing.T) {
+	sql, _ := Select("id").From("jobs").OrderBy("id").Limit(1).ForUpdate().SkipLocked().ToSQL()
+	assert.Equal(t, "SELECT id FROM jobs ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED", sql)
+
+	sql, _ = Select("j.id").From("jobs j JOIN queues q ON q.id = j.queue_id").ForShare().NoWait().Of("j").ToSQL()
+	assert.Equal(t, "SELECT j.id FROM jobs j JOIN queues q ON q.id = j.queue_id FOR SHARE OF j NOWAIT", sql)
+
+	sql, _ = Select("id").From("jobs").ForNoKeyUpdate().NoWait().SkipLocked().ToSQL()
+	assert.Equal(t, "SELECT id FROM jobs FOR NO KEY UPDATE SKIP LOCKED", sql)
+
+	sql, _ = Select("id").From("jobs").ForKeyShare().ToSQL()
+	assert.Equal(t, "SELECT id FROM jobs FOR KEY SHARE", sql)
+
+	assert.Panics(t, func() {
+		Select("id").From("jobs").SkipLocked()
+	})
+}
+
+func TestSelectWhereNilSql(t *testing.T) {
+	var deletedAt *NullTime
+	sql, args := Select("a").From("b").Where(Eq{"deleted_at": deletedAt}).ToSQL()
+	assert.Equal(t, stripWS(`SELECT a FROM b WHERE ("deleted_at" IS NULL)`), stripWS(sql))
+	assert.Empty(t, args)
+
+	sql, args = Select("a").From("b").Where("x = $1 AND y <> $2", nil, 1).ToSQL()
+	assert.Equal(t, "SELECT a FROM b WHERE (x = $1 AND y <> $2)", sql)
+	assert.Equal(t, []interface{}{nil, 1}, args)
+}
+
+func TestSelectRewriteNullEquality(t *testing.T) {
+	RewriteNullEquality = true
+	defer func() { RewriteNullEquality = false }()
+
+	var p *int64
+	sql, args := Select("a").From("b").
+		Where("x = $1 AND y <> $2 AND z != $3 AND w >= $4", nil, 1, p, 2).
+		Where("v = $1", 3).
+		ToSQL()
+	assert.Equal(t, "SELECT a FROM b WHERE (x IS NULL AND y <> $1 AND z IS NOT NULL AND w >= $2) AND (v = $3)", sql)
+	assert.Equal(t, []interface{}{1, 2, 3}, args)
+
+	// an arg still referenced elsewhere is kept
+	sql, args = Select("a").From("b").Where("x = $1 OR $1 IS NULL", nil).ToSQL()
+	assert.Equal(t, "SELECT a FROM b WHERE (x IS NULL OR $1 IS NULL)", sql)
+	assert.Equal(t, []interface{}{nil}, args)
+
+	sql, args = Select("a").From("b").Where(Expr("x=$1", nil)).ToSQL()
+	assert.Equal(t, "SELECT a FROM b WHERE (x IS NULL)", sql)
+	assert.Empty(t, args)
+}
+
+func TestSelectWhereOrAnd(t *testing.T) {
+	sql, args := Select("a").From("b").
+		Where(Or(Expr("a = $1", 1), Eq{"b": 2}, "c IS NULL")).
+		Where("d = $1", 3).
+		ToSQL()
+	assert.Equal(t, `SELECT a FROM b WHERE ((a = $1) OR ("b" = $2) OR (c IS NULL)) AND (d = $3)`, sql)
+	assert.Equal(t, []interface{}{1, 2, 3}, args)
+
+	sql, args = Select("a").From("b").
+		Where("x = $1", 0).
+		Where(Or(
+			And(Expr("a = $1", 1), Expr("b = $1 OR b = $2", 2, 3)),
+			And(Eq{"c": 4, "d": nil}, Expr("e = $1", 5)),
+			Eq{"f": 6, "g": []int{7, 8}},
+		)).
+		ToSQL()
+	assert.Equal(t, stripWS(`SELECT a FROM b WHERE (x = $1) AND (
+		((a = $2) AND (b = $3 OR b = $4)) OR
+		(("c" = $5) AND ("d" IS NULL) AND (e = $6)) OR
+		(("f" = $7) AND ("g" IN $8)))`), stripWS(sql))
+	assert.Equal(t, []interface{}{0, 1, 2, 3, 4, 5, 6, []int{7, 8}}, args)
+
+	sql, args = Select("a").From("b").Where(Or()).Where(And()).ToSQL()
+	assert.Equal(t, "SELECT a FROM b WHERE (FALSE) AND (TRUE)", sql)
+	assert.Empty(t, args)
+}
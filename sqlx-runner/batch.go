@@ -0,0 +1,238 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/casualjim/dat"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"go.uber.org/zap"
+)
+
+// ErrBatchUnsupported occurs when a Batch is not sent on a DB of the pgx
+// driver, eg on lib/pq or within a Tx, whose connection is not reachable
+// through database/sql.
+var ErrBatchUnsupported = errors.New("Batch requires a DB of the pgx driver")
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+var timeType = reflect.TypeOf(time.Time{})
+
+// Batch is a list of queries sent to the database in a single round trip
+// with a pgx batch, eg independent selects of a dashboard. The queries keep
+// their bind args. Postgres runs them in an implicit transaction, so a
+// failing query rolls back those before it. Only a DB of the pgx stdlib
+// driver is supported.
+type Batch struct {
+	q       *Queryable
+	queries []batchQuery
+}
+
+type batchQuery struct {
+	builder dat.Builder
+	dest    interface{}
+}
+
+// NewBatch creates an empty Batch.
+func (q *Queryable) NewBatch() *Batch {
+	return &Batch{q: q}
+}
+
+// Queue appends the query of builder to the batch. Its rows are scanned into
+// dest like QueryStructs if dest is a pointer to a slice, otherwise the first
+// row is scanned like QueryStruct or QueryScalar. A nil dest discards the
+// rows. The query must return rows, eg an UPDATE needs a RETURNING clause.
+func (b *Batch) Queue(builder dat.Builder, dest interface{}) *Batch {
+	b.queries = append(b.queries, batchQuery{builder: builder, dest: dest})
+	return b
+}
+
+// Len returns the number of queued queries.
+func (b *Batch) Len() int {
+	return len(b.queries)
+}
+
+// pgxDB is the *sqlx.DB of a Queryable which may send a Batch.
+type pgxDB interface {
+	DriverName() string
+	Conn(ctx context.Context) (*sql.Conn, error)
+}
+
+// Send executes the queued queries and scans their results. A query
+// returning no row for a non-slice dest fails with dat.ErrNotFound.
+// ErrBatchUnsupported is returned unless the Batch is sent on a DB of the
+// pgx driver.
+func (b *Batch) Send(ctx context.Context) (err error) {
+	if len(b.queries) == 0 {
+		return nil
+	}
+	db, ok := b.q.runner.(pgxDB)
+	if !ok || db.DriverName() != "pgx" {
+		return ErrBatchUnsupported
+	}
+
+	batch := &pgx.Batch{}
+	statements := make([]string, len(b.queries))
+	args := make([][]interface{}, len(b.queries))
+	for i, bq := range b.queries {
+		statements[i], args[i], err = dat.ToSQL(bq.builder)
+		if err != nil {
+			return err
+		}
+		batch.Queue(statements[i], args[i]...)
+	}
+	fullSQL := strings.Join(statements, ";\n")
+
+	lg := logger()
+	ctx, end := observe(ctx, fullSQL, nil)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(lg, time.Now(), fullSQL, nil, zap.Int("queries", len(b.queries)))
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return ctxErr(ctx, logSQLError(lg, err, "batch.1", fullSQL, nil))
+	}
+	defer conn.Close()
+
+	var mapper *reflectx.Mapper
+	if dbx, ok := db.(*sqlx.DB); ok {
+		mapper = dbx.Mapper
+	}
+	return conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return ErrBatchUnsupported
+		}
+		results := c.Conn().SendBatch(ctx, batch)
+		for i, bq := range b.queries {
+			rows, err := results.Query()
+			if err != nil {
+				results.Close()
+				return ctxErr(ctx, logSQLError(lg, err, "batch.2", statements[i], args[i]))
+			}
+			err = scanResult(&batchRows{Rows: rows, mapper: mapper}, bq.dest, extraDests(bq.builder))
+			rows.Close()
+			if err != nil {
+				results.Close()
+				if err == dat.ErrNotFound {
+					return err
+				}
+				return ctxErr(ctx, logSQLError(lg, err, "batch.3", statements[i], args[i]))
+			}
+		}
+		if err := results.Close(); err != nil {
+			return ctxErr(ctx, logSQLError(lg, err, "batch.4", fullSQL, nil))
+		}
+		return nil
+	})
+}
+
+// batchRows are the rows of a query of a Batch.
+type batchRows struct {
+	pgx.Rows
+	mapper *reflectx.Mapper
+}
+
+func (r *batchRows) Columns() ([]string, error) {
+	fields := r.FieldDescriptions()
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = string(f.Name)
+	}
+	return columns, nil
+}
+
+// resultRows are the rows scanned by scanResult, a *sqlx.Rows or
+// *batchRows.
+type resultRows interface {
+	Next() bool
+	Err() error
+	Columns() ([]string, error)
+	Scan(dest ...interface{}) error
+}
+
+// rowsMapper returns the mapper of the struct fields of rows.
+func rowsMapper(rows resultRows) *reflectx.Mapper {
+	switch r := rows.(type) {
+	case *sqlx.Rows:
+		if r.Mapper != nil {
+			return r.Mapper
+		}
+	case *batchRows:
+		if r.mapper != nil {
+			return r.mapper
+		}
+	}
+	return defaultMapper
+}
+
+// scanResult scans the rows of the current result set into dest and the
+// extra columns into extras.
+func scanResult(rows resultRows, dest interface{}, extras map[string]interface{}) error {
+	if dest == nil {
+		for rows.Next() {
+		}
+		return rows.Err()
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("Destination must be a non-nil pointer")
+	}
+
+	slice := v.Elem()
+	if slice.Kind() == reflect.Slice && slice.Type().Elem().Kind() != reflect.Uint8 {
+		base := slice.Type().Elem()
+		isPtr := base.Kind() == reflect.Ptr
+		if isPtr {
+			base = base.Elem()
+		}
+		for rows.Next() {
+			item := reflect.New(base)
+			if err := scanRow(rows, item.Interface(), extras); err != nil {
+				return err
+			}
+			if isPtr {
+				slice.Set(reflect.Append(slice, item))
+			} else {
+				slice.Set(reflect.Append(slice, item.Elem()))
+			}
+		}
+		return rows.Err()
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if resetExtras(extras) {
+			return nil
+		}
+		return dat.ErrNotFound
+	}
+	if err := scanRow(rows, dest, extras); err != nil {
+		return err
+	}
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// scanRow scans the current row into the struct or value pointed to by dest
+// and the extra columns into extras.
+func scanRow(rows resultRows, dest interface{}, extras map[string]interface{}) error {
+	t := reflect.TypeOf(dest).Elem()
+	if t.Kind() == reflect.Struct && t != timeType && !reflect.PtrTo(t).Implements(scannerType) {
+		if r, ok := rows.(*sqlx.Rows); ok && extras == nil && !hasJSONFields(t) {
+			return r.StructScan(dest)
+		}
+		return scanStruct(rows, dest, extras)
+	}
+	return rows.Scan(withExtras([]interface{}{dest}, extras)...)
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/casualjim/dat"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// pgxTestDB opens a DB of the pgx driver on the test database.
+func pgxTestDB(t *testing.T) *DB {
+	db, err := sql.Open("pgx", os.Getenv("DAT_DSN"))
+	assert.NoError(t, err)
+	return NewDB(db, "pgx")
+}
+
+func TestBatchSend(t *testing.T) {
+	installFixtures()
+	db := pgxTestDB(t)
+	defer db.DB.Close()
+
+	var people []*Person
+	var names []string
+	var person Person
+	var total int64
+	var count int64
+	b := db.NewBatch().
+		Queue(db.Select("id", "name").From("people").Where("id < $1", 3).OrderBy("id"), &people).
+		Queue(db.Select("name").From("people").Where("name LIKE $1", "%o%").OrderBy("id"), &names).
+		Queue(db.Select("id", "name").From("people").Where("id = $1", 4).WithTotalCount(&total), &person).
+		Queue(db.Select("id").From("people").Where("key = $1", dat.Secret("abc")), nil).
+		Queue(db.SQL("SELECT count(*) FROM people"), &count)
+	assert.Equal(t, 5, b.Len())
+
+	err := b.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(people))
+	assert.Equal(t, "John", people[1].Name)
+	assert.Equal(t, []string{"Mario", "John", "Tony"}, names)
+	assert.Equal(t, "Tony", person.Name)
+	assert.EqualValues(t, 1, total)
+	assert.EqualValues(t, 6, count)
+}
+
+func TestBatchErrors(t *testing.T) {
+	installFixtures()
+	db := pgxTestDB(t)
+	defer db.DB.Close()
+
+	var p Person
+	err := db.NewBatch().
+		Queue(db.Select("id").From("people").Where("id = $1", 1000), &p).
+		Send(context.Background())
+	assert.Equal(t, dat.ErrNotFound, err)
+
+	var ids []int64
+	err = db.NewBatch().Queue(db.SQL("SELECT id FROM nonexistent"), &ids).Send(context.Background())
+	assert.Error(t, err)
+}
+
+func TestBatchUnsupported(t *testing.T) {
+	var n int64
+	q := &Queryable{runner: sqlx.NewDb(testDB.SQLDB(), "postgres")}
+	err := q.NewBatch().Queue(q.SQL("SELECT 1"), &n).Send(context.Background())
+	assert.Exactly(t, ErrBatchUnsupported, err)
+
+	db := pgxTestDB(t)
+	defer db.DB.Close()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+	err = tx.NewBatch().Queue(tx.SQL("SELECT 1"), &n).Send(context.Background())
+	assert.Exactly(t, ErrBatchUnsupported, err)
+}
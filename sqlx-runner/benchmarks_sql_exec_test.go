@@ -117,3 +117,33 @@ func benchInsertBuilder(rows int, argc int) (*dat.InsertBuilder, error) {
 	}
 	return builder, nil
 }
+
+// These benchmarks compare scanning 100k rows into a slice grown by append
+// to one presized with ExpectRows.
+
+type benchSeries struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+const benchSeriesSQL = `SELECT i AS id, 'name' || i AS name FROM generate_series(1, 100000) i`
+
+func BenchmarkQueryStructsGrow(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var rows []*benchSeries
+		if err := testDB.SQL(benchSeriesSQL).QueryStructs(&rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQueryStructsExpectRows(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var rows []*benchSeries
+		if err := testDB.SQL(benchSeriesSQL).ExpectRows(100000).QueryStructs(&rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
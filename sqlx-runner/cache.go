@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/casualjim/dat"
+	"github.com/casualjim/dat/kvs"
+	"go.uber.org/zap"
+)
+
+// CacheTags tags the cached result of the query so it is expired by
+// InvalidateCache with any of tags. Use with Cache.
+func (ex *Execer) CacheTags(tags ...string) dat.Execer {
+	ex.cacheTags = append(ex.cacheTags, tags...)
+	return ex
+}
+
+// InvalidateCache expires every cached result tagged with any of tags.
+func InvalidateCache(tags ...string) error {
+	if Cache == nil {
+		return nil
+	}
+	for _, tag := range tags {
+		if err := Cache.Set(tagCacheKey(tag), newTagVersion(), kvs.TTLNever); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tagCacheKey(tag string) string {
+	return "dat:tag:" + tag
+}
+
+func newTagVersion() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// taggedCacheKey appends the current version of each tag to key. Results
+// cached under a previous version are no longer read once a tag is
+// invalidated.
+func taggedCacheKey(key string, tags []string) string {
+	if len(tags) == 0 {
+		return key
+	}
+
+	versions := make([]string, len(tags))
+	for i, tag := range tags {
+		version := getCache(tagCacheKey(tag))
+		if version == "" {
+			// a missing tag may have been evicted, start a new version so
+			// stale results cannot be read
+			version = newTagVersion()
+			if err := Cache.Set(tagCacheKey(tag), version, kvs.TTLNever); err != nil {
+				logger().Warn("Could not set cache tag", zap.String("tag", tag), zap.Error(err))
+			}
+		}
+		versions[i] = version
+	}
+	return key + ":" + strings.Join(versions, ".")
+}
+
+// sqlCacheKey hashes sql, without any timeout query ID, and args.
+func (ex *Execer) sqlCacheKey(sql string, args []interface{}) string {
+	if ex.timeout > 0 {
+		sql = strings.TrimPrefix(sql, prependDatQueryID("", ex.queryID))
+	}
+	if len(args) == 0 {
+		return kvs.Hash(sql)
+	}
+	args = unwrapSecrets(args)
+	b, err := json.Marshal(args)
+	if err != nil {
+		return kvs.Hash(sql + fmt.Sprintf("%#v", args))
+	}
+	return kvs.Hash(sql + string(b))
+}
+
+// unwrapSecrets replaces secrets in args with their values, which marshal
+// and print redacted, so queries for different secrets get different keys.
+// The key is a hash so the values are not revealed.
+func unwrapSecrets(args []interface{}) []interface{} {
+	var unwrapped []interface{}
+	for i, arg := range args {
+		secret, ok := arg.(dat.SecretArg)
+		if !ok {
+			continue
+		}
+		if unwrapped == nil {
+			unwrapped = make([]interface{}, len(args))
+			copy(unwrapped, args)
+		}
+		// an unconvertible value fails the query, which is not cached
+		unwrapped[i], _ = secret.Value()
+	}
+	if unwrapped == nil {
+		return args
+	}
+	return unwrapped
+}
+
+// getCache returns the cached value for key or "" if it was not found or
+// the cache could not be read.
+func getCache(key string) string {
+	v, err := Cache.Get(key)
+	if err != nil && err != kvs.ErrNotFound {
+		logger().Error("Unable to read cache key. Continuing with query", zap.String("key", key), zap.Error(err))
+		return ""
+	}
+	return v
+}
@@ -15,6 +15,15 @@ func TestCachePre(t *testing.T) {
 	installFixtures()
 }
 
+func TestCacheKeySecrets(t *testing.T) {
+	ex := &Execer{}
+	sql := "SELECT id FROM people WHERE key = $1"
+
+	key := ex.sqlCacheKey(sql, []interface{}{dat.Secret("abc")})
+	assert.NotEqual(t, key, ex.sqlCacheKey(sql, []interface{}{dat.Secret("xyz")}))
+	assert.Equal(t, key, ex.sqlCacheKey(sql, []interface{}{dat.Secret("abc")}))
+}
+
 func TestCacheSelectDocBytes(t *testing.T) {
 	Cache.FlushDB()
 	for i := 0; i < 2; i++ {
@@ -129,6 +138,26 @@ func TestCacheSelectQueryStructs(t *testing.T) {
 	}
 }
 
+func TestCacheSelectWithTotalCount(t *testing.T) {
+	Cache.FlushDB()
+	for i := 0; i < 2; i++ {
+		var people []Person
+		var total int64
+		err := testDB.
+			Select("id", "name").
+			From("people").
+			OrderBy("id ASC").
+			WithTotalCount(&total).
+			Limit(2).
+			Cache("selectdoc.total", 1*time.Second, false).
+			QueryStructs(&people)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(people))
+		assert.EqualValues(t, 6, total)
+	}
+}
+
 func TestCacheSelectQueryStruct(t *testing.T) {
 	Cache.FlushDB()
 	for i := 0; i < 2; i++ {
@@ -261,3 +290,50 @@ func TestCacheSelectQuerySliceByHash(t *testing.T) {
 		assert.Equal(t, ids, []int64{1})
 	}
 }
+
+func TestCacheKeyIncludesArgs(t *testing.T) {
+	Cache.FlushDB()
+	for _, email := range []string{"john@acme.com", "mario@acme.com", "john@acme.com"} {
+		var name string
+		err := testDB.
+			Select("name").
+			From("people").
+			Where("email = $1", email).
+			Cache("", 1*time.Second, false).
+			QueryScalar(&name)
+		assert.NoError(t, err)
+		if email == "john@acme.com" {
+			assert.Equal(t, "John", name)
+		} else {
+			assert.Equal(t, "Mario", name)
+		}
+	}
+}
+
+func TestCacheTagsInvalidate(t *testing.T) {
+	installFixtures()
+	defer installFixtures()
+	Cache.FlushDB()
+
+	query := func() string {
+		var name string
+		err := testDB.
+			Select("name").
+			From("people").
+			Where("id = $1", 1).
+			Cache("", 10*time.Second, false).
+			CacheTags("people", "people:1").
+			QueryScalar(&name)
+		assert.NoError(t, err)
+		return name
+	}
+
+	assert.Equal(t, "Mario", query())
+
+	_, err := testDB.Update("people").Set("name", "Luigi").Where("id = $1", 1).Exec()
+	assert.NoError(t, err)
+	assert.Equal(t, "Mario", query())
+
+	assert.NoError(t, InvalidateCache("people:1"))
+	assert.Equal(t, "Luigi", query())
+}
@@ -0,0 +1,57 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := testDB.SQL("SELECT pg_sleep(2)").ExecContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	result, err := testDB.SQL("SELECT 0").ExecContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.RowsAffected)
+}
+
+func TestQueryStructContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	var person TimeoutPerson
+	err := testDB.SQL("SELECT pg_sleep(2) as na, 'timeout' as name").QueryStructContext(ctx, &person)
+	assert.Equal(t, context.Canceled, err)
+
+	err = testDB.SQL("SELECT 'john' as name, 10 as age").QueryStructContext(context.Background(), &person)
+	assert.NoError(t, err)
+	assert.Equal(t, "john", person.Name)
+}
+
+func TestQueryStructsContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var people []TimeoutPerson
+	err := testDB.SQL("SELECT 'john' as name, 10 as age").QueryStructsContext(ctx, &people)
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, people)
+}
+
+func TestQueryableExecContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := testDB.ExecContext(ctx, "SELECT pg_sleep(2)")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
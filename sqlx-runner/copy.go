@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/lib/pq"
+)
+
+// BulkCopy loads rows into table using the COPY protocol, which is much
+// faster than INSERT for large data sets. The rows are copied within a
+// single transaction. It returns the number of rows copied.
+func (db *DB) BulkCopy(table string, columns []string, rows [][]interface{}) (int64, error) {
+	return db.bulkCopy(table, columns, sliceRows(rows))
+}
+
+// BulkCopyReader is like BulkCopy but streams CSV records from r. Each
+// field is sent to Postgres as text and converted to the column type.
+func (db *DB) BulkCopyReader(table string, columns []string, r io.Reader) (int64, error) {
+	return db.bulkCopy(table, columns, csvRows(r, len(columns)))
+}
+
+// BulkCopy loads rows into table using the COPY protocol within this
+// transaction. It returns the number of rows copied.
+func (tx *Tx) BulkCopy(table string, columns []string, rows [][]interface{}) (int64, error) {
+	return copyRows(tx, table, columns, sliceRows(rows))
+}
+
+// bulkCopy copies rows in a new transaction.
+func (db *DB) bulkCopy(table string, columns []string, next func() ([]interface{}, error)) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.AutoRollback()
+
+	n, err := copyRows(tx, table, columns, next)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// copyRows copies the rows returned by next, until it returns io.EOF.
+func copyRows(tx *Tx, table string, columns []string, next func() ([]interface{}, error)) (int64, error) {
+	stmt, err := tx.Tx.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var n int64
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if _, err = stmt.Exec(row...); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	// flush buffered rows
+	if _, err = stmt.Exec(); err != nil {
+		return 0, err
+	}
+	return n, stmt.Close()
+}
+
+func sliceRows(rows [][]interface{}) func() ([]interface{}, error) {
+	i := 0
+	return func() ([]interface{}, error) {
+		if i == len(rows) {
+			return nil, io.EOF
+		}
+		i++
+		return rows[i-1], nil
+	}
+}
+
+func csvRows(r io.Reader, columns int) func() ([]interface{}, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = columns
+	cr.ReuseRecord = true
+	row := make([]interface{}, columns)
+	return func() ([]interface{}, error) {
+		record, err := cr.Read()
+		if err != nil {
+			return nil, err
+		}
+		for i, field := range record {
+			row[i] = field
+		}
+		return row, nil
+	}
+}
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkCopy(t *testing.T) {
+	installFixtures()
+
+	rows := make([][]interface{}, 500)
+	for i := range rows {
+		rows[i] = []interface{}{"copied", "copied@acme.com"}
+	}
+	n, err := testDB.BulkCopy("people", []string{"name", "email"}, rows)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 500, n)
+
+	var count int64
+	err = testDB.SQL("SELECT count(*) FROM people WHERE name = $1", "copied").QueryScalar(&count)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 500, count)
+}
+
+func TestBulkCopyReader(t *testing.T) {
+	installFixtures()
+
+	csv := "streamed,1.5\nstreamed,2.5\n"
+	n, err := testDB.BulkCopyReader("people", []string{"name", "amount"}, strings.NewReader(csv))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	var sum float64
+	err = testDB.SQL("SELECT sum(amount) FROM people WHERE name = $1", "streamed").QueryScalar(&sum)
+	assert.NoError(t, err)
+	assert.Equal(t, 4.0, sum)
+
+	// a bad record rolls back the whole copy
+	_, err = testDB.BulkCopyReader("people", []string{"name", "amount"}, strings.NewReader("bad,1\nbad,x\n"))
+	assert.Error(t, err)
+	err = testDB.SQL("SELECT count(*) FROM people WHERE name = $1", "bad").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+}
+
+func TestTxBulkCopy(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	n, err := s.BulkCopy("people", []string{"name"}, [][]interface{}{{"a"}, {"b"}})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+
+	"github.com/casualjim/dat"
+)
+
+// ErrCursorClosed occurs when a closed cursor is fetched from.
+var ErrCursorClosed = errors.New("Cursor is closed")
+
+// Cursor is a Postgres server-side cursor, which keeps both client and
+// server memory flat while scanning a large result. See Tx.DeclareCursor.
+type Cursor struct {
+	tx     *Tx
+	name   string
+	closed bool
+}
+
+// DeclareCursor declares a server-side cursor named name for the query of
+// b. Rows are read in batches with Fetch until it returns no rows. The
+// cursor is valid until it is closed or the transaction ends.
+//
+//	cursor, err := tx.DeclareCursor("export", tx.Select("*").From("events"))
+//	if err != nil {
+//		return err
+//	}
+//	defer cursor.Close()
+//	for {
+//		var events []*Event
+//		if err := cursor.Fetch(1000, &events); err != nil {
+//			return err
+//		}
+//		if len(events) == 0 {
+//			break
+//		}
+//		// write events
+//	}
+func (tx *Tx) DeclareCursor(name string, b dat.Builder) (*Cursor, error) {
+	if tx.IsRollbacked {
+		return nil, ErrTxRollbacked
+	}
+	sql, args, err := dat.ToSQL(b)
+	if err != nil {
+		return nil, err
+	}
+	quoted := dat.QuoteIdent(name)
+	_, err = tx.SQL("DECLARE "+quoted+" NO SCROLL CURSOR FOR "+sql, args...).Exec()
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor{tx: tx, name: quoted}, nil
+}
+
+// Fetch replaces the contents of dest, a pointer to a slice of structs,
+// with up to n rows from the cursor. dest is empty once the cursor is
+// exhausted.
+func (c *Cursor) Fetch(n int, dest interface{}) error {
+	if c.closed {
+		return ErrCursorClosed
+	}
+	slice := reflect.ValueOf(dest).Elem()
+	slice.Set(slice.Slice(0, 0))
+	return c.tx.SQL("FETCH FORWARD " + strconv.Itoa(n) + " FROM " + c.name).QueryStructs(dest)
+}
+
+// Close closes the cursor. Closing a closed cursor does nothing.
+func (c *Cursor) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	_, err := c.tx.Exec("CLOSE " + c.name)
+	return err
+}
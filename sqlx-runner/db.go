@@ -1,10 +1,15 @@
 package runner
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/casualjim/dat"
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 	"go.uber.org/zap"
 )
 
@@ -13,6 +18,108 @@ type DB struct {
 	DB *sqlx.DB
 	*Queryable
 	Version int64
+
+	// statementTimeout is the statement_timeout of transactions, 0 for the
+	// server default
+	statementTimeout time.Duration
+
+	// connString is the connection string used by Listen
+	connString string
+
+	// txs are the open transactions, see Drain
+	txs txTracker
+}
+
+// SetQueryTimeout sets the default timeout for queries executed directly
+// against this DB which neither set their own Timeout nor run with a ctx
+// carrying a deadline. When a query times out, it is cancelled with
+// pg_cancel_backend and dat.ErrTimedout is returned. Queries within a Tx
+// are not affected. 0 disables the default timeout.
+func (db *DB) SetQueryTimeout(d time.Duration) {
+	db.Queryable.timeout = d
+}
+
+// SetStatementTimeout sets the statement_timeout of transactions begun from
+// this DB, see Tx.SetStatementTimeout. Unlike SetQueryTimeout the server
+// aborts a statement running longer than d even if the client is gone. To
+// limit statements outside of transactions too, open the DB with a
+// connection string from WithStatementTimeout. 0 disables the default.
+func (db *DB) SetStatementTimeout(d time.Duration) {
+	db.statementTimeout = d
+}
+
+// PoolConfig configures the connection pool of a DB. Zero values leave the
+// current setting unchanged.
+type PoolConfig struct {
+	// MaxOpenConns is the maximum number of open connections.
+	MaxOpenConns int
+
+	// MaxIdleConns is the maximum number of idle connections.
+	MaxIdleConns int
+
+	// ConnMaxLifetime is the maximum time a connection may be reused.
+	ConnMaxLifetime time.Duration
+
+	// ConnMaxIdleTime is the maximum time a connection may be idle.
+	ConnMaxIdleTime time.Duration
+}
+
+// SetPoolConfig applies config to the underlying connection pool.
+func (db *DB) SetPoolConfig(config PoolConfig) {
+	if config.MaxOpenConns != 0 {
+		db.DB.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns != 0 {
+		db.DB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime != 0 {
+		db.DB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime != 0 {
+		db.DB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+}
+
+// PoolStats returns the connection pool statistics, eg to export metrics.
+func (db *DB) PoolStats() sql.DBStats {
+	return db.DB.Stats()
+}
+
+// SetColumnMapper maps the names of struct fields without a db tag to
+// columns with mapper, eg dat.SnakeCase, both when scanning results and when
+// deriving columns from records. A nil mapper restores the defaults.
+// Transactions begun afterwards use mapper. Since record derivation is
+// global, see dat.SetColumnMapper, it should be called once before the DB
+// is used.
+func (db *DB) SetColumnMapper(mapper func(string) string) {
+	if mapper == nil {
+		db.DB.Mapper = reflectx.NewMapperFunc("db", strings.ToLower)
+	} else {
+		db.DB.Mapper = reflectx.NewMapperFunc("db", mapper)
+	}
+	dat.SetColumnMapper(mapper)
+}
+
+// HealthCheckTimeout is the longest HealthCheck waits for the database.
+var HealthCheckTimeout = 2 * time.Second
+
+// HealthCheck verifies the pool can serve a query by running SELECT 1 on
+// an idle or new connection, eg for a readiness probe. The query is not
+// logged and times out after HealthCheckTimeout.
+func (db *DB) HealthCheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	var n int
+	if err := db.DB.QueryRowContext(ctx, "SELECT 1").Scan(&n); err != nil {
+		return fmt.Errorf("Health check failed: %v", err)
+	}
+	return nil
+}
+
+// SQLDB returns the underlying *sql.DB.
+func (db *DB) SQLDB() *sql.DB {
+	return db.DB.DB
 }
 
 var standardConformingStrings string
@@ -36,7 +143,7 @@ func pgMustNotAllowEscapeSequence(conn *DB) {
 	}
 
 	if standardConformingStrings != "on" {
-		logger.Fatal("Database allows escape sequences. Cannot be used with interpolation. "+
+		logger().Fatal("Database allows escape sequences. Cannot be used with interpolation. "+
 			"standard_conforming_strings=%q\n"+
 			"See http://www.postgresql.org/docs/9.3/interactive/sql-syntax-lexical.html#SQL-SYNTAX-STRINGS-ESCAPE",
 			zap.String("standardConformingStrings", standardConformingStrings))
@@ -48,16 +155,17 @@ func pgSetVersion(db *DB) {
 		SQL("SHOW server_version_num").
 		QueryScalar(&db.Version)
 	if err != nil {
-		logger.Fatal("Could not query Postgres version")
+		logger().Fatal("Could not query Postgres version")
 		return
 	}
 }
 
-// NewDB instantiates a Connection for a given database/sql connection
+// NewDB instantiates a Connection for a given database/sql connection. The
+// driver must be "postgres" (lib/pq) or "pgx" (pgx stdlib).
 func NewDB(db *sql.DB, driverName string) *DB {
 	database := sqlx.NewDb(db, driverName)
-	conn := &DB{DB: database, Queryable: &Queryable{database}}
-	if driverName == "postgres" {
+	conn := &DB{DB: database, Queryable: &Queryable{runner: database}}
+	if driverName == "postgres" || driverName == "pgx" {
 		pgMustNotAllowEscapeSequence(conn)
 		pgSetVersion(conn)
 		if dat.Strict {
@@ -74,18 +182,20 @@ func NewDB(db *sql.DB, driverName string) *DB {
 func NewDBFromString(driver string, connectionString string) *DB {
 	db, err := sql.Open(driver, connectionString)
 	if err != nil {
-		logger.Fatal("Database error ", zap.Error(err))
+		logger().Fatal("Database error ", zap.Error(err))
 	}
 	err = db.Ping()
 	if err != nil {
-		logger.Fatal("Could not ping database", zap.Error(err))
+		logger().Fatal("Could not ping database", zap.Error(err))
 	}
-	return NewDB(db, driver)
+	conn := NewDB(db, driver)
+	conn.connString = connectionString
+	return conn
 }
 
 // NewDBFromSqlx creates a new Connection object from existing Sqlx.DB.
 func NewDBFromSqlx(dbx *sqlx.DB) *DB {
-	conn := &DB{DB: dbx, Queryable: &Queryable{dbx}}
+	conn := &DB{DB: dbx, Queryable: &Queryable{runner: dbx}}
 	pgMustNotAllowEscapeSequence(conn)
 	pgSetVersion(conn)
 	return conn
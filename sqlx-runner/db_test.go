@@ -1,8 +1,12 @@
 package runner
 
 import (
+	"context"
+	"database/sql"
 	"testing"
+	"time"
 
+	"github.com/casualjim/dat"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -10,3 +14,64 @@ func TestVersion(t *testing.T) {
 	// require at least 9.3+ for testing
 	assert.True(t, testDB.Version > 90300)
 }
+
+func TestPing(t *testing.T) {
+	assert.NoError(t, Ping(context.Background(), sqlDB))
+}
+
+func TestPingUnreachable(t *testing.T) {
+	db, err := sql.Open("postgres", "postgres://dat@127.0.0.1:1/dat?sslmode=disable")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = Ping(ctx, db)
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	err = PingWithOptions(context.Background(), db, PingOptions{MaxInterval: 10 * time.Millisecond, MaxElapsedTime: 50 * time.Millisecond})
+	assert.Error(t, err)
+}
+
+func TestPoolConfig(t *testing.T) {
+	assert.Exactly(t, sqlDB, testDB.SQLDB())
+
+	testDB.SetPoolConfig(PoolConfig{MaxOpenConns: 7, ConnMaxLifetime: time.Hour})
+	defer testDB.SetPoolConfig(PoolConfig{MaxOpenConns: -1})
+
+	stats := testDB.PoolStats()
+	assert.Equal(t, 7, stats.MaxOpenConnections)
+}
+
+func TestHealthCheck(t *testing.T) {
+	assert.NoError(t, testDB.HealthCheck(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, testDB.HealthCheck(ctx))
+}
+
+func TestSetColumnMapper(t *testing.T) {
+	type Post struct {
+		ID     int64
+		UserID int64
+		Title  string
+	}
+
+	testDB.SetColumnMapper(dat.SnakeCase)
+	defer testDB.SetColumnMapper(nil)
+
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var post Post
+	err := s.Select("id", "user_id", "title").From("posts").Where("id = $1", 3).QueryStruct(&post)
+	assert.NoError(t, err)
+	assert.Equal(t, Post{ID: 3, UserID: 2, Title: "Apple"}, post)
+
+	err = s.InsertInto("posts").Blacklist("id").Record(&Post{UserID: 1, Title: "Day 3"}).Returning("id").QueryScalar(&post.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 100, post.ID)
+}
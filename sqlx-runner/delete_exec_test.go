@@ -69,3 +69,71 @@ func TestDeleteScope(t *testing.T) {
 	assert.NoError(t, err)
 	assert.EqualValues(t, count, 0)
 }
+
+func TestDeleteSoftDeleteScopeWithWhere(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.Update("posts").Set("deleted_at", dat.Expr("now()")).Where("id = $1", 2).Exec()
+	assert.NoError(t, err)
+
+	// the scope is AND-ed with Where, sparing the deleted post and the
+	// posts of other users
+	res, err := s.
+		DeleteFrom("posts").
+		UseScope(dat.SoftDelete("deleted_at")).
+		Where("user_id = $1", 1).
+		Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, res.RowsAffected)
+
+	var ids []int64
+	err = s.Select("id").From("posts").OrderBy("id").QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 4}, ids)
+}
+
+func TestDeleteReturning(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.InsertInto("people").
+		Columns("name", "email").
+		Values("Barack", "barack@whitehouse.gov").
+		Values("Michelle", "michelle@whitehouse.gov").
+		Exec()
+	assert.NoError(t, err)
+
+	var people []Person
+	err = s.
+		DeleteFrom("people").
+		Where("email LIKE $1", "%@whitehouse.gov").
+		Returning("*").
+		QueryStructs(&people)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(people))
+
+	var ids []int64
+	err = s.
+		DeleteFrom("people").
+		Where(dat.Any("id", []int64{people[0].ID, people[1].ID})).
+		Returning("id").
+		QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(ids))
+}
+
+func TestDeleteUsingReal(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var ids []int64
+	err := s.
+		DeleteFrom("comments").
+		Using("posts").
+		Where("comments.post_id = posts.id AND posts.title = $1", "Apple").
+		Returning("comments.id").
+		QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids)
+}
@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// ErrDraining occurs when a transaction is begun on a DB which is being
+// drained, see DB.Drain.
+var ErrDraining = errors.New("DB is draining, no new transactions are accepted")
+
+// txTracker counts the open transactions of a DB.
+type txTracker struct {
+	mu       sync.Mutex
+	open     int
+	draining bool
+	// idle is closed when the last open transaction of a draining DB ends
+	idle chan struct{}
+}
+
+// acquire counts a transaction about to begin, failing with ErrDraining if
+// the DB is draining.
+func (t *txTracker) acquire() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return ErrDraining
+	}
+	t.open++
+	return nil
+}
+
+func (t *txTracker) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.open--
+	if t.draining && t.open == 0 {
+		close(t.idle)
+	}
+}
+
+// drain stops new transactions and returns a channel closed once there are
+// no open transactions.
+func (t *txTracker) drain() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.draining {
+		t.draining = true
+		t.idle = make(chan struct{})
+		if t.open == 0 {
+			close(t.idle)
+		}
+	}
+	return t.idle
+}
+
+func (t *txTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.open
+}
+
+// trackTx releases tx from db once it is committed or rolled back, or ctx
+// is done since database/sql then rolls it back. A commit or rollback
+// running when ctx is done holds the lock of tx, which is awaited so tx is
+// not released before the driver call returned.
+func (db *DB) trackTx(ctx context.Context, tx *Tx) {
+	var once sync.Once
+	finished := make(chan struct{})
+	tx.release = func() {
+		once.Do(func() {
+			close(finished)
+			db.txs.release()
+		})
+	}
+	if done := ctx.Done(); done != nil {
+		go func() {
+			select {
+			case <-done:
+				tx.Lock()
+				tx.release()
+				tx.Unlock()
+			case <-finished:
+			}
+		}()
+	}
+}
+
+// OpenTransactions returns the number of transactions begun from db which
+// are neither committed nor rolled back.
+func (db *DB) OpenTransactions() int {
+	return db.txs.count()
+}
+
+// Drain gracefully shuts down db, eg on SIGTERM. Begin and BeginTx fail with
+// ErrDraining from now on, while the open transactions may still run
+// statements and commit. Once they are done or ctx is done, db is closed.
+// ctx.Err() is returned if the wait was cut short.
+//
+// Statements executed directly on db are not tracked, stop issuing them
+// before calling Drain.
+func (db *DB) Drain(ctx context.Context) error {
+	select {
+	case <-db.txs.drain():
+	case <-ctx.Done():
+		logger().Warn("drain: closing with open transactions", zap.Int("open", db.OpenTransactions()))
+		db.DB.Close()
+		return ctx.Err()
+	}
+	return db.DB.Close()
+}
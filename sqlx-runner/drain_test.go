@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain(t *testing.T) {
+	db := NewDB(realDb(), "postgres")
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	tx2, err := db.BeginTx(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, db.OpenTransactions())
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- db.Drain(context.Background())
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	_, err = db.Begin()
+	assert.Exactly(t, ErrDraining, err)
+
+	// the open transactions can still run statements
+	var n int64
+	err = tx.SQL("SELECT 1").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, 1, db.OpenTransactions())
+
+	select {
+	case <-drained:
+		t.Fatal("Drain returned with an open transaction")
+	default:
+	}
+
+	// database/sql rolls back tx2 when its context is cancelled
+	cancel()
+	select {
+	case err = <-drained:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Drain did not return")
+	}
+	assert.Equal(t, 0, db.OpenTransactions())
+	assert.Error(t, tx2.Commit())
+}
+
+func TestDrainCommitInFlight(t *testing.T) {
+	db := NewDB(realDb(), "postgres")
+	defer db.DB.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := db.BeginTx(ctx, nil)
+	assert.NoError(t, err)
+
+	// a commit holds the lock of tx until the driver returns
+	tx.Lock()
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, db.OpenTransactions())
+	tx.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, db.OpenTransactions())
+}
+
+func TestDrainTimeout(t *testing.T) {
+	db := NewDB(realDb(), "postgres")
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = db.Drain(ctx)
+	assert.Exactly(t, context.DeadlineExceeded, err)
+	assert.Error(t, db.DB.Ping())
+}
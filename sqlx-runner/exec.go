@@ -2,6 +2,7 @@ package runner
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -12,9 +13,7 @@ import (
 	"time"
 
 	"github.com/casualjim/dat"
-	"github.com/casualjim/dat/kvs"
 	"github.com/jmoiron/sqlx"
-	"github.com/lib/pq"
 	guid "github.com/satori/go.uuid"
 	"go.uber.org/zap"
 )
@@ -27,6 +26,11 @@ type database interface {
 	QueryRowx(query string, args ...interface{}) *sqlx.Row
 	Select(dest interface{}, query string, args ...interface{}) error
 	Get(dest interface{}, query string, args ...interface{}) error
+
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 }
 
 func toOutputStr(args []interface{}) string {
@@ -48,74 +52,109 @@ func toOutputStr(args []interface{}) string {
 			buf.WriteString("<binary>")
 		}
 	}
-	return buf.String()
+	return dat.TruncateLogged(buf.String())
+}
+
+// sqlField returns statement as a log field, truncated to
+// dat.MaxLoggedSQLLength.
+func sqlField(statement string) zap.Field {
+	return zap.String("sql", dat.TruncateLogged(statement))
 }
 
-func logSQLError(err error, msg string, statement string, args []interface{}) error {
+func logSQLError(lg *zap.Logger, err error, msg string, statement string, args []interface{}) error {
 	// it might be possible for a query to finish in between ex.timeout expiring locally
 	// and before pg_cancel_backend executes on postgres server.
-	if pe, ok := err.(*pq.Error); ok {
+	if pe, ok := dat.AsPgError(err); ok {
 		if pe.Code == "57014" {
 			// dat initiates the cancellation of a query on timeout.  Coerce the error into
 			// a timedout error so the end user does not see a false error in the logs.
 			if strings.HasPrefix(statement, queryIDPrefix) {
 				return dat.ErrTimedout
 			}
+		} else if dat.IsDeadlock(pe) {
+			// the message of a deadlock omits the processes and locks involved
+			lg = lg.With(zap.String("detail", pe.Detail), zap.String("where", pe.Where))
 		}
 	} else if err == sql.ErrNoRows || err == dat.ErrNotFound {
+		err = dat.ErrNotFound
 		if !LogErrNoRows {
 			return err
 		}
-		lg := logger.With(zap.Error(err), zap.String("sql", statement), zap.String("args", toOutputStr(args)))
+		lg = lg.With(zap.Error(err), sqlField(statement), zap.String("args", toOutputStr(args)))
 		if dat.Strict {
 			lg.Warn(msg)
 			return err
 		}
-		if logger.Core().Enabled(zap.DebugLevel) {
-			logger.Debug(msg)
+		if lg.Core().Enabled(zap.DebugLevel) {
+			lg.Debug(msg)
 		}
 		return err
 	}
 
-	logger.Error(msg, zap.Error(err), zap.String("sql", statement), zap.String("args", toOutputStr(args)))
-	return err
+	lg.Error(msg, zap.Error(err), sqlField(statement), zap.String("args", toOutputStr(args)))
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+	return dat.NewQueryError(err, statement, args)
 }
 
-func logExecutionTime(start time.Time, sql string, args []interface{}) {
+// logExecutionTime logs the execution time of a query. Queries slower than
+// LogQueriesThreshold are logged at Warn along with any extra fields such as
+// the number of rows affected.
+func logExecutionTime(lg *zap.Logger, start time.Time, sql string, args []interface{}, fields ...zap.Field) {
 	logged := false
-	if logger.Core().Enabled(zap.WarnLevel) {
+	if lg.Core().Enabled(zap.WarnLevel) {
 		elapsed := time.Since(start)
 		if LogQueriesThreshold > 0 && elapsed.Nanoseconds() > LogQueriesThreshold.Nanoseconds() {
+			fields = append([]zap.Field{zap.Duration("elapsed", elapsed), sqlField(sql)}, fields...)
 			if len(args) > 0 {
-				logger.Warn("SLOW query", zap.Duration("elapsed", elapsed), zap.String("sql", sql), zap.String("args", toOutputStr(args)))
-			} else {
-				logger.Warn("SLOW query", zap.Duration("elapsed", elapsed), zap.String("sql", sql))
+				fields = append(fields, zap.String("args", toOutputStr(args)))
 			}
+			lg.Warn("SLOW query", fields...)
 			logged = true
 		}
 	}
 
-	if logger.Core().Enabled(zap.InfoLevel) && !logged {
+	if lg.Core().Enabled(zap.InfoLevel) && !logged {
 		elapsed := time.Since(start)
-		logger.Info("Query time", zap.Duration("elapsed", elapsed), zap.String("sql", sql))
+		lg.Info("Query time", zap.Duration("elapsed", elapsed), sqlField(sql))
+	}
+}
+
+// rowsAffectedField returns the rows affected by result as a log field.
+func rowsAffectedField(result sql.Result) zap.Field {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return zap.Skip()
+	}
+	return zap.Int64("rowsAffected", n)
+}
+
+// rowsAffected returns the rows affected by result or -1 if unknown.
+func rowsAffected(result sql.Result) int64 {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return -1
 	}
+	return n
 }
 
-func (ex *Execer) exec() (sql.Result, error) {
-	if ex.timeout == 0 {
-		return ex.execFn()
+func (ex *Execer) exec(ctx context.Context) (sql.Result, error) {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.execFn(ctx)
 	}
 
 	ch := make(chan bool, 1)
 	var result sql.Result
 	var err error
 	go func() {
-		result, err = ex.execFn()
+		result, err = ex.execFn(ctx)
 		ch <- true
 	}()
 	for {
 		select {
-		case <-time.After(ex.timeout):
+		case <-time.After(timeout):
 			return nil, ex.Cancel()
 		case <-ch:
 			return result, err
@@ -125,91 +164,103 @@ func (ex *Execer) exec() (sql.Result, error) {
 
 // execFn executes the query built by builder. Use execFn when data is not
 // to be returned.
-func (ex *Execer) execFn() (sql.Result, error) {
+func (ex *Execer) execFn(ctx context.Context) (sql.Result, error) {
 	fullSQL, args, err := ex.Interpolate()
 	if err != nil {
-		logger.Error("execFn.10", zap.Error(err), zap.String("sql", fullSQL))
+		ex.log().Error("execFn.10", zap.Error(err), sqlField(fullSQL))
 		return nil, err
 	}
-	defer logExecutionTime(time.Now(), fullSQL, args)
-
+	ctx, end := observe(ctx, fullSQL, args)
+	start := time.Now()
 	var result sql.Result
-	result, err = ex.database.Exec(fullSQL, args...)
+	result, err = ex.database.ExecContext(ctx, fullSQL, args...)
 	if err != nil {
-		return nil, logSQLError(err, "execFn.30:"+fmt.Sprintf("%T", err), fullSQL, args)
+		logExecutionTime(ex.log(), start, fullSQL, args)
+		end(err, 0)
+		return nil, logSQLError(ex.log(), err, "execFn.30:"+fmt.Sprintf("%T", err), fullSQL, args)
 	}
 
+	logExecutionTime(ex.log(), start, fullSQL, args, rowsAffectedField(result))
+	end(nil, rowsAffected(result))
 	return result, nil
 }
 
 // execSQL executes SQL. DO NOT add timeout logic here since this is called
 // by Cancel when a timeout occurs.
 func (ex *Execer) execSQL(fullSQL string, args []interface{}) (sql.Result, error) {
-	defer logExecutionTime(time.Now(), fullSQL, args)
-
+	_, end := observe(context.Background(), fullSQL, args)
+	start := time.Now()
 	var result sql.Result
 	var err error
 	result, err = ex.database.Exec(fullSQL, args...)
 	if err != nil {
-		return nil, logSQLError(err, "execSQL.30", fullSQL, args)
+		logExecutionTime(ex.log(), start, fullSQL, args)
+		end(err, 0)
+		return nil, logSQLError(ex.log(), err, "execSQL.30", fullSQL, args)
 	}
 
+	logExecutionTime(ex.log(), start, fullSQL, args, rowsAffectedField(result))
+	end(nil, rowsAffected(result))
 	return result, nil
 }
 
-func (ex *Execer) query() (*sqlx.Rows, error) {
-	if ex.timeout == 0 {
-		return ex.queryFn()
+func (ex *Execer) query(ctx context.Context) (*sqlx.Rows, error) {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.queryFn(ctx)
 	}
 
 	ch := make(chan bool, 1)
 	var rows *sqlx.Rows
 	var err error
 	go func() {
-		rows, err = ex.queryFn()
+		rows, err = ex.queryFn(ctx)
 		ch <- true
 	}()
 	for {
 		select {
-		case <-time.After(ex.timeout):
+		case <-time.After(timeout):
 			return nil, ex.Cancel()
 		case <-ch:
-			//logger.Error("doexec completed")
+			//logger().Error("doexec completed")
 			return rows, err
 		}
 	}
 }
 
 // Query delegates to the internal runner's Query.
-func (ex *Execer) queryFn() (*sqlx.Rows, error) {
+func (ex *Execer) queryFn(ctx context.Context) (*sqlx.Rows, error) {
 	fullSQL, args, err := ex.Interpolate()
 	if err != nil {
 		return nil, err
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	rows, err := ex.database.Queryx(fullSQL, args...)
+	ctx, end := observe(ctx, fullSQL, args)
+	defer logExecutionTime(ex.log(), time.Now(), fullSQL, args)
+	rows, err := ex.database.QueryxContext(ctx, fullSQL, args...)
+	end(err, -1)
 	if err != nil {
-		return nil, logSQLError(err, "queryFn.30", fullSQL, args)
+		return nil, logSQLError(ex.log(), err, "queryFn.30", fullSQL, args)
 	}
 
 	return rows, nil
 }
 
-func (ex *Execer) queryScalar(destinations ...interface{}) error {
-	if ex.timeout == 0 {
-		return ex.queryScalarFn(destinations)
+func (ex *Execer) queryScalar(ctx context.Context, destinations ...interface{}) error {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.queryScalarFn(ctx, destinations)
 	}
 
 	ch := make(chan bool, 1)
 	var err error
 	go func() {
-		err = ex.queryScalarFn(destinations)
+		err = ex.queryScalarFn(ctx, destinations)
 		ch <- true
 	}()
 	for {
 		select {
-		case <-time.After(ex.timeout):
+		case <-time.After(timeout):
 			return ex.Cancel()
 		case <-ch:
 			return err
@@ -221,7 +272,7 @@ func (ex *Execer) queryScalar(destinations ...interface{}) error {
 // one or more destinations.
 //
 // Returns ErrNotFound if no value was found, and it was therefore not set.
-func (ex *Execer) queryScalarFn(destinations []interface{}) error {
+func (ex *Execer) queryScalarFn(ctx context.Context, destinations []interface{}) (err error) {
 	fullSQL, args, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return err
@@ -232,47 +283,57 @@ func (ex *Execer) queryScalarFn(destinations []interface{}) error {
 			return nil
 		}
 		// log it and fallthrough to let the query continue
-		logger.Warn("queryScalarFn.10: Could not unmarshal cache data. Continuing with query")
+		ex.log().Warn("queryScalarFn.10: Could not unmarshal cache data. Continuing with query")
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
+	extras := extraDests(ex.builder)
+	destinations = withExtras(destinations, extras)
+
+	ctx, end := observe(ctx, fullSQL, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(ex.log(), time.Now(), fullSQL, args)
 	// Run the query:
 	var rows *sqlx.Rows
-	rows, err = ex.database.Queryx(fullSQL, args...)
+	rows, err = ex.database.QueryxContext(ctx, fullSQL, args...)
 	if err != nil {
-		return logSQLError(err, "queryScalarFn.12: querying database", fullSQL, args)
+		return logSQLError(ex.log(), err, "queryScalarFn.12: querying database", fullSQL, args)
 	}
 
 	defer rows.Close()
 	if rows.Next() {
 		err = rows.Scan(destinations...)
 		if err != nil {
-			return logSQLError(err, "queryScalarFn.14: scanning to destination", fullSQL, args)
+			return logSQLError(ex.log(), err, "queryScalarFn.14: scanning to destination", fullSQL, args)
 		}
 		ex.setCache(destinations, dtStruct)
 		return nil
 	}
 	if err := rows.Err(); err != nil {
-		return logSQLError(err, "queryScalarFn.20: iterating through rows", fullSQL, args)
+		return logSQLError(ex.log(), err, "queryScalarFn.20: iterating through rows", fullSQL, args)
+	}
+	if resetExtras(extras) {
+		// the conflict was ignored
+		return nil
 	}
 
 	return dat.ErrNotFound
 }
 
-func (ex *Execer) querySlice(dest interface{}) error {
-	if ex.timeout == 0 {
-		return ex.querySliceFn(dest)
+func (ex *Execer) querySlice(ctx context.Context, dest interface{}) error {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.querySliceFn(ctx, dest)
 	}
 
 	ch := make(chan bool, 1)
 	var err error
 	go func() {
-		err = ex.querySliceFn(dest)
+		err = ex.querySliceFn(ctx, dest)
 		ch <- true
 	}()
 	for {
 		select {
-		case <-time.After(ex.timeout):
+		case <-time.After(timeout):
 			return ex.Cancel()
 		case <-ch:
 			return err
@@ -284,7 +345,7 @@ func (ex *Execer) querySlice(dest interface{}) error {
 // slice of primitive values
 //
 // Returns ErrNotFound if no value was found, and it was therefore not set.
-func (ex *Execer) querySliceFn(dest interface{}) error {
+func (ex *Execer) querySliceFn(ctx context.Context, dest interface{}) (err error) {
 	// Validate the dest and reflection values we need
 
 	// This must be a pointer to a slice
@@ -320,25 +381,30 @@ func (ex *Execer) querySliceFn(dest interface{}) error {
 			return nil
 		}
 		// log it and fallthrough to let the query continue
-		logger.Warn("querySlice.2: Could not unmarshal cache data. Continuing with query")
+		ex.log().Warn("querySlice.2: Could not unmarshal cache data. Continuing with query")
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	rows, err := ex.database.Queryx(fullSQL, args...)
+	ctx, end := observe(ctx, fullSQL, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(ex.log(), time.Now(), fullSQL, args)
+	rows, err := ex.database.QueryxContext(ctx, fullSQL, args...)
 	if err != nil {
-		return logSQLError(err, "querySlice.load_all_values.query", fullSQL, args)
+		return logSQLError(ex.log(), err, "querySlice.load_all_values.query", fullSQL, args)
 	}
 
+	ex.presize(dest)
 	sliceValue := valueOfDest
+	extras := extraDests(ex.builder)
+	resetExtras(extras)
 	defer rows.Close()
 	for rows.Next() {
 		// Create a new value to store our row:
 		pointerToNewValue := reflect.New(recordType)
 		newValue := reflect.Indirect(pointerToNewValue)
 
-		err = rows.Scan(pointerToNewValue.Interface())
+		err = rows.Scan(withExtras([]interface{}{pointerToNewValue.Interface()}, extras)...)
 		if err != nil {
-			return logSQLError(err, "querySlice.load_all_values.scan", fullSQL, args)
+			return logSQLError(ex.log(), err, "querySlice.load_all_values.scan", fullSQL, args)
 		}
 
 		// Append our new value to the slice:
@@ -347,7 +413,7 @@ func (ex *Execer) querySliceFn(dest interface{}) error {
 	valueOfDest.Set(sliceValue)
 
 	if err := rows.Err(); err != nil {
-		return logSQLError(err, "querySlice.load_all_values.rows_err", fullSQL, args)
+		return logSQLError(ex.log(), err, "querySlice.load_all_values.rows_err", fullSQL, args)
 	}
 
 	ex.setCache(dest, dtStruct)
@@ -355,20 +421,21 @@ func (ex *Execer) querySliceFn(dest interface{}) error {
 	return nil
 }
 
-func (ex *Execer) queryStruct(dest interface{}) error {
-	if ex.timeout == 0 {
-		return ex.queryStructFn(dest)
+func (ex *Execer) queryStruct(ctx context.Context, dest interface{}) error {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.queryStructFn(ctx, dest)
 	}
 
 	ch := make(chan bool, 1)
 	var err error
 	go func() {
-		err = ex.queryStructFn(dest)
+		err = ex.queryStructFn(ctx, dest)
 		ch <- true
 	}()
 	for {
 		select {
-		case <-time.After(ex.timeout):
+		case <-time.After(timeout):
 			return ex.Cancel()
 		case <-ch:
 			return err
@@ -380,7 +447,7 @@ func (ex *Execer) queryStruct(dest interface{}) error {
 // a struct dest must be a pointer to a struct
 //
 // Returns ErrNotFound if nothing was found
-func (ex *Execer) queryStructFn(dest interface{}) error {
+func (ex *Execer) queryStructFn(ctx context.Context, dest interface{}) (err error) {
 	fullSQL, args, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return err
@@ -391,33 +458,44 @@ func (ex *Execer) queryStructFn(dest interface{}) error {
 			return nil
 		}
 		// log it and fallthrough to let the query continue
-		logger.Warn("queryStruct.2: Could not unmarshal queryStruct cache data. Continuing with query")
+		ex.log().Warn("queryStruct.2: Could not unmarshal queryStruct cache data. Continuing with query")
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	err = ex.database.Get(dest, fullSQL, args...)
+	ctx, end := observe(ctx, fullSQL, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(ex.log(), time.Now(), fullSQL, args)
+	if extras := extraDests(ex.builder); extras != nil || hasJSONFields(reflect.TypeOf(dest)) {
+		err = getStruct(ctx, ex.database, dest, extras, fullSQL, args)
+		if err != nil {
+			return logSQLError(ex.log(), err, "queryStruct.4", fullSQL, args)
+		}
+		ex.setCache(dest, dtStruct)
+		return nil
+	}
+	err = ex.database.GetContext(ctx, dest, fullSQL, args...)
 	if err != nil {
-		return logSQLError(err, "queryStruct.3", fullSQL, args)
+		return logSQLError(ex.log(), err, "queryStruct.3", fullSQL, args)
 	}
 
 	ex.setCache(dest, dtStruct)
 	return nil
 }
 
-func (ex *Execer) queryStructs(dest interface{}) error {
-	if ex.timeout == 0 {
-		return ex.queryStructsFn(dest)
+func (ex *Execer) queryStructs(ctx context.Context, dest interface{}) error {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.queryStructsFn(ctx, dest)
 	}
 
 	ch := make(chan bool, 1)
 	var err error
 	go func() {
-		err = ex.queryStructsFn(dest)
+		err = ex.queryStructsFn(ctx, dest)
 		ch <- true
 	}()
 	for {
 		select {
-		case <-time.After(ex.timeout):
+		case <-time.After(timeout):
 			return ex.Cancel()
 		case <-ch:
 			return err
@@ -430,10 +508,10 @@ func (ex *Execer) queryStructs(dest interface{}) error {
 //
 // Returns the number of items found (which is not necessarily the # of items
 // set)
-func (ex *Execer) queryStructsFn(dest interface{}) error {
+func (ex *Execer) queryStructsFn(ctx context.Context, dest interface{}) (err error) {
 	fullSQL, args, blob, err := ex.cacheOrSQL()
 	if err != nil {
-		logger.Error("queryStructs.1: Could not convert to SQL", zap.Error(err))
+		ex.log().Error("queryStructs.1: Could not convert to SQL", zap.Error(err))
 		return err
 	}
 	if blob != nil {
@@ -442,25 +520,123 @@ func (ex *Execer) queryStructsFn(dest interface{}) error {
 			return nil
 		}
 		// log it and let the query continue
-		logger.Warn("queryStructs.2: Could not unmarshal queryStruct cache data. Continuing with query", zap.Error(err))
+		ex.log().Warn("queryStructs.2: Could not unmarshal queryStruct cache data. Continuing with query", zap.Error(err))
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	err = ex.database.Select(dest, fullSQL, args...)
+	ctx, end := observe(ctx, fullSQL, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(ex.log(), time.Now(), fullSQL, args)
+	ex.presize(dest)
+	if extras := extraDests(ex.builder); extras != nil || hasJSONFields(reflect.TypeOf(dest)) {
+		err = selectStructs(ctx, ex.database, dest, extras, fullSQL, args)
+	} else {
+		err = ex.database.SelectContext(ctx, dest, fullSQL, args...)
+	}
 	if err != nil {
-		logSQLError(err, "queryStructs", fullSQL, args)
+		return logSQLError(ex.log(), err, "queryStructs", fullSQL, args)
 	}
 
 	ex.setCache(dest, dtStruct)
-	return err
+	return nil
+}
+
+func (ex *Execer) queryMaps(ctx context.Context, single bool) ([]map[string]interface{}, error) {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.queryMapsFn(ctx, single)
+	}
+
+	ch := make(chan bool, 1)
+	var maps []map[string]interface{}
+	var err error
+	go func() {
+		maps, err = ex.queryMapsFn(ctx, single)
+		ch <- true
+	}()
+	for {
+		select {
+		case <-time.After(timeout):
+			return nil, ex.Cancel()
+		case <-ch:
+			return maps, err
+		}
+	}
+}
+
+// queryMapsFn executes the query in builder and scans each row into a map of
+// column name to value. Only the first row is scanned if single is true.
+//
+// Returns ErrNotFound if single is true and nothing was found
+func (ex *Execer) queryMapsFn(ctx context.Context, single bool) (_ []map[string]interface{}, err error) {
+	fullSQL, args, err := ex.Interpolate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, end := observe(ctx, fullSQL, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(ex.log(), time.Now(), fullSQL, args)
+	rows, err := ex.database.QueryxContext(ctx, fullSQL, args...)
+	if err != nil {
+		return nil, logSQLError(ex.log(), err, "queryMaps.1", fullSQL, args)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, logSQLError(ex.log(), err, "queryMaps.2", fullSQL, args)
+	}
+
+	var maps []map[string]interface{}
+	for rows.Next() {
+		m := map[string]interface{}{}
+		if err = rows.MapScan(m); err != nil {
+			return nil, logSQLError(ex.log(), err, "queryMaps.3", fullSQL, args)
+		}
+		for _, ct := range types {
+			m[ct.Name()] = decodeMapValue(ct.DatabaseTypeName(), m[ct.Name()])
+		}
+		maps = append(maps, m)
+		if single {
+			break
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, logSQLError(ex.log(), err, "queryMaps.4", fullSQL, args)
+	}
+	if single && len(maps) == 0 {
+		return nil, logSQLError(ex.log(), dat.ErrNotFound, "queryMaps.5", fullSQL, args)
+	}
+	return maps, nil
+}
+
+// decodeMapValue converts the raw bytes the driver returns for some column
+// types. NUMERIC becomes float64, JSON and JSONB become dat.JSON, BYTEA is
+// left as []byte and any other []byte value becomes a string.
+func decodeMapValue(typeName string, v interface{}) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	switch typeName {
+	case "BYTEA":
+		return b
+	case "JSON", "JSONB":
+		return dat.JSON(b)
+	case "NUMERIC":
+		if f, err := strconv.ParseFloat(string(b), 64); err == nil {
+			return f
+		}
+	}
+	return string(b)
 }
 
 // queryJSONStruct executes the query in builder and loads the resulting data into
 // a struct, using json.Unmarshal().
 //
 // Returns ErrNotFound if nothing was found
-func (ex *Execer) queryJSONStruct(dest interface{}) error {
-	blob, err := ex.queryJSONBlob(true)
+func (ex *Execer) queryJSONStruct(ctx context.Context, dest interface{}) error {
+	blob, err := ex.queryJSONBlob(ctx, true)
 	if err != nil {
 		return err
 	}
@@ -470,21 +646,22 @@ func (ex *Execer) queryJSONStruct(dest interface{}) error {
 	return nil
 }
 
-func (ex *Execer) queryJSONBlob(single bool) ([]byte, error) {
-	if ex.timeout == 0 {
-		return ex.queryJSONBlobFn(single)
+func (ex *Execer) queryJSONBlob(ctx context.Context, single bool) ([]byte, error) {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.queryJSONBlobFn(ctx, single)
 	}
 
 	ch := make(chan bool, 1)
 	var err error
 	var b []byte
 	go func() {
-		b, err = ex.queryJSONBlobFn(single)
+		b, err = ex.queryJSONBlobFn(ctx, single)
 		ch <- true
 	}()
 	for {
 		select {
-		case <-time.After(ex.timeout):
+		case <-time.After(timeout):
 			return nil, ex.Cancel()
 		case <-ch:
 			return b, err
@@ -496,7 +673,7 @@ func (ex *Execer) queryJSONBlob(single bool) ([]byte, error) {
 // into a blob. If a single item is to be returned, set single to true.
 //
 // Returns ErrNotFound if nothing was found
-func (ex *Execer) queryJSONBlobFn(single bool) ([]byte, error) {
+func (ex *Execer) queryJSONBlobFn(ctx context.Context, single bool) (_ []byte, err error) {
 	fullSQL, args, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return nil, err
@@ -505,10 +682,12 @@ func (ex *Execer) queryJSONBlobFn(single bool) ([]byte, error) {
 		return blob, nil
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	rows, err := ex.database.Queryx(fullSQL, args...)
+	ctx, end := observe(ctx, fullSQL, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(ex.log(), time.Now(), fullSQL, args)
+	rows, err := ex.database.QueryxContext(ctx, fullSQL, args...)
 	if err != nil {
-		return nil, logSQLError(err, "queryJSONStructs", fullSQL, args)
+		return nil, logSQLError(ex.log(), err, "queryJSONStructs", fullSQL, args)
 	}
 
 	// TODO optimize this later, may be better to
@@ -519,8 +698,8 @@ func (ex *Execer) queryJSONBlobFn(single bool) ([]byte, error) {
 		for rows.Next() {
 			if i == 1 {
 				if dat.Strict {
-					logSQLError(errors.New("Multiple results returned"), "Expected single result", fullSQL, args)
-					logger.Fatal("Expected single result, got many")
+					logSQLError(ex.log(), errors.New("Multiple results returned"), "Expected single result", fullSQL, args)
+					ex.log().Fatal("Expected single result, got many")
 				} else {
 					break
 				}
@@ -555,7 +734,7 @@ func (ex *Execer) queryJSONBlobFn(single bool) ([]byte, error) {
 	}
 
 	if i == 0 {
-		return nil, sql.ErrNoRows
+		return nil, dat.ErrNotFound
 	}
 
 	blob = buf.Bytes()
@@ -567,8 +746,8 @@ func (ex *Execer) queryJSONBlobFn(single bool) ([]byte, error) {
 // a struct, using json.Unmarshal().
 //
 // Returns ErrNotFound if nothing was found
-func (ex *Execer) queryJSONStructs(dest interface{}) error {
-	blob, err := ex.queryJSONBlob(false)
+func (ex *Execer) queryJSONStructs(ctx context.Context, dest interface{}) error {
+	blob, err := ex.queryJSONBlob(ctx, false)
 	if err != nil {
 		return err
 	}
@@ -581,16 +760,22 @@ func (ex *Execer) queryJSONStructs(dest interface{}) error {
 // cacheOrSQL attempts to get a valeu from cache, otherwise it builds
 // the SQL and args to be executed. If value = "" then the SQL is built.
 // Returns sql, args, value, err.
+// cacheable determines if the results of the query are cached. Queries
+// with extra destinations, eg WithTotalCount, are not since only the
+// destination of the query is cached.
+func (ex *Execer) cacheable() bool {
+	return Cache != nil && ex.cacheTTL > 0 && extraDests(ex.builder) == nil
+}
+
 func (ex *Execer) cacheOrSQL() (string, []interface{}, []byte, error) {
 	// if a cacheID exists, return the value ASAP
-	if Cache != nil && ex.cacheTTL > 0 && ex.cacheID != "" && !ex.cacheInvalidate {
-		v, err := Cache.Get(ex.cacheID)
-		//logger.Warn("DBG cacheOrSQL.1 getting by id", "id", execer.cacheID, "v", v, "err", err)
-		if err != nil && err != kvs.ErrNotFound {
-			logger.Error("Unable to read cache key. Continuing with query", zap.String("key", ex.cacheID), zap.Error(err))
-		} else if v != "" {
-			//logger.Warn("DBG cacheOrSQL.11 HIT", "v", v)
-			return "", nil, []byte(v), nil
+	if ex.cacheable() && ex.cacheID != "" {
+		// this must be set for setCache() to work below
+		ex.cacheKey = taggedCacheKey(ex.cacheID, ex.cacheTags)
+		if !ex.cacheInvalidate {
+			if v := getCache(ex.cacheKey); v != "" {
+				return "", nil, []byte(v), nil
+			}
 		}
 	}
 
@@ -599,16 +784,11 @@ func (ex *Execer) cacheOrSQL() (string, []interface{}, []byte, error) {
 		return "", nil, nil, err
 	}
 
-	// if there is no cacheID, use the checksum of SQL as the ID
-	if Cache != nil && ex.cacheTTL > 0 && ex.cacheID == "" {
-		// this must be set for setCache() to work below
-		ex.cacheID = kvs.Hash(fullSQL)
-
+	// if there is no cacheID, use the checksum of SQL and args as the ID
+	if ex.cacheable() && ex.cacheID == "" {
+		ex.cacheKey = taggedCacheKey(ex.sqlCacheKey(fullSQL, args), ex.cacheTags)
 		if !ex.cacheInvalidate {
-			v, err := Cache.Get(ex.cacheID)
-			//logger.Warn("DBG cacheOrSQL.2 getting by hash", "hash", execer.cacheID, "v", v, "err", err)
-			if v != "" && (err == nil || err != kvs.ErrNotFound) {
-				//logger.Warn("DBG cacheOrSQL.22 HIT")
+			if v := getCache(ex.cacheKey); v != "" {
 				return "", nil, []byte(v), nil
 			}
 		}
@@ -623,12 +803,12 @@ const (
 	dtBytes
 )
 
-// Sets the cache value using the execer.ID key. Note that execer.ID
-// is set as a side-effect of calling cacheOrSQL function above if
-// execer.cacheID is not set. data must be a string or a value that
+// Sets the cache value using the execer.cacheKey key. Note that
+// execer.cacheKey is set as a side-effect of calling cacheOrSQL function
+// above. data must be a string or a value that
 // can be json.Marshal'ed to string.
 func (ex *Execer) setCache(data interface{}, dataType int) {
-	if Cache == nil || ex.cacheTTL < 1 {
+	if !ex.cacheable() {
 		return
 	}
 
@@ -637,10 +817,10 @@ func (ex *Execer) setCache(data interface{}, dataType int) {
 	case dtStruct:
 		b, err := json.Marshal(data)
 		if err != nil {
-			logger.Warn("Could not marshal data, clearing", zap.String("key", ex.cacheID), zap.Error(err))
-			err = Cache.Del(ex.cacheID)
+			ex.log().Warn("Could not marshal data, clearing", zap.String("key", ex.cacheKey), zap.Error(err))
+			err = Cache.Del(ex.cacheKey)
 			if err != nil {
-				logger.Error("Could not delete cache key", zap.String("key", ex.cacheID), zap.Error(err))
+				ex.log().Error("Could not delete cache key", zap.String("key", ex.cacheKey), zap.Error(err))
 			}
 			return
 		}
@@ -651,31 +831,31 @@ func (ex *Execer) setCache(data interface{}, dataType int) {
 		s = string(data.([]byte))
 	}
 
-	//logger.Warn("DBG setting cache", "key", execer.cacheID, "data", string(b), "ttl", execer.cacheTTL)
-	err := Cache.Set(ex.cacheID, s, ex.cacheTTL)
+	err := Cache.Set(ex.cacheKey, s, ex.cacheTTL)
 	if err != nil {
-		logger.Warn("Could not set cache. Query will proceed without caching", zap.Error(err))
+		ex.log().Warn("Could not set cache. Query will proceed without caching", zap.Error(err))
 	}
 }
 
-func (ex *Execer) queryJSON() ([]byte, error) {
-	if ex.timeout == 0 {
-		return ex.queryJSONFn()
+func (ex *Execer) queryJSON(ctx context.Context) ([]byte, error) {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.queryJSONFn(ctx)
 	}
 
 	ch := make(chan bool, 1)
 	var err error
 	var b []byte
 	go func() {
-		b, err = ex.queryJSONFn()
+		b, err = ex.queryJSONFn(ctx)
 		ch <- true
 	}()
 	for {
 		select {
-		case <-time.After(ex.timeout):
+		case <-time.After(timeout):
 			return nil, ex.Cancel()
 		case <-ch:
-			//logger.Error("doexec completed")
+			//logger().Error("doexec completed")
 			return b, err
 		}
 	}
@@ -685,7 +865,7 @@ func (ex *Execer) queryJSON() ([]byte, error) {
 // a bytes slice compatible.
 //
 // Returns ErrNotFound if nothing was found
-func (ex *Execer) queryJSONFn() ([]byte, error) {
+func (ex *Execer) queryJSONFn(ctx context.Context) (_ []byte, err error) {
 	fullSQL, args, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return nil, err
@@ -694,24 +874,83 @@ func (ex *Execer) queryJSONFn() ([]byte, error) {
 		return blob, nil
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
 	jsonSQL := fmt.Sprintf("SELECT TO_JSON(ARRAY_AGG(__datq.*)) FROM (%s) AS __datq", fullSQL)
+	ctx, end := observe(ctx, jsonSQL, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(ex.log(), time.Now(), fullSQL, args)
 
-	err = ex.database.Get(&blob, jsonSQL, args...)
+	err = ex.database.GetContext(ctx, &blob, jsonSQL, args...)
 	if err != nil {
-		logSQLError(err, "queryJSON", jsonSQL, args)
+		err = logSQLError(ex.log(), err, "queryJSON", jsonSQL, args)
 	}
 	ex.setCache(blob, dtBytes)
 
 	return blob, err
 }
 
+func (ex *Execer) queryJSONAgg(ctx context.Context) ([]byte, error) {
+	timeout := ex.timeoutFor(ctx)
+	if timeout == 0 {
+		return ex.queryJSONAggFn(ctx)
+	}
+
+	ch := make(chan bool, 1)
+	var err error
+	var b []byte
+	go func() {
+		b, err = ex.queryJSONAggFn(ctx)
+		ch <- true
+	}()
+	for {
+		select {
+		case <-time.After(timeout):
+			return nil, ex.Cancel()
+		case <-ch:
+			return b, err
+		}
+	}
+}
+
+// queryJSONAggFn executes the query in builder aggregating its rows into a
+// JSON array.
+func (ex *Execer) queryJSONAggFn(ctx context.Context) (_ []byte, err error) {
+	fullSQL, args, blob, err := ex.cacheOrSQL()
+	if err != nil {
+		return nil, err
+	}
+	if blob != nil {
+		return blob, nil
+	}
+
+	jsonSQL := wrapSQL(fullSQL, "SELECT coalesce(json_agg(__datq), '[]') FROM (", ") AS __datq")
+	ctx, end := observe(ctx, jsonSQL, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(ex.log(), time.Now(), jsonSQL, args)
+
+	err = ex.database.GetContext(ctx, &blob, jsonSQL, args...)
+	if err != nil {
+		return nil, logSQLError(ex.log(), err, "queryJSONAgg", jsonSQL, args)
+	}
+	ex.setCache(blob, dtBytes)
+	return blob, nil
+}
+
+// wrapSQL encloses fullSQL within before and after, keeping the query ID
+// comment first so Cancel finds the statement.
+func wrapSQL(fullSQL, before, after string) string {
+	if strings.HasPrefix(fullSQL, queryIDPrefix) {
+		i := strings.IndexByte(fullSQL, '\n') + 1
+		return fullSQL[:i] + before + fullSQL[i:] + after
+	}
+	return before + fullSQL + after
+}
+
 // queryObject executes the query in builder and loads the resulting data into
 // an object agreeable with json.Unmarshal.
 //
 // Returns ErrNotFound if nothing was found
-func (ex *Execer) queryObject(dest interface{}) error {
-	blob, err := ex.queryJSON()
+func (ex *Execer) queryObject(ctx context.Context, dest interface{}) error {
+	blob, err := ex.queryJSON(ctx)
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,11 @@
 package runner
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/casualjim/dat"
@@ -18,15 +21,33 @@ type Execer struct {
 	cacheID         string
 	cacheTTL        time.Duration
 	cacheInvalidate bool
+	cacheTags       []string
+	cacheKey        string
 
 	// timeout is the time to wait for a query before cancelling it, 0 means forever
 	timeout time.Duration
+	// defaultTimeout is set if timeout is the default of the DB, which a
+	// ctx with a deadline overrides
+	defaultTimeout bool
 
 	// uuid is prepended into the SQL for the query to be searched
 	// in pg_stat_activity, used by timeout logic
 	queryID string
+
+	retry      RetryPolicy
+	idempotent bool
+
+	// txID is the correlation ID of the transaction the query runs in
+	txID string
+
+	// expectRows is the capacity to allocate for QueryStructs and QuerySlice
+	expectRows int
 }
 
+// maxLimitRows caps the capacity allocated from a LIMIT, which is often an
+// upper bound rather than the expected number of rows.
+const maxLimitRows = 1000
+
 const queryIDPrefix = "--dat:qid="
 
 // NewExecer creates a new instance of Execer.
@@ -37,7 +58,8 @@ func NewExecer(database database, builder dat.Builder) *Execer {
 	}
 }
 
-// Cache caches the results of queries for Select and SelectDoc.
+// Cache caches the results of queries for Select and SelectDoc. Queries
+// with extra columns, eg WithTotalCount, are not cached.
 func (ex *Execer) Cache(id string, ttl time.Duration, invalidate bool) dat.Execer {
 	ex.cacheID = id
 	ex.cacheTTL = ttl
@@ -48,6 +70,7 @@ func (ex *Execer) Cache(id string, ttl time.Duration, invalidate bool) dat.Exece
 // Timeout sets the timeout for current query.
 func (ex *Execer) Timeout(timeout time.Duration) dat.Execer {
 	ex.timeout = timeout
+	ex.defaultTimeout = false
 	if timeout > 0 {
 		ex.queryID = uuid()
 	} else {
@@ -56,6 +79,73 @@ func (ex *Execer) Timeout(timeout time.Duration) dat.Execer {
 	return ex
 }
 
+// timeoutFor returns the timeout of a query run with ctx. The default
+// timeout of the DB does not apply if ctx already has a deadline.
+func (ex *Execer) timeoutFor(ctx context.Context) time.Duration {
+	if ex.defaultTimeout {
+		if _, ok := ctx.Deadline(); ok {
+			return 0
+		}
+	}
+	return ex.timeout
+}
+
+// log returns the logger for the query, tagged with the transaction ID when
+// run within a transaction.
+func (ex *Execer) log() *zap.Logger {
+	if ex.txID == "" {
+		return logger()
+	}
+	return logger().With(zap.String("tx", ex.txID))
+}
+
+// ExpectRows sets the capacity allocated for the destination slice of
+// QueryStructs and QuerySlice, avoiding reallocations while scanning large
+// results. By default the capacity is the LIMIT of a select, up to 1000.
+func (ex *Execer) ExpectRows(n int) dat.Execer {
+	ex.expectRows = n
+	return ex
+}
+
+// rowsHint returns the expected number of rows, 0 if unknown.
+func (ex *Execer) rowsHint() int {
+	if ex.expectRows > 0 {
+		return ex.expectRows
+	}
+	var limit uint64
+	var ok bool
+	switch b := ex.builder.(type) {
+	case *dat.SelectBuilder:
+		limit, ok = b.LimitCount()
+	case *dat.SelectDocBuilder:
+		limit, ok = b.LimitCount()
+	}
+	if !ok {
+		return 0
+	}
+	if limit > maxLimitRows {
+		return maxLimitRows
+	}
+	return int(limit)
+}
+
+// presize allocates the capacity of the empty slice pointed to by dest for
+// the expected number of rows.
+func (ex *Execer) presize(dest interface{}) {
+	n := ex.rowsHint()
+	if n == 0 {
+		return
+	}
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return
+	}
+	slice := v.Elem()
+	if slice.Len() == 0 && slice.Cap() < n {
+		slice.Set(reflect.MakeSlice(slice.Type(), 0, n))
+	}
+}
+
 func datQueryID(id string) string {
 	return fmt.Sprintf("--dat:qid=%s", id)
 }
@@ -82,7 +172,7 @@ func (ex *Execer) Cancel() error {
 
 	_, err := ex.execSQL(q, nil)
 	if err != nil {
-		logger.Error("While trying to cancel a query", zap.Error(err))
+		ex.log().Error("While trying to cancel a query", zap.Error(err))
 	}
 	return dat.ErrTimedout
 }
@@ -98,59 +188,206 @@ func (ex *Execer) Interpolate() (string, []interface{}, error) {
 
 // Exec executes a builder's query.
 func (ex *Execer) Exec() (*dat.Result, error) {
-	res, err := ex.exec()
+	return ex.ExecContext(context.Background())
+}
+
+// ExecContext executes a builder's query. Cancelling ctx aborts the
+// in-flight statement.
+func (ex *Execer) ExecContext(ctx context.Context) (*dat.Result, error) {
+	var res sql.Result
+	err := ex.withRetry(ctx, func() (err error) {
+		res, err = ex.exec(ctx)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, ctxErr(ctx, err)
 	}
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		return nil, err
+		ex.log().Debug("RowsAffected is not reported", zap.Error(err))
+		return nil, dat.ErrRowsAffectedUnsupported
+	}
+	if rowsAffected == 0 {
+		if b, ok := ex.builder.(*dat.UpdateBuilder); ok && b.IsOptimisticLocked() {
+			return nil, dat.ErrStaleObject
+		}
 	}
 	return &dat.Result{RowsAffected: rowsAffected}, nil
 }
 
+// ExecRows executes a builder's query and returns the number of rows
+// affected.
+func (ex *Execer) ExecRows() (int64, error) {
+	return ex.ExecRowsContext(context.Background())
+}
+
+// ExecRowsContext is like ExecRows but honours ctx cancellation.
+func (ex *Execer) ExecRowsContext(ctx context.Context) (int64, error) {
+	res, err := ex.ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected, nil
+}
+
 // Queryx executes builder's query and returns rows.
 func (ex *Execer) Queryx() (*sqlx.Rows, error) {
-	return ex.query()
+	return ex.QueryxContext(context.Background())
+}
+
+// QueryxContext executes builder's query and returns rows. The rows are
+// closed when ctx is cancelled.
+func (ex *Execer) QueryxContext(ctx context.Context) (*sqlx.Rows, error) {
+	rows, err := ex.query(ctx)
+	return rows, ctxErr(ctx, err)
 }
 
 // QueryScalar executes builder's query and scans returned row into destinations.
 func (ex *Execer) QueryScalar(destinations ...interface{}) error {
-	return ex.queryScalar(destinations...)
+	return ex.QueryScalarContext(context.Background(), destinations...)
+}
+
+// QueryScalarContext is like QueryScalar but honours ctx cancellation.
+func (ex *Execer) QueryScalarContext(ctx context.Context, destinations ...interface{}) error {
+	return ctxErr(ctx, ex.withRetry(ctx, func() error {
+		return ex.queryScalar(ctx, destinations...)
+	}))
 }
 
 // QuerySlice executes builder's query and builds a slice of values from each row, where
 // each row only has one column.
 func (ex *Execer) QuerySlice(dest interface{}) error {
-	return ex.querySlice(dest)
+	return ex.QuerySliceContext(context.Background(), dest)
+}
+
+// QuerySliceContext is like QuerySlice but honours ctx cancellation.
+func (ex *Execer) QuerySliceContext(ctx context.Context, dest interface{}) error {
+	return ctxErr(ctx, ex.withRetry(ctx, func() error {
+		return ex.querySlice(ctx, dest)
+	}))
 }
 
 // QueryStruct executes builders' query and scans the result row into dest.
 func (ex *Execer) QueryStruct(dest interface{}) error {
+	return ex.QueryStructContext(context.Background(), dest)
+}
+
+// QueryStructContext is like QueryStruct but honours ctx cancellation.
+func (ex *Execer) QueryStructContext(ctx context.Context, dest interface{}) error {
 	if _, ok := ex.builder.(*dat.SelectDocBuilder); ok {
-		err := ex.queryJSONStruct(dest)
+		return ctxErr(ctx, ex.withRetry(ctx, func() error {
+			return ex.queryJSONStruct(ctx, dest)
+		}))
+	}
+	return ctxErr(ctx, ex.withRetry(ctx, func() error {
+		return ex.queryStruct(ctx, dest)
+	}))
+}
+
+// QueryStructOrNil is like QueryStruct but dest is a pointer to a pointer
+// to a struct, which is allocated for the row or set to nil if nothing was
+// found rather than returning dat.ErrNotFound.
+//
+//	var person *Person
+//	err := DB.Select("*").From("people").Where("id = $1", id).QueryStructOrNil(&person)
+func (ex *Execer) QueryStructOrNil(dest interface{}) error {
+	return ex.QueryStructOrNilContext(context.Background(), dest)
+}
+
+// QueryStructOrNilContext is like QueryStructOrNil but honours ctx
+// cancellation.
+func (ex *Execer) QueryStructOrNilContext(ctx context.Context, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Ptr || v.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("QueryStructOrNil requires a pointer to a pointer to a struct, got %T", dest)
+	}
+	item := reflect.New(v.Elem().Type().Elem())
+	err := ex.QueryStructContext(ctx, item.Interface())
+	if err == dat.ErrNotFound {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
+		return nil
+	}
+	if err != nil {
 		return err
 	}
-	return ex.queryStruct(dest)
+	v.Elem().Set(item)
+	return nil
 }
 
 // QueryStructs executes builders' query and scans each row as an item in a slice of structs.
 func (ex *Execer) QueryStructs(dest interface{}) error {
+	return ex.QueryStructsContext(context.Background(), dest)
+}
+
+// QueryStructsContext is like QueryStructs but honours ctx cancellation.
+func (ex *Execer) QueryStructsContext(ctx context.Context, dest interface{}) error {
 	if _, ok := ex.builder.(*dat.SelectDocBuilder); ok {
-		err := ex.queryJSONStructs(dest)
+		return ctxErr(ctx, ex.withRetry(ctx, func() error {
+			return ex.queryJSONStructs(ctx, dest)
+		}))
+	}
+	return ctxErr(ctx, ex.withRetry(ctx, func() error {
+		return ex.queryStructs(ctx, dest)
+	}))
+}
+
+// QueryMap executes builder's query and scans the first row into dest as
+// column name to value. Returns dat.ErrNotFound if nothing was found.
+func (ex *Execer) QueryMap(dest *map[string]interface{}) error {
+	return ex.QueryMapContext(context.Background(), dest)
+}
+
+// QueryMapContext is like QueryMap but honours ctx cancellation.
+func (ex *Execer) QueryMapContext(ctx context.Context, dest *map[string]interface{}) error {
+	var maps []map[string]interface{}
+	err := ex.withRetry(ctx, func() (err error) {
+		maps, err = ex.queryMaps(ctx, true)
 		return err
+	})
+	if err != nil {
+		return ctxErr(ctx, err)
 	}
+	*dest = maps[0]
+	return nil
+}
+
+// QueryMaps executes builder's query and scans each row into a map of column
+// name to value. NUMERIC values are decoded as float64, JSON and JSONB as
+// dat.JSON and other textual types as string.
+func (ex *Execer) QueryMaps(dest *[]map[string]interface{}) error {
+	return ex.QueryMapsContext(context.Background(), dest)
+}
 
-	return ex.queryStructs(dest)
+// QueryMapsContext is like QueryMaps but honours ctx cancellation.
+func (ex *Execer) QueryMapsContext(ctx context.Context, dest *[]map[string]interface{}) error {
+	var maps []map[string]interface{}
+	err := ex.withRetry(ctx, func() (err error) {
+		maps, err = ex.queryMaps(ctx, false)
+		return err
+	})
+	if err != nil {
+		return ctxErr(ctx, err)
+	}
+	*dest = maps
+	return nil
 }
 
 // QueryObject wraps the builder's query within a `to_json` then executes and unmarshals
 // the result into dest.
 func (ex *Execer) QueryObject(dest interface{}) error {
+	return ex.QueryObjectContext(context.Background(), dest)
+}
+
+// QueryObjectContext is like QueryObject but honours ctx cancellation.
+func (ex *Execer) QueryObjectContext(ctx context.Context, dest interface{}) error {
 	if _, ok := ex.builder.(*dat.SelectDocBuilder); ok {
-		b, err := ex.queryJSONBlob(false)
-		if err != nil {
+		var b []byte
+		err := ex.withRetry(ctx, func() (err error) {
+			b, err = ex.queryJSONBlob(ctx, false)
 			return err
+		})
+		if err != nil {
+			return ctxErr(ctx, err)
 		}
 		if b == nil {
 			return nil
@@ -158,15 +395,70 @@ func (ex *Execer) QueryObject(dest interface{}) error {
 		return json.Unmarshal(b, dest)
 	}
 
-	return ex.queryObject(dest)
+	return ctxErr(ctx, ex.withRetry(ctx, func() error {
+		return ex.queryObject(ctx, dest)
+	}))
 }
 
 // QueryJSON wraps the builder's query within a `to_json` then executes and returns
 // the JSON []byte representation.
 func (ex *Execer) QueryJSON() ([]byte, error) {
-	if _, ok := ex.builder.(*dat.SelectDocBuilder); ok {
-		return ex.queryJSONBlob(false)
+	return ex.QueryJSONContext(context.Background())
+}
+
+// QueryJSONContext is like QueryJSON but honours ctx cancellation.
+func (ex *Execer) QueryJSONContext(ctx context.Context) ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+	err = ex.withRetry(ctx, func() (err error) {
+		if _, ok := ex.builder.(*dat.SelectDocBuilder); ok {
+			b, err = ex.queryJSONBlob(ctx, false)
+		} else {
+			b, err = ex.queryJSON(ctx)
+		}
+		return err
+	})
+	return b, ctxErr(ctx, err)
+}
+
+// QueryJSONAgg aggregates the rows of builder's query into a JSON array in
+// Postgres with json_agg and stores it in dest, [] if there are no rows. It
+// is faster than scanning rows which are only marshalled again, eg for API
+// responses.
+func (ex *Execer) QueryJSONAgg(dest *json.RawMessage) error {
+	return ex.QueryJSONAggContext(context.Background(), dest)
+}
+
+// QueryJSONAggContext is like QueryJSONAgg but honours ctx cancellation.
+func (ex *Execer) QueryJSONAggContext(ctx context.Context, dest *json.RawMessage) error {
+	var b []byte
+	err := ex.withRetry(ctx, func() (err error) {
+		if _, ok := ex.builder.(*dat.SelectDocBuilder); ok {
+			// the rows of a document are JSON already
+			b, err = ex.queryJSONBlob(ctx, false)
+			if err == dat.ErrNotFound {
+				b, err = []byte("[]"), nil
+			}
+		} else {
+			b, err = ex.queryJSONAgg(ctx)
+		}
+		return err
+	})
+	if err != nil {
+		return ctxErr(ctx, err)
 	}
+	*dest = json.RawMessage(b)
+	return nil
+}
 
-	return ex.queryJSON()
+// ctxErr returns ctx.Err() in place of err when the failure was caused by
+// ctx being cancelled or timing out, so callers can test for
+// context.Canceled and context.DeadlineExceeded.
+func ctxErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Explain returns the plan of builder's query as text. With analyze the
+// query is executed to report actual times and row counts, beware of its
+// side effects: an explained INSERT, UPDATE or DELETE modifies data unless
+// run within a transaction which is rolled back.
+func (ex *Execer) Explain(analyze bool) (string, error) {
+	return ex.ExplainContext(context.Background(), analyze)
+}
+
+// ExplainContext is like Explain but honours ctx cancellation.
+func (ex *Execer) ExplainContext(ctx context.Context, analyze bool) (string, error) {
+	option := "EXPLAIN "
+	if analyze {
+		option = "EXPLAIN ANALYZE "
+	}
+	var plan []string
+	err := ex.explain(ctx, option, func(ctx context.Context, sql string, args []interface{}) error {
+		return ex.database.SelectContext(ctx, &plan, sql, args...)
+	})
+	if err != nil {
+		return "", ctxErr(ctx, err)
+	}
+	return strings.Join(plan, "\n"), nil
+}
+
+// ExplainJSON returns the plan of builder's query in JSON format. See
+// Explain for the side effects of analyze.
+func (ex *Execer) ExplainJSON(analyze bool) ([]byte, error) {
+	return ex.ExplainJSONContext(context.Background(), analyze)
+}
+
+// ExplainJSONContext is like ExplainJSON but honours ctx cancellation.
+func (ex *Execer) ExplainJSONContext(ctx context.Context, analyze bool) ([]byte, error) {
+	option := "EXPLAIN (FORMAT JSON) "
+	if analyze {
+		option = "EXPLAIN (ANALYZE, FORMAT JSON) "
+	}
+	var plan []byte
+	err := ex.explain(ctx, option, func(ctx context.Context, sql string, args []interface{}) error {
+		return ex.database.GetContext(ctx, &plan, sql, args...)
+	})
+	if err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+	return plan, nil
+}
+
+// explain prefixes builder's query with option and runs it with query.
+func (ex *Execer) explain(ctx context.Context, option string, query func(ctx context.Context, sql string, args []interface{}) error) (err error) {
+	fullSQL, args, err := ex.Interpolate()
+	if err != nil {
+		return err
+	}
+	fullSQL = wrapSQL(fullSQL, option, "")
+
+	ctx, end := observe(ctx, fullSQL, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(ex.log(), time.Now(), fullSQL, args)
+
+	err = query(ctx, fullSQL, args)
+	if err != nil {
+		err = logSQLError(ex.log(), err, "explain", fullSQL, args)
+	}
+	return err
+}
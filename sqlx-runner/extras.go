@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/casualjim/dat"
+)
+
+// extraDests returns the destinations of the columns builder adds to its
+// statement, eg by ReturningInserted or WithTotalCount, nil if there are
+// none. They are scanned apart from the destination of the query.
+func extraDests(builder dat.Builder) map[string]interface{} {
+	var extras map[string]interface{}
+	if ir, ok := builder.(dat.InsertedReturner); ok && ir.InsertedDest() != nil {
+		extras = map[string]interface{}{dat.InsertedColumn: ir.InsertedDest()}
+	}
+	if tc, ok := builder.(dat.TotalCounter); ok && tc.TotalCountDest() != nil {
+		if extras == nil {
+			extras = map[string]interface{}{}
+		}
+		extras[dat.TotalCountColumn] = tc.TotalCountDest()
+	}
+	return extras
+}
+
+// withExtras appends the extra destinations to the destinations of a scan,
+// the inserted column before the total count as they are written.
+func withExtras(destinations []interface{}, extras map[string]interface{}) []interface{} {
+	if len(extras) == 0 {
+		return destinations
+	}
+	destinations = destinations[:len(destinations):len(destinations)]
+	for _, column := range []string{dat.InsertedColumn, dat.TotalCountColumn} {
+		if extra, ok := extras[column]; ok {
+			destinations = append(destinations, extra)
+		}
+	}
+	return destinations
+}
+
+// resetExtras sets the extra destinations for a statement returning no
+// rows, returning true if no rows is not an error since the insert
+// conflict was ignored.
+func resetExtras(extras map[string]interface{}) bool {
+	if total, ok := extras[dat.TotalCountColumn].(*int64); ok {
+		*total = 0
+	}
+	if inserted, ok := extras[dat.InsertedColumn].(*bool); ok {
+		*inserted = false
+		return true
+	}
+	return false
+}
+
+// getStruct scans the row returned by query into the struct pointed to by
+// dest like GetContext, unmarshalling json fields and scanning extra
+// columns into extras. No row is dat.ErrNotFound unless the row was not
+// inserted, see resetExtras.
+func getStruct(ctx context.Context, db database, dest interface{}, extras map[string]interface{}, query string, args []interface{}) error {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if resetExtras(extras) {
+			return nil
+		}
+		return dat.ErrNotFound
+	}
+	if err := scanStruct(rows, dest, extras); err != nil {
+		return err
+	}
+	return rows.Close()
+}
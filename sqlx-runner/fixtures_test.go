@@ -110,7 +110,7 @@ func installFixtures() {
 	for _, v := range sqlToRun {
 		_, err := testDB.Exec(v)
 		if err != nil {
-			logger.Fatal("Failed to execute statement", zap.String("sql", v), zap.Error(err))
+			logger().Fatal("Failed to execute statement", zap.String("sql", v), zap.Error(err))
 		}
 	}
 }
@@ -1,17 +1,24 @@
 package runner
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
 	"github.com/casualjim/dat"
+	"github.com/casualjim/dat/common"
 	"github.com/casualjim/dat/kvs"
 	"github.com/casualjim/dat/postgres"
 	"github.com/cenkalti/backoff"
 	"go.uber.org/zap"
 )
 
-var logger *zap.Logger
+var namedLogger = common.NewNamedLogger("dat:sqlx")
+
+// logger returns the package logger.
+func logger() *zap.Logger {
+	return namedLogger.Get()
+}
 
 // LogQueriesThreshold is the threshold for logging "slow" queries
 var LogQueriesThreshold time.Duration
@@ -19,9 +26,17 @@ var LogQueriesThreshold time.Duration
 // LogErrNoRows tells runner to log `sql.ErrNoRows`
 var LogErrNoRows bool
 
+// StrictLeakTimeout is how long a transaction may remain open before
+// panicking when dat.Strict is enabled. 0 disables the check.
+var StrictLeakTimeout = time.Minute
+
+// LeakWarnTimeout is how long a transaction may remain open before a
+// warning with the transaction ID and the stack of the Begin call site is
+// logged. Unlike StrictLeakTimeout it never panics. 0 disables the check.
+var LeakWarnTimeout time.Duration
+
 func init() {
 	dat.Dialect = postgres.New()
-	logger = zap.L().Named("dat:sqlx")
 }
 
 // Cache caches query results.
@@ -33,24 +48,43 @@ func SetCache(store kvs.KeyValueStore) {
 	Cache = store
 }
 
+// PingOptions configures the exponential backoff used by PingWithOptions.
+// Zero values use the backoff package defaults.
+type PingOptions struct {
+	// MaxInterval caps the delay between pings.
+	MaxInterval time.Duration
+
+	// MaxElapsedTime is how long to keep pinging before giving up.
+	MaxElapsedTime time.Duration
+}
+
+// Ping pings a database with an exponential backoff until it responds, ctx
+// is done or 15 minutes have elapsed. The last ping error is returned.
+func Ping(ctx context.Context, db *sql.DB) error {
+	return PingWithOptions(ctx, db, PingOptions{})
+}
+
+// PingWithOptions is like Ping with the backoff configured by opts.
+func PingWithOptions(ctx context.Context, db *sql.DB, opts PingOptions) error {
+	b := backoff.NewExponentialBackOff()
+	if opts.MaxInterval > 0 {
+		b.MaxInterval = opts.MaxInterval
+	}
+	if opts.MaxElapsedTime > 0 {
+		b.MaxElapsedTime = opts.MaxElapsedTime
+	}
+
+	return backoff.RetryNotify(func() error {
+		return db.PingContext(ctx)
+	}, backoff.WithContext(b, ctx), func(err error, next time.Duration) {
+		logger().Info("pinging database...", zap.Error(err))
+	})
+}
+
 // MustPing pings a database with an exponential backoff. The
 // function panics if the database cannot be pinged after 15 minutes
 func MustPing(db *sql.DB) {
-	var err error
-	b := backoff.NewExponentialBackOff()
-	ticker := backoff.NewTicker(b)
-
-	// Ticks will continue to arrive when the previous operation is still running,
-	// so operations that take a while to fail could run in quick succession.
-	for range ticker.C {
-		if err = db.Ping(); err != nil {
-			logger.Info("pinging database...", zap.Error(err))
-			continue
-		}
-
-		ticker.Stop()
-		return
+	if err := Ping(context.Background(), db); err != nil {
+		panic("Could not ping database!")
 	}
-
-	panic("Could not ping database!")
 }
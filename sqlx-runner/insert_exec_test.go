@@ -2,8 +2,10 @@ package runner
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/casualjim/dat"
 	"github.com/casualjim/dat/common"
@@ -241,3 +243,296 @@ func TestInsertBytes(t *testing.T) {
 	assert.Exactly(t, b, image)
 	dat.EnableInterpolation = false
 }
+
+func TestInsertOnConflict(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	res, err := s.
+		InsertInto("people").
+		Columns("id", "name").
+		Values(1, "Luigi").
+		OnConflict("id").
+		DoNothing().
+		Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, res.RowsAffected)
+
+	var person Person
+	err = s.
+		InsertInto("people").
+		Columns("id", "name").
+		Values(1, "Luigi").
+		OnConflict("id").
+		DoUpdate().
+		Set("name", dat.Expr("excluded.name")).
+		Where("people.name = $1", "Mario").
+		Returning("id", "name").
+		QueryStruct(&person)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, person.ID)
+	assert.Equal(t, "Luigi", person.Name)
+
+	res, err = s.
+		InsertInto("people").
+		Columns("id", "name").
+		Values(1, "Bowser").
+		OnConflictConstraint("people_pkey").
+		Set("name", dat.Expr("excluded.name")).
+		Where("people.name = $1", "Mario").
+		Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, res.RowsAffected)
+}
+
+func TestInsertReturningInserted(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	upsert := func(id int64, name string, person *Person) (bool, error) {
+		inserted := true
+		err := s.
+			InsertInto("people").
+			Columns("id", "name").
+			Values(id, name).
+			OnConflict("id").
+			Set("name", dat.Expr("excluded.name")).
+			Returning("id", "name").
+			ReturningInserted(&inserted).
+			QueryStruct(person)
+		return inserted, err
+	}
+
+	var person Person
+	inserted, err := upsert(1, "Luigi", &person)
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	assert.Equal(t, "Luigi", person.Name)
+
+	inserted, err = upsert(100, "Peach", &person)
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+	assert.EqualValues(t, 100, person.ID)
+
+	// DO NOTHING returns no row for an existing row
+	inserted = true
+	var id int64
+	err = s.
+		InsertInto("people").
+		Columns("id", "name").
+		Values(2, "Wario").
+		OnConflict("id").
+		Returning("id").
+		ReturningInserted(&inserted).
+		QueryScalar(&id)
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	assert.EqualValues(t, 0, id)
+}
+
+func TestInsertRecords(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	people := make([]*Person, 1000)
+	for i := range people {
+		people[i] = &Person{Name: "batch"}
+	}
+	res, err := s.InsertInto("people").Columns("name").Records(people).Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1000, res.RowsAffected)
+
+	people = make([]*Person, dat.MaxParameters+1)
+	for i := range people {
+		people[i] = &Person{Name: "batch"}
+	}
+	_, err = s.InsertInto("people").Columns("name").Records(people).Exec()
+	assert.Equal(t, dat.ErrTooManyParameters, err)
+}
+
+func TestInsertInterpolatedTimeLocation(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.Exec("SET LOCAL TIME ZONE 'America/New_York'")
+	assert.NoError(t, err)
+
+	created := time.Date(2004, time.January, 1, 1, 1, 1, 0, time.FixedZone("IST", 5*3600+30*60))
+	dat.EnableInterpolation = true
+	defer func() { dat.EnableInterpolation = false }()
+
+	var id int64
+	err = s.
+		InsertInto("people").
+		Columns("name", "created_at").
+		Values("Barack", created).
+		Returning("id").
+		QueryScalar(&id)
+	assert.NoError(t, err)
+
+	var actual time.Time
+	err = s.SQL("SELECT created_at FROM people WHERE id = $1 AND created_at = $2", id, created).QueryScalar(&actual)
+	assert.NoError(t, err)
+	assert.True(t, created.Equal(actual))
+}
+
+func TestInsertBytesRoundTrip(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	image := []byte{0x00, 0x01, 0xff, 0x00, '\'', '\\', 0x7f, 0x00}
+
+	for _, interpolate := range []bool{false, true} {
+		dat.EnableInterpolation = interpolate
+
+		var id int64
+		err := s.InsertInto("people").
+			Columns("name", "image").
+			Values("Binary", image).
+			Returning("id").
+			QueryScalar(&id)
+		assert.NoError(t, err)
+
+		var person Person
+		err = s.Select("id", "image").From("people").Where("id = $1 AND image = $2", id, image).QueryStruct(&person)
+		assert.NoError(t, err)
+		assert.Equal(t, image, person.Image)
+	}
+	dat.EnableInterpolation = false
+}
+
+// mood is a Postgres enum
+type mood string
+
+func (m mood) Value() (driver.Value, error) {
+	return string(m), nil
+}
+
+func (m mood) SQLType() string {
+	return "mood"
+}
+
+func TestInsertEnumValuer(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.SQL("CREATE TYPE mood AS ENUM ('sad', 'ok', 'happy')").Exec()
+	assert.NoError(t, err)
+	_, err = s.SQL("ALTER TABLE people ADD COLUMN mood mood").Exec()
+	assert.NoError(t, err)
+
+	for _, interpolate := range []bool{false, true} {
+		dat.EnableInterpolation = interpolate
+
+		_, err = s.Update("people").Set("mood", mood("happy")).Where("id = $1", 1).Exec()
+		assert.NoError(t, err)
+
+		var name string
+		err = s.Select("name").From("people").Where("mood = $1", mood("happy")).QueryScalar(&name)
+		assert.NoError(t, err)
+		assert.Equal(t, "Mario", name)
+	}
+	dat.EnableInterpolation = false
+}
+
+func TestInsertQueryError(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.SQL("SAVEPOINT dup").Exec()
+	assert.NoError(t, err)
+	_, err = s.InsertInto("people").Columns("id", "name").Values(1, "Dup").Exec()
+	qe, ok := err.(*dat.QueryError)
+	if assert.True(t, ok, "%T", err) {
+		assert.Equal(t, "23505", qe.Code)
+		assert.Equal(t, `INSERT INTO people ("id","name") VALUES ($1,$2)`, qe.SQL)
+		assert.Nil(t, qe.Args)
+		assert.Contains(t, qe.Error(), "duplicate key")
+	}
+	_, err = s.SQL("ROLLBACK TO SAVEPOINT dup").Exec()
+	assert.NoError(t, err)
+
+	dat.IncludeArgsInErrors = true
+	defer func() { dat.IncludeArgsInErrors = false }()
+	_, err = s.InsertInto("people").Columns("id", "name").Values(2, "Dup").Exec()
+	if qe, ok := err.(*dat.QueryError); assert.True(t, ok) {
+		assert.Equal(t, []interface{}{2, "Dup"}, qe.Args)
+	}
+}
+
+func TestInsertPgError(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.InsertInto("posts").Columns("user_id", "title").Values(999, "Orphan").Exec()
+	assert.True(t, dat.IsForeignKeyViolation(err))
+	pe, ok := dat.AsPgError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "posts", pe.Table)
+		assert.Equal(t, "posts_user_id_fkey", pe.Constraint)
+		assert.Contains(t, pe.Detail, "(user_id)=(999)")
+	}
+}
+
+func TestInsertSecret(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	for _, interpolate := range []bool{false, true} {
+		dat.EnableInterpolation = interpolate
+
+		var key string
+		err := s.
+			InsertInto("people").
+			Columns("name", "key").
+			Values("Secretive", dat.Secret("s3cr3t")).
+			Returning("key").
+			QueryScalar(&key)
+		assert.NoError(t, err)
+		assert.Equal(t, "s3cr3t", key)
+	}
+	dat.EnableInterpolation = false
+}
+
+func TestInsertOnConflictDoUpdateAllExcept(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	p := &Person{ID: 1, Name: "Mario Bros", Email: dat.NullStringFrom("mario@bros.com")}
+	var out Person
+	err := s.
+		InsertInto("people").
+		Columns("id", "name", "email").
+		Record(p).
+		OnConflict("id").
+		DoUpdateAllExcept("id").
+		Returning("id", "name", "email").
+		QueryStruct(&out)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, out.ID)
+	assert.Equal(t, "Mario Bros", out.Name)
+	assert.Equal(t, "mario@bros.com", out.Email.String)
+}
+
+func TestInsertInterpolatedTimeWithoutTimezone(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.Exec("CREATE TEMP TABLE local_times (at timestamp) ON COMMIT DROP")
+	assert.NoError(t, err)
+	_, err = s.Exec("SET LOCAL TIME ZONE 'America/New_York'")
+	assert.NoError(t, err)
+
+	at := time.Date(2004, time.January, 1, 1, 1, 1, 0, time.FixedZone("IST", 5*3600+30*60))
+	dat.EnableInterpolation = true
+	defer func() { dat.EnableInterpolation = false }()
+
+	_, err = s.InsertInto("local_times").Columns("at").Values(at).Exec()
+	assert.NoError(t, err)
+
+	// a timestamp column stores the wall clock time
+	var actual string
+	err = s.SQL("SELECT to_char(at, 'YYYY-MM-DD HH24:MI:SS') FROM local_times").QueryScalar(&actual)
+	assert.NoError(t, err)
+	assert.Equal(t, "2004-01-01 01:01:01", actual)
+}
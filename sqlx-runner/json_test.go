@@ -33,3 +33,78 @@ func TestRealJSONInterpolated(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 2, num)
 }
+
+func TestJSONOperators(t *testing.T) {
+	from := `(VALUES (1, '{"a": {"b": "x"}, "tags": [1, 2]}'::jsonb), (2, '{"a": {"b": "y"}}'::jsonb)) AS docs (id, data)`
+
+	var ids []int64
+	err := testDB.Select("id").
+		From(from).
+		Where(dat.JSONGet("data", "a", "b")+" = $1", "x").
+		QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, ids)
+
+	ids = nil
+	err = testDB.Select("id").
+		From(from).
+		Where(dat.JSONContains("data", map[string]interface{}{"a": map[string]string{"b": "y"}})).
+		QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids)
+
+	ids = nil
+	err = testDB.Select("id").
+		From(from).
+		Where(dat.JSONExists("data", "tags")).
+		QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, ids)
+}
+
+func TestJSONFieldRoundTrip(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.SQL("ALTER TABLE people ADD COLUMN config jsonb").Exec()
+	assert.NoError(t, err)
+
+	type Config struct {
+		Theme  string `json:"theme"`
+		Layout struct {
+			Columns int `json:"columns"`
+		} `json:"layout"`
+	}
+	type Profile struct {
+		ID     int64   `db:"id"`
+		Name   string  `db:"name"`
+		Config *Config `db:"config,json"`
+	}
+
+	for _, interpolate := range []bool{false, true} {
+		dat.EnableInterpolation = interpolate
+
+		in := Profile{Name: "Configured", Config: &Config{Theme: "dark"}}
+		in.Config.Layout.Columns = 3
+		var out Profile
+		err = s.
+			InsertInto("people").
+			Columns("name", "config").
+			Record(&in).
+			Returning("id", "name", "config").
+			QueryStruct(&out)
+		assert.NoError(t, err)
+		assert.Equal(t, in.Config, out.Config)
+
+		var profiles []Profile
+		err = s.Select("id", "name", "config").From("people").Where("id = $1 OR id = $2", 1, out.ID).OrderBy("id").QueryStructs(&profiles)
+		assert.NoError(t, err)
+		if assert.Equal(t, 2, len(profiles)) {
+			// NULL is a nil pointer
+			assert.Nil(t, profiles[0].Config)
+			assert.Equal(t, "dark", profiles[1].Config.Theme)
+			assert.Equal(t, 3, profiles[1].Config.Layout.Columns)
+		}
+	}
+	dat.EnableInterpolation = false
+}
@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// ErrNoConnectionString occurs when Listen is called on a DB whose
+// connection string is unknown, see SetConnectionString.
+var ErrNoConnectionString = errors.New("Connection string is required to listen, see SetConnectionString")
+
+// ListenerMinReconnectInterval is the delay before a lost listener
+// connection is reestablished, doubling on every failed attempt up to
+// ListenerMaxReconnectInterval.
+var ListenerMinReconnectInterval = time.Second
+
+// ListenerMaxReconnectInterval caps the delay between attempts to reconnect
+// a listener.
+var ListenerMaxReconnectInterval = time.Minute
+
+// listenerPingInterval is how often an idle listener checks its connection.
+const listenerPingInterval = 90 * time.Second
+
+// Notification is a message sent with NOTIFY.
+type Notification struct {
+	// Channel is the channel the notification was sent on.
+	Channel string
+	// Payload is the payload, empty if unspecified.
+	Payload string
+	// PID is the process ID of the notifying backend.
+	PID int
+	// Reconnected is set on the notification sent after the connection was
+	// reestablished, notifications sent in the meantime are lost, eg flush a
+	// cache invalidated by notifications.
+	Reconnected bool
+}
+
+// SetConnectionString sets the connection string used by Listen for a DB
+// not created with NewDBFromString.
+func (db *DB) SetConnectionString(connectionString string) {
+	db.connString = connectionString
+}
+
+// Listen executes LISTEN on channel over a dedicated connection and delivers
+// the notifications on the returned channel until ctx is done, when the
+// channel is closed. A lost connection is reconnected with exponential
+// backoff, see ListenerMinReconnectInterval.
+func (db *DB) Listen(ctx context.Context, channel string) (<-chan Notification, error) {
+	if db.connString == "" {
+		return nil, ErrNoConnectionString
+	}
+
+	lg := logger().With(zap.String("channel", channel))
+	l := pq.NewListener(db.connString, ListenerMinReconnectInterval, ListenerMaxReconnectInterval,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				lg.Warn("listener.error", zap.Error(err))
+			}
+		})
+
+	// Listen blocks until the connection is established
+	listening := make(chan error, 1)
+	go func() {
+		listening <- l.Listen(channel)
+	}()
+	select {
+	case err := <-listening:
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+	case <-ctx.Done():
+		l.Close()
+		return nil, ctx.Err()
+	}
+	lg.Debug("listen")
+
+	ch := make(chan Notification)
+	go func() {
+		defer close(ch)
+		defer l.Close()
+
+		ticker := time.NewTicker(listenerPingInterval)
+		defer ticker.Stop()
+		for {
+			var n Notification
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// detects a dead connection which would otherwise go unnoticed
+				go l.Ping()
+				continue
+			case pn := <-l.Notify:
+				if pn == nil {
+					n = Notification{Channel: channel, Reconnected: true}
+				} else {
+					n = Notification{Channel: pn.Channel, Payload: pn.Extra, PID: pn.BePid}
+				}
+			}
+			select {
+			case ch <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Notify sends payload on channel with pg_notify. Within a transaction the
+// notification is delivered on commit.
+func (q *Queryable) Notify(channel string, payload string) error {
+	_, err := q.Exec("SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenNotify(t *testing.T) {
+	db := NewDB(sqlDB, "postgres")
+	_, err := db.Listen(context.Background(), "dat_test")
+	assert.Equal(t, ErrNoConnectionString, err)
+
+	db.SetConnectionString(os.Getenv("DAT_DSN"))
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := db.Listen(ctx, "dat_test")
+	assert.NoError(t, err)
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Notify("dat_test", "posts:1"))
+	assert.NoError(t, tx.Commit())
+
+	select {
+	case n := <-ch:
+		assert.Equal(t, "dat_test", n.Channel)
+		assert.Equal(t, "posts:1", n.Payload)
+		assert.False(t, n.Reconnected)
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not received")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed")
+	}
+}
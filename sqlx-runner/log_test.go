@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/casualjim/dat"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	defer func(threshold time.Duration) {
+		dat.SetLogger(nil)
+		LogQueriesThreshold = threshold
+	}(LogQueriesThreshold)
+	dat.SetLogger(zap.New(core))
+
+	LogQueriesThreshold = 1 * time.Millisecond
+	_, err := testDB.SQL("SELECT pg_sleep(0.1)").Exec()
+	assert.NoError(t, err)
+
+	slow := logs.FilterMessage("SLOW query").All()
+	assert.Equal(t, 1, len(slow))
+	fields := slow[0].ContextMap()
+	assert.Equal(t, "SELECT pg_sleep(0.1)", fields["sql"])
+	assert.EqualValues(t, 1, fields["rowsAffected"])
+	assert.True(t, fields["elapsed"].(time.Duration) >= 100*time.Millisecond)
+
+	// 0 disables slow query logging
+	LogQueriesThreshold = 0
+	_, err = testDB.SQL("SELECT pg_sleep(0.01)").Exec()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, logs.FilterMessage("SLOW query").Len())
+}
+
+func TestTxLogID(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	defer dat.SetLogger(nil)
+	dat.SetLogger(zap.New(core))
+
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	assert.Len(t, tx.ID(), 8)
+	id := tx.ID()
+
+	_, err = tx.SQL("SELECT 1").Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+
+	for _, msg := range []string{"begin tx", "Query time", "commit"} {
+		entries := logs.FilterMessage(msg).All()
+		if assert.Equal(t, 1, len(entries), msg) {
+			assert.Equal(t, id, entries[0].ContextMap()["tx"], msg)
+		}
+	}
+
+	// statements outside a transaction are not tagged
+	logs.TakeAll()
+	_, err = testDB.SQL("SELECT 1").Exec()
+	assert.NoError(t, err)
+	_, tagged := logs.FilterMessage("Query time").All()[0].ContextMap()["tx"]
+	assert.False(t, tagged)
+
+	other, err := testDB.Begin()
+	assert.NoError(t, err)
+	assert.NotEqual(t, id, other.ID())
+	other.WithID("req-42")
+	_, err = other.SQL("SELECT 1").Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, other.Rollback())
+	assert.Equal(t, "req-42", logs.FilterMessage("rollback").All()[0].ContextMap()["tx"])
+}
+
+func TestLeakWarnTimeout(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	defer func(timeout time.Duration) {
+		dat.SetLogger(nil)
+		LeakWarnTimeout = timeout
+	}(LeakWarnTimeout)
+	dat.SetLogger(zap.New(core))
+	LeakWarnTimeout = 20 * time.Millisecond
+
+	closed, err := testDB.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, closed.Commit())
+
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, tx.Rollback())
+
+	leaks := logs.FilterMessage("transaction held open").All()
+	if assert.Equal(t, 1, len(leaks)) {
+		fields := leaks[0].ContextMap()
+		assert.Equal(t, tx.ID(), fields["tx"])
+		assert.Contains(t, fields["caller"], "log_test.go")
+		assert.Contains(t, fields["stack"], "TestLeakWarnTimeout")
+	}
+}
+
+func TestDeadlockLog(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	detail := "Process 12 waits for ShareLock on transaction 34; blocked by process 56."
+	err := logSQLError(zap.New(core), &pq.Error{
+		Code:    "40P01",
+		Message: "deadlock detected",
+		Detail:  detail,
+		Where:   `while updating tuple (0,1) in relation "people"`,
+	}, "execFn.30", "UPDATE people SET name = $1", nil)
+
+	assert.True(t, dat.IsDeadlock(err))
+	if assert.Equal(t, 1, logs.Len()) {
+		fields := logs.All()[0].ContextMap()
+		assert.Equal(t, detail, fields["detail"])
+		assert.Equal(t, `while updating tuple (0,1) in relation "people"`, fields["where"])
+	}
+}
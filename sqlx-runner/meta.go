@@ -47,7 +47,7 @@ CREATE TABLE IF NOT EXISTS dat__meta (
 
 	tx, err := db.Begin()
 	if err != nil {
-		logger.Fatal("Could not create session")
+		logger().Fatal("Could not create session")
 	}
 	defer tx.AutoRollback()
 
@@ -56,7 +56,7 @@ CREATE TABLE IF NOT EXISTS dat__meta (
 		dat.Expr(createMeta),
 	)
 	if err != nil {
-		logger.Fatal("Could not execute Multi SQL")
+		logger().Fatal("Could not execute Multi SQL")
 		panic(err)
 	}
 	tx.Commit()
@@ -68,7 +68,7 @@ CREATE TABLE IF NOT EXISTS dat__meta (
 func (db *DB) MustRegisterFunction(name string, version string, body string) {
 	tx, err := db.Begin()
 	if err != nil {
-		logger.Fatal("Could not register function", zap.Error(err), zap.String("name", name))
+		logger().Fatal("Could not register function", zap.Error(err), zap.String("name", name))
 	}
 	defer tx.AutoRollback()
 
@@ -85,11 +85,11 @@ func (db *DB) MustRegisterFunction(name string, version string, body string) {
 		SQL(`SELECT id FROM dat__meta WHERE kind = 'function' AND version = $1 AND name = $2`, crc, name).
 		QueryScalar(&metaID)
 	if err != nil && err != sql.ErrNoRows && err != dat.ErrNotFound {
-		logger.Fatal("Could not get metadata for function", zap.Error(err))
+		logger().Fatal("Could not get metadata for function", zap.Error(err))
 	}
 
 	if metaID == 0 {
-		logger.Debug("Adding function", zap.String("name", name))
+		logger().Debug("Adding function", zap.String("name", name))
 		commands := []*dat.Expression{
 			dat.Expr(`
 				INSERT INTO dat__meta (kind, version, name)
@@ -107,7 +107,7 @@ func (db *DB) MustRegisterFunction(name string, version string, body string) {
 
 		_, err := tx.ExecMulti(commands...)
 		if err != nil {
-			logger.Fatal("Could not insert function", zap.Error(err))
+			logger().Fatal("Could not insert function", zap.Error(err))
 		}
 	}
 	tx.Commit()
@@ -123,16 +123,16 @@ func (db *DB) MustRegisterFunction(name string, version string, body string) {
 // 		if fi.IsDir() {
 // 			return nil
 // 		}
-// 		logger.Debug("MustRegisterFunctionsInDir", "dir", dir, "path", path)
+// 		logger().Debug("MustRegisterFunctionsInDir", "dir", dir, "path", path)
 
 // 		if filepath.Ext(path) == ".sql" {
 // 			f, err := os.Open(path)
 // 			if err != nil {
-// 				logger.Fatal("Could not open SQL file.", "file", path)
+// 				logger().Fatal("Could not open SQL file.", "file", path)
 // 			}
 // 			err = dat.ParseFromReader(f, keys, sprocs)
 // 			if err != nil {
-// 				logger.Fatal("Could not parse SQL file.", "err", err)
+// 				logger().Fatal("Could not parse SQL file.", "err", err)
 // 			}
 // 		}
 // 		return nil
@@ -0,0 +1,35 @@
+package runner
+
+import "context"
+
+// QueryObserver observes queries dispatched to the database for tracing or
+// metrics.
+type QueryObserver interface {
+	// QueryStart is called before a query is dispatched. The returned context
+	// is passed to QueryEnd.
+	QueryStart(ctx context.Context, sql string, args []interface{}) context.Context
+	// QueryEnd is called after a query completes. rowsAffected is -1 for
+	// queries which return rows.
+	QueryEnd(ctx context.Context, err error, rowsAffected int64)
+}
+
+// Observer observes queries.
+var Observer QueryObserver
+
+// SetObserver sets this runner's query observer. Use nil to remove it.
+func SetObserver(o QueryObserver) {
+	Observer = o
+}
+
+// observe notifies Observer that a query is about to be dispatched. The
+// returned func must be called when the query completes.
+func observe(ctx context.Context, sql string, args []interface{}) (context.Context, func(err error, rowsAffected int64)) {
+	o := Observer
+	if o == nil {
+		return ctx, func(error, int64) {}
+	}
+	ctx = o.QueryStart(ctx, sql, args)
+	return ctx, func(err error, rowsAffected int64) {
+		o.QueryEnd(ctx, err, rowsAffected)
+	}
+}
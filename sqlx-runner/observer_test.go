@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey string
+
+type recordingObserver struct {
+	sqls    []string
+	ends    []int64
+	errs    []error
+	spanIDs []interface{}
+}
+
+func (o *recordingObserver) QueryStart(ctx context.Context, sql string, args []interface{}) context.Context {
+	o.sqls = append(o.sqls, sql)
+	return context.WithValue(ctx, ctxKey("span"), len(o.sqls))
+}
+
+func (o *recordingObserver) QueryEnd(ctx context.Context, err error, rowsAffected int64) {
+	o.spanIDs = append(o.spanIDs, ctx.Value(ctxKey("span")))
+	o.errs = append(o.errs, err)
+	o.ends = append(o.ends, rowsAffected)
+}
+
+func TestQueryObserver(t *testing.T) {
+	installFixtures()
+
+	o := &recordingObserver{}
+	SetObserver(o)
+	defer SetObserver(nil)
+
+	_, err := testDB.Update("people").Set("foo", "bar").Where("id = $1", 1).Exec()
+	assert.NoError(t, err)
+
+	var name string
+	err = testDB.Select("name").From("people").Where("id = $1", 1).QueryScalar(&name)
+	assert.NoError(t, err)
+
+	err = testDB.Select("name").From("people").Where("id = $1", -1).QueryScalar(&name)
+	assert.Error(t, err)
+
+	assert.Equal(t, 3, len(o.sqls))
+	assert.Equal(t, []interface{}{1, 2, 3}, o.spanIDs)
+	assert.Equal(t, []int64{1, -1, -1}, o.ends)
+	assert.NoError(t, o.errs[0])
+	assert.NoError(t, o.errs[1])
+	assert.Error(t, o.errs[2])
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/casualjim/dat"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ErrPrepareUnsupported occurs when the Queryable cannot prepare statements,
+// eg a Sharded DB which routes each query by its args.
+var ErrPrepareUnsupported = errors.New("Prepare is not supported")
+
+type preparer interface {
+	PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error)
+}
+
+// PreparedStmt is a statement parsed once by the database and executed with
+// new args on each call, eg to insert a row per message on an ingest path.
+// It is safe for concurrent use. Close it when done.
+type PreparedStmt struct {
+	stmt *sqlx.Stmt
+	sql  string
+	txID string
+}
+
+// Prepare prepares the statement of builder. The builder supplies the shape
+// of the statement, its args are discarded and supplied on each call
+// instead, in the order of the placeholders.
+//
+//	stmt, err := DB.Prepare(DB.InsertInto("events").Columns("name", "at").Values(nil, nil))
+//	defer stmt.Close()
+//	_, err = stmt.Exec("signup", time.Now())
+//
+// The statement is never interpolated. A statement prepared within a Tx is
+// closed by Commit or Rollback. An error building the statement is returned
+// rather than panicking.
+func (q *Queryable) Prepare(builder dat.Builder) (*PreparedStmt, error) {
+	return q.PrepareContext(context.Background(), builder)
+}
+
+// PrepareContext is like Prepare but honours ctx cancellation while
+// preparing.
+func (q *Queryable) PrepareContext(ctx context.Context, builder dat.Builder) (*PreparedStmt, error) {
+	p, ok := q.runner.(preparer)
+	if !ok {
+		return nil, ErrPrepareUnsupported
+	}
+	fullSQL, _, err := dat.ToSQL(builder)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := p.PreparexContext(ctx, fullSQL)
+	if err != nil {
+		return nil, ctxErr(ctx, logSQLError(logger(), err, "prepare", fullSQL, nil))
+	}
+	return &PreparedStmt{stmt: stmt, sql: fullSQL, txID: q.txID}, nil
+}
+
+// SQL returns the statement.
+func (p *PreparedStmt) SQL() string {
+	return p.sql
+}
+
+func (p *PreparedStmt) log() *zap.Logger {
+	if p.txID != "" {
+		return logger().With(zap.String("tx", p.txID))
+	}
+	return logger()
+}
+
+// Exec executes the statement with args.
+func (p *PreparedStmt) Exec(args ...interface{}) (*dat.Result, error) {
+	return p.ExecContext(context.Background(), args...)
+}
+
+// ExecContext is like Exec but honours ctx cancellation.
+func (p *PreparedStmt) ExecContext(ctx context.Context, args ...interface{}) (*dat.Result, error) {
+	lg := p.log()
+	ctx, end := observe(ctx, p.sql, args)
+	start := time.Now()
+	result, err := p.stmt.ExecContext(ctx, args...)
+	if err != nil {
+		logExecutionTime(lg, start, p.sql, args)
+		end(err, 0)
+		return nil, ctxErr(ctx, logSQLError(lg, err, "prepared.exec", p.sql, args))
+	}
+	logExecutionTime(lg, start, p.sql, args, rowsAffectedField(result))
+	end(nil, rowsAffected(result))
+	n, err := result.RowsAffected()
+	if err != nil {
+		lg.Debug("RowsAffected is not reported", zap.Error(err))
+		return nil, dat.ErrRowsAffectedUnsupported
+	}
+	return &dat.Result{RowsAffected: n}, nil
+}
+
+// QueryStruct executes the statement with args and scans the first row
+// into dest like Execer.QueryStruct, or all rows if dest is a pointer to a
+// slice like Execer.QueryStructs. Returns dat.ErrNotFound if no row was
+// found for a non-slice dest.
+func (p *PreparedStmt) QueryStruct(dest interface{}, args ...interface{}) error {
+	return p.QueryStructContext(context.Background(), dest, args...)
+}
+
+// QueryStructContext is like QueryStruct but honours ctx cancellation.
+func (p *PreparedStmt) QueryStructContext(ctx context.Context, dest interface{}, args ...interface{}) (err error) {
+	lg := p.log()
+	ctx, end := observe(ctx, p.sql, args)
+	defer func() { end(err, -1) }()
+	defer logExecutionTime(lg, time.Now(), p.sql, args)
+
+	rows, err := p.stmt.QueryxContext(ctx, args...)
+	if err != nil {
+		return ctxErr(ctx, logSQLError(lg, err, "prepared.query", p.sql, args))
+	}
+	defer rows.Close()
+	if err = scanResult(rows, dest, nil); err != nil {
+		return ctxErr(ctx, logSQLError(lg, err, "prepared.scan", p.sql, args))
+	}
+	return nil
+}
+
+// Close closes the statement.
+func (p *PreparedStmt) Close() error {
+	return p.stmt.Close()
+}
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/casualjim/dat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreparedStmt(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	insert, err := s.Prepare(s.InsertInto("people").Columns("name", "email").Values(nil, nil))
+	assert.NoError(t, err)
+	defer insert.Close()
+	assert.Equal(t, `INSERT INTO people ("name","email") VALUES ($1,$2)`, insert.SQL())
+
+	for _, name := range []string{"Luigi", "Peach"} {
+		res, err := insert.Exec(name, name+"@mgutz.com")
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, res.RowsAffected)
+	}
+
+	byName, err := s.Prepare(s.Select("id", "name", "email").From("people").Where("name = $1", ""))
+	assert.NoError(t, err)
+	defer byName.Close()
+
+	var person Person
+	err = byName.QueryStruct(&person, "Peach")
+	assert.NoError(t, err)
+	assert.Equal(t, "Peach", person.Name)
+	assert.Equal(t, "Peach@mgutz.com", person.Email.String)
+
+	var people []*Person
+	err = byName.QueryStruct(&people, "Luigi")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(people))
+
+	err = byName.QueryStruct(&person, "Bowser")
+	assert.Exactly(t, dat.ErrNotFound, err)
+
+	_, err = s.Prepare(s.Select("id").From("nonexistent"))
+	assert.Error(t, err)
+}
+
+func TestPrepareBuildError(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	stmt, err := s.Prepare(s.Select("id").From("people").AsOfSystemTime("'-10s'"))
+	assert.Equal(t, dat.ErrAsOfSystemTimeUnsupported, err)
+	assert.Nil(t, stmt)
+}
@@ -1,8 +1,9 @@
 package runner
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/casualjim/dat"
@@ -11,6 +12,16 @@ import (
 // Queryable is an object that can be queried.
 type Queryable struct {
 	runner database
+
+	// timeout is the default timeout for queries which do not set their own,
+	// 0 means forever
+	timeout time.Duration
+
+	// retry is the policy for retrying idempotent statements
+	retry RetryPolicy
+
+	// txID is the correlation ID logged with queries of a transaction
+	txID string
 }
 
 // WrapSqlxExt converts a sqlx.Ext to a *Queryable
@@ -19,67 +30,64 @@ func WrapSqlxExt(e sqlx.Ext) *Queryable {
 	default:
 		panic(fmt.Sprintf("unexpected type %T", e))
 	case database:
-		return &Queryable{e}
+		return &Queryable{runner: e}
 	}
 }
 
 // Call creates a new CallBuilder for the given sproc and args.
 func (q *Queryable) Call(sproc string, args ...interface{}) *dat.CallBuilder {
 	b := dat.NewCallBuilder(sproc, args...)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // DeleteFrom creates a new DeleteBuilder for the given table.
 func (q *Queryable) DeleteFrom(table string) *dat.DeleteBuilder {
 	b := dat.NewDeleteBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
+// newExecer creates an Execer for builder applying the default timeout.
+func (q *Queryable) newExecer(b dat.Builder) *Execer {
+	ex := NewExecer(q.runner, b)
+	ex.retry = q.retry
+	ex.txID = q.txID
+	if q.timeout > 0 {
+		ex.Timeout(q.timeout)
+		ex.defaultTimeout = true
+	}
+	return ex
+}
+
 // Exec executes a SQL query with optional arguments.
 func (q *Queryable) Exec(cmd string, args ...interface{}) (*dat.Result, error) {
-	var result sql.Result
-	var err error
+	return q.ExecContext(context.Background(), cmd, args...)
+}
 
-	if len(args) == 0 {
-		result, err = q.runner.Exec(cmd)
-	} else {
-		result, err = q.runner.Exec(cmd, args...)
-	}
-	if err != nil {
-		return nil, logSQLError(err, "Exec", cmd, args)
-	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return nil, logSQLError(err, "Exec", cmd, args)
-	}
-	return &dat.Result{RowsAffected: rowsAffected}, nil
+// ExecContext executes a SQL query with optional arguments, aborting it
+// when ctx is cancelled.
+func (q *Queryable) ExecContext(ctx context.Context, cmd string, args ...interface{}) (*dat.Result, error) {
+	return q.newExecer(dat.NewRawBuilder(cmd, args...).SetIsInterpolated(false)).ExecContext(ctx)
 }
 
 // ExecBuilder executes the SQL in builder.
 func (q *Queryable) ExecBuilder(b dat.Builder) error {
-	sql, args, err := b.Interpolate()
-	if err != nil {
-		return err
-	}
+	return q.ExecBuilderContext(context.Background(), b)
+}
 
-	if len(args) == 0 {
-		_, err = q.runner.Exec(sql)
-	} else {
-		_, err = q.runner.Exec(sql, args...)
-	}
-	if err != nil {
-		return logSQLError(err, "ExecBuilder", sql, args)
-	}
-	return nil
+// ExecBuilderContext executes the SQL in builder, aborting it when ctx is
+// cancelled.
+func (q *Queryable) ExecBuilderContext(ctx context.Context, b dat.Builder) error {
+	_, err := q.newExecer(b).exec(ctx)
+	return ctxErr(ctx, err)
 }
 
 // ExecMulti executes multiple SQL statements returning the number of
 // statements executed, or the index at which an error occurred.
 func (q *Queryable) ExecMulti(commands ...*dat.Expression) (int, error) {
 	for i, cmd := range commands {
-		_, err := q.runner.Exec(cmd.Sql, cmd.Args...)
+		_, err := q.Exec(cmd.Sql, cmd.Args...)
 		if err != nil {
 			return i, err
 		}
@@ -90,48 +98,48 @@ func (q *Queryable) ExecMulti(commands ...*dat.Expression) (int, error) {
 // InsertInto creates a new InsertBuilder for the given table.
 func (q *Queryable) InsertInto(table string) *dat.InsertBuilder {
 	b := dat.NewInsertBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // Insect inserts or selects.
 func (q *Queryable) Insect(table string) *dat.InsectBuilder {
 	b := dat.NewInsectBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // Select creates a new SelectBuilder for the given columns.
 func (q *Queryable) Select(columns ...string) *dat.SelectBuilder {
 	b := dat.NewSelectBuilder(columns...)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // SelectDoc creates a new SelectBuilder for the given columns.
 func (q *Queryable) SelectDoc(columns ...string) *dat.SelectDocBuilder {
 	b := dat.NewSelectDocBuilder(columns...)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // SQL creates a new raw SQL builder.
 func (q *Queryable) SQL(sql string, args ...interface{}) *dat.RawBuilder {
 	b := dat.NewRawBuilder(sql, args...)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // Update creates a new UpdateBuilder for the given table.
 func (q *Queryable) Update(table string) *dat.UpdateBuilder {
 	b := dat.NewUpdateBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // Upsert creates a new UpdateBuilder for the given table.
 func (q *Queryable) Upsert(table string) *dat.UpsertBuilder {
 	b := dat.NewUpsertBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"sync/atomic"
+
+	"github.com/casualjim/dat"
+)
+
+// Replicated is a primary database with read replicas. Select and SelectDoc
+// are load balanced round robin across the replicas, everything else
+// including transactions runs on the primary.
+type Replicated struct {
+	*DB
+
+	replicas []*DB
+	next     uint32
+}
+
+// NewReplicated creates a Replicated database from a primary and its
+// replicas. Reads go to the primary if there are no replicas.
+func NewReplicated(primary *DB, replicas ...*DB) *Replicated {
+	if primary == nil {
+		panic("NewReplicated requires a primary")
+	}
+	return &Replicated{DB: primary, replicas: replicas}
+}
+
+// Primary returns the primary database, eg to read after a write without
+// replication lag.
+func (r *Replicated) Primary() *DB {
+	return r.DB
+}
+
+// Replica returns the next replica, the primary if there are none.
+func (r *Replicated) Replica() *DB {
+	n := len(r.replicas)
+	if n == 0 {
+		return r.DB
+	}
+	i := atomic.AddUint32(&r.next, 1)
+	return r.replicas[int((i-1)%uint32(n))]
+}
+
+// Replicas returns the read replicas.
+func (r *Replicated) Replicas() []*DB {
+	return r.replicas
+}
+
+// Select creates a new SelectBuilder for the given columns which runs on a
+// replica.
+func (r *Replicated) Select(columns ...string) *dat.SelectBuilder {
+	return r.Replica().Select(columns...)
+}
+
+// SelectDoc creates a new SelectDocBuilder for the given columns which runs
+// on a replica.
+func (r *Replicated) SelectDoc(columns ...string) *dat.SelectDocBuilder {
+	return r.Replica().SelectDoc(columns...)
+}
@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicatedRouting(t *testing.T) {
+	installFixtures()
+	// distinct sqlx.DBs on the same pool to tell which one runs a query
+	replica1 := NewDBFromSqlx(sqlx.NewDb(testDB.SQLDB(), "postgres"))
+	replica2 := NewDBFromSqlx(sqlx.NewDb(testDB.SQLDB(), "postgres"))
+	db := NewReplicated(testDB, replica1, replica2)
+
+	var conn Connection = db
+	assert.NotNil(t, conn)
+	assert.True(t, db.Primary() == testDB)
+	assert.True(t, db.Replica() == replica1)
+	assert.True(t, db.Replica() == replica2)
+	assert.True(t, db.Replica() == replica1)
+
+	b := db.Select("name").From("people").Where("id = $1", 1)
+	assert.True(t, b.Execer.(*Execer).database == replica2.Queryable.runner)
+	assert.False(t, b.Execer.(*Execer).database == testDB.Queryable.runner)
+	var name string
+	assert.NoError(t, b.QueryScalar(&name))
+	assert.Equal(t, "Mario", name)
+
+	doc := db.SelectDoc("name").From("people")
+	assert.True(t, doc.Execer.(*Execer).database == replica1.Queryable.runner)
+
+	u := db.Update("people").Set("name", "Mario").Where("id = $1", 1)
+	assert.True(t, u.Execer.(*Execer).database == testDB.Queryable.runner)
+
+	// no replicas reads from the primary
+	db = NewReplicated(testDB)
+	assert.True(t, db.Replica() == testDB)
+}
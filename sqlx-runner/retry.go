@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/casualjim/dat"
+	"github.com/cenkalti/backoff"
+	"go.uber.org/zap"
+)
+
+// MaxTxRetries is the maximum number of times WithTxRetry replays a
+// transaction after a serialization failure.
+var MaxTxRetries uint64 = 5
+
+// isTxReplayable determines if the whole transaction should be replayed
+// because of a serialization_failure (40001) or deadlock_detected (40P01).
+func isTxReplayable(err error) bool {
+	if pe, ok := dat.AsPgError(err); ok {
+		return pe.Code == "40001" || pe.Code == "40P01"
+	}
+	return false
+}
+
+// WithTxRetry begins a transaction, calls fn and commits. If the transaction
+// fails with a serialization failure or deadlock, it is rolled back and
+// replayed with an exponential backoff up to MaxTxRetries times. The last
+// error is returned.
+func (db *DB) WithTxRetry(ctx context.Context, opts *sql.TxOptions, fn func(*Tx) error) error {
+	b := backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), MaxTxRetries), ctx)
+	return backoff.Retry(func() error {
+		err := db.runTx(ctx, opts, fn)
+		if err == nil {
+			return nil
+		}
+		if isTxReplayable(err) {
+			logger().Warn("WithTxRetry: replaying transaction", zap.Error(err))
+			return err
+		}
+		return backoff.Permanent(err)
+	}, b)
+}
+
+// runTx runs fn in a single transaction.
+func (db *DB) runTx(ctx context.Context, opts *sql.TxOptions, fn func(*Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer tx.AutoRollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.CommitContext(ctx)
+}
+
+// RetryPolicy decides when an idempotent statement executed outside of a
+// transaction is executed again, see DB.SetExecRetry.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a statement is retried, 0
+	// disables retries.
+	MaxRetries uint64
+
+	// Retryable reports whether err is transient. IsTransientError is used
+	// when nil.
+	Retryable func(err error) bool
+}
+
+// SetExecRetry retries idempotent statements executed directly against db
+// which fail with a transient error, with an exponential backoff. Select
+// and SelectDoc builders are idempotent, mark other builders with
+// Idempotent. Statements within a Tx are never retried.
+func (db *DB) SetExecRetry(policy RetryPolicy) {
+	db.Queryable.retry = policy
+}
+
+// IsTransientError determines if err is a connection failure which may
+// succeed on another connection: a bad or dropped connection,
+// connection_exception (class 08), admin_shutdown (57P01),
+// crash_shutdown (57P02) or cannot_connect_now (57P03).
+func IsTransientError(err error) bool {
+	if pe, ok := dat.AsPgError(err); ok {
+		return strings.HasPrefix(pe.Code, "08") ||
+			pe.Code == "57P01" || pe.Code == "57P02" || pe.Code == "57P03"
+	}
+	err = dat.Cause(err)
+	if _, ok := err.(*net.OpError); ok {
+		return true
+	}
+	return err == driver.ErrBadConn || err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// Idempotent marks the statement as safe to execute more than once, so it
+// is retried per the DB's RetryPolicy.
+func (ex *Execer) Idempotent() dat.Execer {
+	ex.idempotent = true
+	return ex
+}
+
+func (ex *Execer) isIdempotent() bool {
+	if ex.idempotent {
+		return true
+	}
+	switch ex.builder.(type) {
+	case *dat.SelectBuilder, *dat.SelectDocBuilder:
+		return true
+	}
+	return false
+}
+
+// withRetry calls fn and calls it again per the retry policy while it fails
+// with a transient error.
+func (ex *Execer) withRetry(ctx context.Context, fn func() error) error {
+	if ex.retry.MaxRetries == 0 || !ex.isIdempotent() {
+		return fn()
+	}
+
+	retryable := ex.retry.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+	b := backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), ex.retry.MaxRetries), ctx)
+	return backoff.Retry(func() error {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() == nil && retryable(err) {
+			logger().Warn("Retrying statement", zap.Error(err))
+			return err
+		}
+		return backoff.Permanent(err)
+	}, b)
+}
@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/casualjim/dat"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTxRetrySerializationFailure(t *testing.T) {
+	installFixtures()
+
+	attempts := 0
+	err := testDB.WithTxRetry(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx *Tx) error {
+		attempts++
+		if attempts < 3 {
+			return &pq.Error{Code: "40001"}
+		}
+		_, err := tx.InsertInto("people").Columns("name", "email").
+			Values("Retry", "retry@mgutz.com").
+			Exec()
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	var n int
+	err = testDB.SQL("SELECT count(*) FROM people WHERE email = $1", "retry@mgutz.com").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestWithTxRetryPermanentError(t *testing.T) {
+	installFixtures()
+
+	expected := errors.New("boom")
+	attempts := 0
+	err := testDB.WithTxRetry(context.Background(), nil, func(tx *Tx) error {
+		attempts++
+		return expected
+	})
+	assert.Exactly(t, expected, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestWithTxRetryMaxRetries(t *testing.T) {
+	installFixtures()
+
+	attempts := 0
+	err := testDB.WithTxRetry(context.Background(), nil, func(tx *Tx) error {
+		attempts++
+		return &pq.Error{Code: "40P01"}
+	})
+	assert.Error(t, err)
+	assert.EqualValues(t, MaxTxRetries+1, attempts)
+}
+
+func TestIsTransientError(t *testing.T) {
+	assert.True(t, IsTransientError(driver.ErrBadConn))
+	assert.True(t, IsTransientError(&pq.Error{Code: "08006"}))
+	assert.True(t, IsTransientError(&pq.Error{Code: "57P01"}))
+	assert.False(t, IsTransientError(&pq.Error{Code: "23505"}))
+	assert.False(t, IsTransientError(errors.New("boom")))
+	assert.True(t, IsTransientError(dat.NewQueryError(driver.ErrBadConn, "SELECT 1", nil)))
+}
+
+func TestExecRetryIdempotent(t *testing.T) {
+	ex := NewExecer(nil, testDB.Select("id").From("people"))
+	ex.retry = RetryPolicy{MaxRetries: 3}
+
+	attempts := 0
+	err := ex.withRetry(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = ex.withRetry(context.Background(), func() error {
+		attempts++
+		return &pq.Error{Code: "23505"}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestExecRetryNotIdempotent(t *testing.T) {
+	ex := NewExecer(nil, testDB.InsertInto("people").Columns("name").Values("Retry"))
+	ex.retry = RetryPolicy{MaxRetries: 3}
+
+	attempts := 0
+	err := ex.withRetry(context.Background(), func() error {
+		attempts++
+		return driver.ErrBadConn
+	})
+	assert.Exactly(t, driver.ErrBadConn, err)
+	assert.Equal(t, 1, attempts)
+
+	attempts = 0
+	ex.Idempotent()
+	ex.withRetry(context.Background(), func() error {
+		attempts++
+		return driver.ErrBadConn
+	})
+	assert.Equal(t, 4, attempts)
+}
+
+func TestSetExecRetry(t *testing.T) {
+	installFixtures()
+	testDB.SetExecRetry(RetryPolicy{MaxRetries: 2})
+	defer testDB.SetExecRetry(RetryPolicy{})
+
+	var n int
+	err := testDB.Select("count(*)").From("people").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, n)
+}
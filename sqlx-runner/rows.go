@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"time"
+
+	"github.com/casualjim/dat"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Rows streams the rows of a query, see Execer.Iterate. Rows must be
+// closed.
+type Rows struct {
+	*sqlx.Rows
+
+	ctx    context.Context
+	sql    string
+	args   []interface{}
+	start  time.Time
+	end    func(err error, rowsAffected int64)
+	log    *zap.Logger
+	count  int64
+	closed bool
+}
+
+// Next prepares the next row for Scan. It returns false when there are no
+// more rows or an error occurred, see Err.
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	return false
+}
+
+// Scan scans the current row into dest. A single pointer to a struct is
+// scanned by column name like QueryStructs, otherwise each column is
+// scanned into the corresponding destination.
+func (r *Rows) Scan(dest ...interface{}) error {
+	if len(dest) == 1 && isStructPtr(dest[0]) {
+		return scanRow(r.Rows, dest[0], nil)
+	}
+	return r.Rows.Scan(dest...)
+}
+
+// Err returns the error which ended the iteration, ctx.Err() if it was
+// cancelled.
+func (r *Rows) Err() error {
+	return ctxErr(r.ctx, r.Rows.Err())
+}
+
+// Close closes the rows and logs the query time, which includes the time
+// spent iterating.
+func (r *Rows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	err := r.Rows.Close()
+	iterErr := r.Err()
+	r.end(iterErr, r.count)
+	logExecutionTime(r.log, r.start, r.sql, r.args, zap.Int64("rows", r.count))
+	if iterErr != nil {
+		return logSQLError(r.log, iterErr, "Rows.Close", r.sql, r.args)
+	}
+	return err
+}
+
+// isStructPtr determines if v is a pointer to a struct which is not itself
+// a scannable value such as time.Time.
+func isStructPtr(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	if _, ok := v.(sql.Scanner); ok {
+		return false
+	}
+	return t.Elem() != reflect.TypeOf(time.Time{})
+}
+
+// Iterate executes builder's query and returns the rows to be streamed
+// with constant memory, rather than loaded into a slice.
+//
+//	rows, err := DB.Select("*").From("events").Iterate()
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//	for rows.Next() {
+//		var e Event
+//		if err := rows.Scan(&e); err != nil {
+//			return err
+//		}
+//	}
+//	return rows.Err()
+func (ex *Execer) Iterate() (dat.Rows, error) {
+	return ex.IterateContext(context.Background())
+}
+
+// IterateContext is like Iterate but honours ctx cancellation, which ends
+// the iteration.
+func (ex *Execer) IterateContext(ctx context.Context) (dat.Rows, error) {
+	fullSQL, args, err := ex.Interpolate()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	qctx, end := observe(ctx, fullSQL, args)
+	rows, err := ex.database.QueryxContext(qctx, fullSQL, args...)
+	if err != nil {
+		end(err, -1)
+		logExecutionTime(ex.log(), start, fullSQL, args)
+		return nil, ctxErr(ctx, logSQLError(ex.log(), err, "IterateContext", fullSQL, args))
+	}
+
+	return &Rows{
+		Rows:  rows,
+		ctx:   ctx,
+		sql:   fullSQL,
+		args:  args,
+		start: start,
+		end:   end,
+		log:   ex.log(),
+	}, nil
+}
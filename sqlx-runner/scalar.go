@@ -0,0 +1,108 @@
+package runner
+
+import "context"
+
+// The typed scalar getters execute builder's query and scan the single
+// column of the first row. They return dat.ErrNotFound if there are no rows.
+// Scanning NULL, eg max(x) over no rows, is an error, use the Ptr variants
+// which return nil for NULL.
+
+// QueryInt64 executes builder's query and returns the int64 scalar.
+func (ex *Execer) QueryInt64() (int64, error) {
+	return ex.QueryInt64Context(context.Background())
+}
+
+// QueryInt64Context is like QueryInt64 but honours ctx cancellation.
+func (ex *Execer) QueryInt64Context(ctx context.Context) (int64, error) {
+	var v int64
+	err := ex.QueryScalarContext(ctx, &v)
+	return v, err
+}
+
+// QueryInt64Ptr executes builder's query and returns the int64 scalar, nil
+// if it is NULL.
+func (ex *Execer) QueryInt64Ptr() (*int64, error) {
+	return ex.QueryInt64PtrContext(context.Background())
+}
+
+// QueryInt64PtrContext is like QueryInt64Ptr but honours ctx cancellation.
+func (ex *Execer) QueryInt64PtrContext(ctx context.Context) (*int64, error) {
+	var v *int64
+	err := ex.QueryScalarContext(ctx, &v)
+	return v, err
+}
+
+// QueryFloat64 executes builder's query and returns the float64 scalar.
+func (ex *Execer) QueryFloat64() (float64, error) {
+	return ex.QueryFloat64Context(context.Background())
+}
+
+// QueryFloat64Context is like QueryFloat64 but honours ctx cancellation.
+func (ex *Execer) QueryFloat64Context(ctx context.Context) (float64, error) {
+	var v float64
+	err := ex.QueryScalarContext(ctx, &v)
+	return v, err
+}
+
+// QueryFloat64Ptr executes builder's query and returns the float64 scalar, nil
+// if it is NULL.
+func (ex *Execer) QueryFloat64Ptr() (*float64, error) {
+	return ex.QueryFloat64PtrContext(context.Background())
+}
+
+// QueryFloat64PtrContext is like QueryFloat64Ptr but honours ctx cancellation.
+func (ex *Execer) QueryFloat64PtrContext(ctx context.Context) (*float64, error) {
+	var v *float64
+	err := ex.QueryScalarContext(ctx, &v)
+	return v, err
+}
+
+// QueryString executes builder's query and returns the string scalar.
+func (ex *Execer) QueryString() (string, error) {
+	return ex.QueryStringContext(context.Background())
+}
+
+// QueryStringContext is like QueryString but honours ctx cancellation.
+func (ex *Execer) QueryStringContext(ctx context.Context) (string, error) {
+	var v string
+	err := ex.QueryScalarContext(ctx, &v)
+	return v, err
+}
+
+// QueryStringPtr executes builder's query and returns the string scalar, nil
+// if it is NULL.
+func (ex *Execer) QueryStringPtr() (*string, error) {
+	return ex.QueryStringPtrContext(context.Background())
+}
+
+// QueryStringPtrContext is like QueryStringPtr but honours ctx cancellation.
+func (ex *Execer) QueryStringPtrContext(ctx context.Context) (*string, error) {
+	var v *string
+	err := ex.QueryScalarContext(ctx, &v)
+	return v, err
+}
+
+// QueryBool executes builder's query and returns the bool scalar.
+func (ex *Execer) QueryBool() (bool, error) {
+	return ex.QueryBoolContext(context.Background())
+}
+
+// QueryBoolContext is like QueryBool but honours ctx cancellation.
+func (ex *Execer) QueryBoolContext(ctx context.Context) (bool, error) {
+	var v bool
+	err := ex.QueryScalarContext(ctx, &v)
+	return v, err
+}
+
+// QueryBoolPtr executes builder's query and returns the bool scalar, nil
+// if it is NULL.
+func (ex *Execer) QueryBoolPtr() (*bool, error) {
+	return ex.QueryBoolPtrContext(context.Background())
+}
+
+// QueryBoolPtrContext is like QueryBoolPtr but honours ctx cancellation.
+func (ex *Execer) QueryBoolPtrContext(ctx context.Context) (*bool, error) {
+	var v *bool
+	err := ex.QueryScalarContext(ctx, &v)
+	return v, err
+}
@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+var defaultMapper = reflectx.NewMapperFunc("db", strings.ToLower)
+
+// jsonFieldTypes caches whether a struct type has json fields.
+var jsonFieldTypes = &sync.Map{}
+
+// hasJSONFields determines if the struct type of t, which may be a pointer
+// or slice, has fields tagged `db:"name,json"`, which sqlx cannot scan.
+func hasJSONFields(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	if has, ok := jsonFieldTypes.Load(t); ok {
+		return has.(bool)
+	}
+	has := false
+	for _, fi := range defaultMapper.TypeMap(t).Index {
+		if _, ok := fi.Options["json"]; ok {
+			has = true
+			break
+		}
+	}
+	jsonFieldTypes.Store(t, has)
+	return has
+}
+
+// jsonField scans a json or jsonb column into a field tagged
+// `db:"name,json"`. NULL sets the zero value.
+type jsonField struct {
+	v reflect.Value
+}
+
+func (f jsonField) Scan(src interface{}) error {
+	var b []byte
+	switch t := src.(type) {
+	case nil:
+		f.v.Set(reflect.Zero(f.v.Type()))
+		return nil
+	case []byte:
+		b = t
+	case string:
+		b = []byte(t)
+	default:
+		return fmt.Errorf("Cannot unmarshal %T into JSON field of type %s", src, f.v.Type())
+	}
+	return json.Unmarshal(b, f.v.Addr().Interface())
+}
+
+// selectStructs scans the rows returned by query into the slice pointed to
+// by dest like SelectContext, unmarshalling json fields and scanning extra
+// columns into extras.
+func selectStructs(ctx context.Context, db database, dest interface{}, extras map[string]interface{}, query string, args []interface{}) error {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	resetExtras(extras)
+	if err := scanResult(rows, dest, extras); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// scanStruct scans the current row into the struct pointed to by dest like
+// StructScan, unmarshalling json fields. Columns in extras, eg
+// dat.InsertedColumn, are scanned into their destination.
+func scanStruct(rows resultRows, dest interface{}, extras map[string]interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a pointer to a struct, not %T", dest)
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	mapper := rowsMapper(rows)
+	tm := mapper.TypeMap(v.Type())
+	traversals := mapper.TraversalsByName(v.Type(), columns)
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		if extra, ok := extras[column]; ok {
+			values[i] = extra
+			continue
+		}
+		if len(traversals[i]) == 0 {
+			return fmt.Errorf("missing destination name %s in %T", column, dest)
+		}
+		field := reflectx.FieldByIndexes(v.Elem(), traversals[i])
+		if _, ok := tm.GetByTraversal(traversals[i]).Options["json"]; ok {
+			values[i] = jsonField{field}
+			continue
+		}
+		values[i] = field.Addr().Interface()
+	}
+	return rows.Scan(values...)
+}
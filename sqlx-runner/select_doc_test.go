@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"database/sql"
 	"encoding/json"
 	"testing"
 
@@ -86,7 +85,7 @@ func TestSelectDocNil(t *testing.T) {
 		From("people").
 		Where("id = $1", 1000).
 		QueryStruct(&person)
-	assert.Equal(sql.ErrNoRows, err)
+	assert.Equal(dat.ErrNotFound, err)
 }
 
 func TestSelectDocRows(t *testing.T) {
@@ -151,7 +150,7 @@ func TestSelectDocRowsNil(t *testing.T) {
 		Where("id in $1", []int{2000, 2001}).
 		SetIsInterpolated(true).
 		QueryStructs(&people)
-	assert.Equal(sql.ErrNoRows, err)
+	assert.Equal(dat.ErrNotFound, err)
 }
 
 func TestSelectDoc(t *testing.T) {
@@ -1,6 +1,10 @@
 package runner
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/casualjim/dat"
@@ -319,4 +323,556 @@ func TestSelectFor(t *testing.T) {
 	assert.Equal(t, int64(1), p.ID)
 }
 
+func TestSelectForUpdateSkipLocked(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var p Person
+	err := s.Select("p.*").
+		From("people p").
+		Where("p.id = $1", 1).
+		ForUpdate().
+		Of("p").
+		SkipLocked().
+		QueryStruct(&p)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), p.ID)
+}
+
+func TestSelectWithRecursive(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var ids []int64
+	err := s.
+		Select("n").
+		WithRecursive("walk(n)", `
+			SELECT $1::int
+			UNION ALL
+			SELECT n + 1 FROM walk WHERE n < $2`, 1, 4).
+		From("walk").
+		Where("n > $1", 1).
+		QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 4}, ids)
+
+	var names []string
+	err = s.
+		Select("name").
+		With("mario", dat.Select("id").From("people").Where("name = $1", "Mario")).
+		From("people").
+		Where("id IN (SELECT id FROM mario)").
+		QuerySlice(&names)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Mario"}, names)
+}
+
+func TestSelectArrayConditions(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var ids []int64
+	err := s.Select("id").From("people").Where(dat.Any("id", []int64{2, 3})).OrderBy("id").QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, ids)
+
+	ids = nil
+	err = s.Select("id").From("people").Where(dat.Any("id", []int64{})).QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	ids = nil
+	err = s.Select("id").
+		From("people").
+		Where(dat.ArrayOverlap("ARRAY[id, id + 10]::bigint[]", []int64{12})).
+		Where(dat.All("ARRAY[id]", 2)).
+		QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids)
+}
+
+func TestSelectInSubquery(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var names []string
+	err := s.Select("name").
+		From("people").
+		Where(dat.In("id", dat.Select("user_id").From("posts").Where("title = $1", "Apple"))).
+		Where(dat.NotExists(dat.Select("1").From("comments").Where("comments.user_id = people.id AND comments.post_id = $1", 1))).
+		QuerySlice(&names)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"John"}, names)
+}
+
+func TestToSQLRoundTripWithSqlx(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	b := dat.Select("id", "name").
+		From("people").
+		Where("id > $1", 1).
+		Where(dat.In("id", dat.Select("user_id").From("posts").Where("state = $1", "published"))).
+		OrderBy("id").
+		LimitPlaceholder(5)
+	sql, args, err := dat.ToSQL(b)
+	assert.NoError(t, err)
+
+	var expected, actual []Person
+	err = s.Tx.Select(&actual, sql, args...)
+	assert.NoError(t, err)
+	err = s.Select("id", "name").
+		From("people").
+		Where("id > $1", 1).
+		Where(dat.In("id", dat.Select("user_id").From("posts").Where("state = $1", "published"))).
+		OrderBy("id").
+		LimitPlaceholder(5).
+		QueryStructs(&expected)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Len(t, actual, 1)
+}
+
+func TestSelectQueryMaps(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var m map[string]interface{}
+	err := s.SQL(`
+		SELECT id, name, 1.5::numeric AS amount, '{"a": 1}'::jsonb AS doc, '\x01'::bytea AS image, true AS ok, null AS missing
+		FROM people WHERE id = $1`, 1).QueryMap(&m)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), m["id"])
+	assert.Equal(t, "Mario", m["name"])
+	assert.Equal(t, 1.5, m["amount"])
+	assert.Equal(t, dat.JSON(`{"a": 1}`), m["doc"])
+	assert.Equal(t, []byte{1}, m["image"])
+	assert.Equal(t, true, m["ok"])
+	assert.Nil(t, m["missing"])
+
+	err = s.SQL("SELECT id FROM people WHERE id = $1", 1000).QueryMap(&m)
+	assert.Equal(t, dat.ErrNotFound, err)
+
+	var maps []map[string]interface{}
+	err = s.Select("id", "name").From("people").Where("id < $1", 3).OrderBy("id").QueryMaps(&maps)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "name": "Mario"},
+		{"id": int64(2), "name": "John"},
+	}, maps)
+}
+
+func TestSelectHavingArgs(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.InsertInto("posts").
+		Columns("user_id", "title", "state").
+		Values(1, "Day 3", "published").
+		Exec()
+	assert.NoError(t, err)
+
+	var userIDs []int64
+	err = s.Select("user_id").
+		From("posts").
+		Where("deleted_at IS NULL AND title <> $1", "").
+		GroupBy("user_id").
+		Having("count(*) > $1", 2).
+		OrderBy("user_id").
+		QuerySlice(&userIDs)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, userIDs)
+}
+
+func TestSelectUnionAll(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var titles []string
+	err := s.Select("title").
+		From("posts").
+		Where("state = $1", "published").
+		UnionAll(dat.Select("comment").From("comments").Where("user_id = $1", 2)).
+		OrderBy("title").
+		Limit(3).
+		QuerySlice(&titles)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Apple", "Day 1", "Yum. Apple pie."}, titles)
+}
+
+func TestSelectIterate(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	rows, err := s.Select("id", "name").From("people").OrderBy("id").Iterate()
+	assert.NoError(t, err)
+	var names []string
+	for rows.Next() {
+		var p Person
+		assert.NoError(t, rows.Scan(&p))
+		names = append(names, p.Name)
+	}
+	assert.NoError(t, rows.Err())
+	assert.NoError(t, rows.Close())
+	assert.Equal(t, []string{"Mario", "John", "Grant", "Tony", "Ester", "Reggie"}, names)
+
+	rows, err = s.Select("id", "name").From("people").Where("id = $1", 2).Iterate()
+	assert.NoError(t, err)
+	defer rows.Close()
+	assert.True(t, rows.Next())
+	var id int64
+	var name string
+	assert.NoError(t, rows.Scan(&id, &name))
+	assert.EqualValues(t, 2, id)
+	assert.Equal(t, "John", name)
+	assert.False(t, rows.Next())
+}
+
+func TestSelectIterateCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows, err := testDB.SQL("SELECT generate_series(1, 1000000)").IterateContext(ctx)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		n++
+		if n == 10 {
+			cancel()
+		}
+	}
+	assert.True(t, n < 1000000)
+	assert.Equal(t, context.Canceled, rows.Err())
+}
+
+func TestSelectJoinReal(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var titles []string
+	err := s.Select("p.title").
+		From("people u").
+		Join("posts p", "p.user_id = u.id AND p.title <> $1", "Orange").
+		Where("u.name = $1", "John").
+		OrderBy("p.id").
+		QuerySlice(&titles)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Apple"}, titles)
+
+	var n int
+	err = s.Select("count(*)").
+		From("people u").
+		LeftJoin("posts p", "p.user_id = u.id").
+		Where("p.id IS NULL").
+		QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+}
+
+func TestSelectJoinLateralReal(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	latest := dat.Select("title").
+		From("posts").
+		Where("user_id = u.id AND title <> $1", "Orange").
+		OrderBy("id DESC").
+		Limit(1)
+
+	var titles []string
+	err := s.Select("p.title").
+		From("people u").
+		JoinLateral(latest, "p", "").
+		Where("u.id < $1", 3).
+		OrderBy("u.id").
+		QuerySlice(&titles)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Day 2", "Apple"}, titles)
+}
+
+func TestSelectFromDerivedReal(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	counts := dat.Select("user_id", "count(*) AS n").
+		From("posts").
+		Where("title <> $1", "Orange").
+		GroupBy("user_id")
+
+	var ids []int64
+	err := s.Select("t.user_id").
+		FromDerived(counts, "t").
+		Where("t.n > $1", 1).
+		QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, ids)
+}
+
+func TestSelectHstore(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.SQL("CREATE EXTENSION IF NOT EXISTS hstore").Exec()
+	assert.NoError(t, err)
+	_, err = s.SQL("ALTER TABLE people ADD COLUMN meta hstore").Exec()
+	assert.NoError(t, err)
+
+	meta := dat.Hstore{"color": "red", "quote": `"it's"`}
+	_, err = s.Update("people").Set("meta", meta).Where("id = $1", 1).Exec()
+	assert.NoError(t, err)
+
+	var scanned dat.Hstore
+	err = s.Select("meta").
+		From("people").
+		Where(dat.HstoreGet("meta", "color")+" = $1", "red").
+		Where(dat.HstoreHasKey("meta", "quote")).
+		QueryScalar(&scanned)
+	assert.NoError(t, err)
+	assert.Equal(t, meta, scanned)
+}
+
+func TestSelectRange(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.SQL("ALTER TABLE posts ADD COLUMN pages int4range").Exec()
+	assert.NoError(t, err)
+	_, err = s.Update("posts").Set("pages", dat.Range(1, 10, "[)")).Where("id = $1", 1).Exec()
+	assert.NoError(t, err)
+	_, err = s.Update("posts").Set("pages", dat.Range(20, nil, "[)")).Where("id = $1", 2).Exec()
+	assert.NoError(t, err)
+
+	var ids []int64
+	err = s.Select("id").From("posts").Where(dat.RangeOverlap("pages", dat.Range(5, 25, "[]"))).OrderBy("id").QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+
+	var r dat.RangeValue
+	err = s.Select("pages").From("posts").Where(dat.RangeContains("pages", dat.Range(30, 40, "[)"))).QueryScalar(&r)
+	assert.NoError(t, err)
+	assert.Equal(t, dat.RangeValue{Lower: "20", LowerInc: true}, r)
+}
+
+func TestSelectTypedScalars(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	n, err := s.Select("count(*)").From("people").QueryInt64()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 6, n)
+
+	f, err := s.SQL("SELECT avg(id) FROM people").QueryFloat64()
+	assert.NoError(t, err)
+	assert.Equal(t, 3.5, f)
+
+	name, err := s.Select("name").From("people").Where("id = $1", 1).QueryString()
+	assert.NoError(t, err)
+	assert.Equal(t, "Mario", name)
+
+	ok, err := s.SQL("SELECT EXISTS (SELECT 1 FROM people WHERE name = $1)", "John").QueryBool()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// no rows
+	_, err = s.Select("name").From("people").Where("id = $1", 1000).QueryString()
+	assert.Exactly(t, dat.ErrNotFound, err)
+
+	// NULL
+	_, err = s.Select("max(id)").From("people").Where("id > $1", 1000).QueryInt64()
+	assert.Error(t, err)
+	highest, err := s.Select("max(id)").From("people").Where("id > $1", 1000).QueryInt64Ptr()
+	assert.NoError(t, err)
+	assert.Nil(t, highest)
+	highest, err = s.Select("max(id)").From("people").QueryInt64Ptr()
+	assert.NoError(t, err)
+	if assert.NotNil(t, highest) {
+		assert.EqualValues(t, 6, *highest)
+	}
+}
+
+func TestSelectExpectRows(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var people []*Person
+	err := s.Select("id", "name").From("people").OrderBy("id").Limit(4).QueryStructs(&people)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(people))
+	assert.Equal(t, 4, cap(people))
+
+	people = nil
+	err = s.Select("id", "name").From("people").OrderBy("id").ExpectRows(10).QueryStructs(&people)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(people))
+	assert.Equal(t, 10, cap(people))
+
+	var ids []int64
+	err = s.Select("id").From("people").Limit(1000000).QuerySlice(&ids)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(ids))
+	assert.Equal(t, maxLimitRows, cap(ids))
+}
+
+func TestSelectUseArrayBinding(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	for _, interpolate := range []bool{true, false} {
+		dat.EnableInterpolation = interpolate
+		var names []string
+		err := s.Select("name").
+			From("people").
+			Where(dat.Eq{"id": []int64{1, 3, 5}}).
+			Where("name <> ALL($1)", []string{"Grant"}).
+			OrderBy("id").
+			UseArrayBinding(true).
+			QuerySlice(&names)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Mario", "Ester"}, names)
+	}
+	dat.EnableInterpolation = false
+}
+
+func TestSelectExplain(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	plan, err := s.Select("id", "name").From("people").Where("id = $1", 1).Explain(false)
+	assert.NoError(t, err)
+	assert.Contains(t, plan, "people")
+	assert.NotContains(t, plan, "actual time")
+
+	plan, err = s.Select("id", "name").From("people").Where("id = $1", 1).Explain(true)
+	assert.NoError(t, err)
+	assert.Contains(t, plan, "actual time")
+
+	b, err := s.Select("id").From("people").ExplainJSON(true)
+	assert.NoError(t, err)
+	var explained []struct {
+		Plan map[string]interface{}
+	}
+	assert.NoError(t, json.Unmarshal(b, &explained))
+	assert.Equal(t, 1, len(explained))
+	assert.Equal(t, "people", explained[0].Plan["Relation Name"])
+	assert.EqualValues(t, 6, explained[0].Plan["Actual Rows"])
+
+	_, err = s.Select("id").From("nonexistent").Explain(false)
+	assert.Error(t, err)
+}
+
+func TestSelectQueryJSONAgg(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var raw json.RawMessage
+	err := s.Select("id", "name").From("people").Where("id < $1", 3).OrderBy("id").QueryJSONAgg(&raw)
+	assert.NoError(t, err)
+	var people []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(raw, &people))
+	if assert.Equal(t, 2, len(people)) {
+		assert.Equal(t, "Mario", people[0]["name"])
+		assert.EqualValues(t, 2, people[1]["id"])
+	}
+
+	// no rows is an empty array
+	err = s.Select("id").From("people").Where("id = $1", -1).QueryJSONAgg(&raw)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(raw))
+
+	err = s.SelectDoc("id", "name").From("people").Where("id = $1", -1).QueryJSONAgg(&raw)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(raw))
+}
+
+func TestSelectSeek(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var names []string
+	var last interface{}
+	for page := 0; page < 5; page++ {
+		var people []*Person
+		b := s.Select("id", "name").From("people").Seek("name, id", last, dat.After).Limit(4)
+		err := b.QueryStructs(&people)
+		assert.NoError(t, err)
+		for _, p := range people {
+			names = append(names, p.Name)
+		}
+		last, err = b.NextSeek(people)
+		assert.NoError(t, err)
+		if last == nil {
+			break
+		}
+	}
+	assert.Equal(t, []string{"Ester", "Grant", "John", "Mario", "Reggie", "Tony"}, names)
+
+	var people []*Person
+	err := s.Select("id", "name").From("people").Seek("id", 3, dat.Before).QueryStructs(&people)
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(people)) {
+		assert.Equal(t, "John", people[0].Name)
+		assert.Equal(t, "Mario", people[1].Name)
+	}
+}
+
+func TestSelectWithTotalCount(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var total int64
+	var people []*Person
+	err := s.Select("id", "name").From("people").OrderBy("id").WithTotalCount(&total).Paginate(1, 2).QueryStructs(&people)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 6, total)
+	if assert.Equal(t, 2, len(people)) {
+		assert.Equal(t, "Mario", people[0].Name)
+	}
+
+	var names []string
+	total = 0
+	err = s.Select("name").From("people").Where("id > $1", 2).OrderBy("id").WithTotalCount(&total).Limit(1).QuerySlice(&names)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, total)
+	assert.Equal(t, []string{"Grant"}, names)
+
+	var person Person
+	total = 0
+	err = s.Select("id", "name").From("people").Where("id = $1", 4).WithTotalCount(&total).QueryStruct(&person)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Equal(t, "Tony", person.Name)
+
+	// a page past the end has no total
+	people = nil
+	err = s.Select("id", "name").From("people").WithTotalCount(&total).Paginate(9, 2).QueryStructs(&people)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+	assert.Empty(t, people)
+}
+
+func TestSelectQueryStructOrNil(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var person *Person
+	err := s.Select("id", "name").From("people").Where("id = $1", 1).QueryStructOrNil(&person)
+	assert.NoError(t, err)
+	if assert.NotNil(t, person) {
+		assert.Equal(t, "Mario", person.Name)
+	}
+
+	err = s.Select("id", "name").From("people").Where("id = $1", 1000).QueryStructOrNil(&person)
+	assert.NoError(t, err)
+	assert.Nil(t, person)
+
+	var p Person
+	err = s.Select("id", "name").From("people").Where("id = $1", 1000).QueryStruct(&p)
+	assert.Exactly(t, dat.ErrNotFound, err)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+
+	err = s.Select("id", "name").From("people").QueryStructOrNil(&p)
+	assert.Error(t, err)
+}
+
 // Series of tests that test mapping struct fields to columns
@@ -0,0 +1,171 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hash/fnv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNoShardKey occurs when the shard of a query run on a ShardedDB cannot
+// be determined.
+var ErrNoShardKey = errors.New("Shard key not found, use OnShard")
+
+// ErrCrossShard occurs when a query for another shard is run within a
+// sharded transaction.
+var ErrCrossShard = errors.New("Query for another shard within a sharded transaction")
+
+// ShardKeyFunc extracts the shard key from a query, returning false if the
+// query has none. sql and args are as sent to the database, args are empty
+// if the query is interpolated.
+type ShardKeyFunc func(sql string, args []interface{}) (string, bool)
+
+// ShardedDB routes queries to one of several databases by shard key, eg a
+// tenant ID. The builders of ShardedDB run on the shard of the key extracted
+// from the query by Key, use OnShard to supply the key.
+type ShardedDB struct {
+	*Queryable
+
+	// Key extracts the shard key of a query, nil requires OnShard.
+	Key ShardKeyFunc
+
+	// Locate returns the index of the shard for key. The default is the
+	// FNV-1a hash of key modulo the number of shards.
+	Locate func(key string) int
+
+	shards []*DB
+}
+
+// NewShardedDB creates a ShardedDB routing queries to shards with key.
+func NewShardedDB(key ShardKeyFunc, shards ...*DB) *ShardedDB {
+	if len(shards) == 0 {
+		panic("NewShardedDB requires at least one shard")
+	}
+	s := &ShardedDB{Key: key, shards: shards}
+	s.Queryable = &Queryable{runner: &shardRouter{sharded: s, pinned: -1}}
+	return s
+}
+
+// Shards returns the databases of the shards.
+func (s *ShardedDB) Shards() []*DB {
+	return s.shards
+}
+
+// OnShard returns the database of the shard for key.
+func (s *ShardedDB) OnShard(key string) *DB {
+	return s.shards[s.locate(key)]
+}
+
+// Begin creates a transaction on the shard for key. Its queries fail with
+// ErrCrossShard if Key extracts the key of another shard.
+func (s *ShardedDB) Begin(key string) (*Tx, error) {
+	return s.BeginTx(context.Background(), key, nil)
+}
+
+// BeginTx is like Begin with options such as the isolation level.
+func (s *ShardedDB) BeginTx(ctx context.Context, key string, opts *sql.TxOptions) (*Tx, error) {
+	i := s.locate(key)
+	tx, err := s.shards[i].BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	tx.Queryable.runner = &shardRouter{sharded: s, pinned: i, database: tx.Queryable.runner}
+	return tx, nil
+}
+
+func (s *ShardedDB) locate(key string) int {
+	if s.Locate != nil {
+		return s.Locate(key)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(s.shards)))
+}
+
+// shardRouter is a database which runs each statement on the shard of its
+// key. When pinned to a shard it runs statements on database, failing those
+// for other shards.
+type shardRouter struct {
+	sharded  *ShardedDB
+	pinned   int
+	database database
+}
+
+// route returns the database to run query on.
+func (r *shardRouter) route(query string, args []interface{}) (database, error) {
+	var key string
+	ok := false
+	if r.sharded.Key != nil {
+		key, ok = r.sharded.Key(query, args)
+	}
+	if r.pinned >= 0 {
+		if ok && r.sharded.locate(key) != r.pinned {
+			return nil, ErrCrossShard
+		}
+		return r.database, nil
+	}
+	if !ok {
+		return nil, ErrNoShardKey
+	}
+	return r.sharded.OnShard(key).Queryable.runner, nil
+}
+
+func (r *shardRouter) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.ExecContext(context.Background(), query, args...)
+}
+
+func (r *shardRouter) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	db, err := r.route(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+func (r *shardRouter) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return r.QueryxContext(context.Background(), query, args...)
+}
+
+func (r *shardRouter) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	db, err := r.route(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryxContext(ctx, query, args...)
+}
+
+// QueryRowx panics if the shard cannot be determined since sqlx.Row cannot
+// carry the error.
+func (r *shardRouter) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	db, err := r.route(query, args)
+	if err != nil {
+		panic(err)
+	}
+	return db.QueryRowx(query, args...)
+}
+
+func (r *shardRouter) Select(dest interface{}, query string, args ...interface{}) error {
+	return r.SelectContext(context.Background(), dest, query, args...)
+}
+
+func (r *shardRouter) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	db, err := r.route(query, args)
+	if err != nil {
+		return err
+	}
+	return db.SelectContext(ctx, dest, query, args...)
+}
+
+func (r *shardRouter) Get(dest interface{}, query string, args ...interface{}) error {
+	return r.GetContext(context.Background(), dest, query, args...)
+}
+
+func (r *shardRouter) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	db, err := r.route(query, args)
+	if err != nil {
+		return err
+	}
+	return db.GetContext(ctx, dest, query, args...)
+}
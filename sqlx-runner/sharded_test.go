@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/casualjim/dat"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestShardedDB() *ShardedDB {
+	// the first arg of a query is the shard key
+	key := func(sql string, args []interface{}) (string, bool) {
+		if len(args) == 0 {
+			return "", false
+		}
+		return fmt.Sprint(args[0]), true
+	}
+	s := NewShardedDB(key,
+		NewDBFromSqlx(sqlx.NewDb(testDB.SQLDB(), "postgres")),
+		NewDBFromSqlx(sqlx.NewDb(testDB.SQLDB(), "postgres")))
+	s.Locate = func(key string) int {
+		if key == "1" || key == "2" {
+			return 0
+		}
+		return 1
+	}
+	return s
+}
+
+func TestShardedDBRouting(t *testing.T) {
+	installFixtures()
+	s := newTestShardedDB()
+
+	assert.True(t, s.OnShard("1") == s.Shards()[0])
+	assert.True(t, s.OnShard("3") == s.Shards()[1])
+
+	var name string
+	err := s.Select("name").From("people").Where("id = $1", 3).QueryScalar(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "Grant", name)
+
+	err = s.OnShard("1").SQL("SELECT name FROM people WHERE id = 1").QueryScalar(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mario", name)
+
+	err = s.SQL("SELECT name FROM people WHERE id = 1").QueryScalar(&name)
+	assert.Equal(t, ErrNoShardKey, dat.Cause(err))
+}
+
+func TestShardedDBTx(t *testing.T) {
+	installFixtures()
+	s := newTestShardedDB()
+
+	tx, err := s.Begin("1")
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	var name string
+	err = tx.Select("name").From("people").Where("id = $1", 2).QueryScalar(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "John", name)
+
+	// queries without a key run on the pinned shard
+	err = tx.SQL("SELECT name FROM people WHERE id = 1").QueryScalar(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mario", name)
+
+	_, err = tx.DeleteFrom("people").Where("id = $1", 3).Exec()
+	assert.Equal(t, ErrCrossShard, dat.Cause(err))
+}
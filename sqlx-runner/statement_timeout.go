@@ -0,0 +1,42 @@
+package runner
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statementTimeoutMillis converts d to the milliseconds of statement_timeout,
+// rounding a positive d up to 1ms since 0 disables the timeout.
+func statementTimeoutMillis(d time.Duration) int64 {
+	ms := d.Nanoseconds() / int64(time.Millisecond)
+	if ms == 0 && d > 0 {
+		ms = 1
+	}
+	return ms
+}
+
+// WithStatementTimeout returns connString, a URL or key/value connection
+// string of lib/pq or pgx, with the statement_timeout run-time parameter
+// set to d. Each connection of a DB opened with it makes the server abort
+// any statement running longer than d with SQLSTATE 57014 query_canceled,
+// within transactions or not.
+//
+//	dsn, err := runner.WithStatementTimeout(dsn, 5*time.Second)
+//	DB := runner.NewDBFromString("postgres", dsn)
+func WithStatementTimeout(connString string, d time.Duration) (string, error) {
+	ms := strconv.FormatInt(statementTimeoutMillis(d), 10)
+	if strings.HasPrefix(connString, "postgres://") || strings.HasPrefix(connString, "postgresql://") {
+		u, err := url.Parse(connString)
+		if err != nil {
+			return "", err
+		}
+		q := u.Query()
+		q.Set("statement_timeout", ms)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	// the last occurrence of a key wins
+	return strings.TrimSpace(connString + " statement_timeout=" + ms), nil
+}
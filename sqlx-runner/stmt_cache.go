@@ -0,0 +1,189 @@
+package runner
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/casualjim/dat"
+	"github.com/jmoiron/sqlx"
+)
+
+// SetPreparedStatementCache enables a cache of up to size prepared
+// statements keyed by SQL text. Statements with arguments are prepared the
+// first time they are executed and reused afterwards, the least recently
+// used statement is closed when the cache is full. A size <= 0 disables the
+// cache. Statements executed within a Tx are not cached.
+//
+// Call this when setting up the connection, not while queries are running.
+func (db *DB) SetPreparedStatementCache(size int) {
+	if sc, ok := db.Queryable.runner.(*stmtCacheDB); ok {
+		sc.close()
+	}
+	if size <= 0 {
+		db.Queryable.runner = db.DB
+		return
+	}
+	db.Queryable.runner = newStmtCacheDB(db.DB, size)
+}
+
+// stmtCacheDB is a database which executes statements having arguments with
+// cached prepared statements.
+type stmtCacheDB struct {
+	*sqlx.DB
+
+	mu    sync.Mutex
+	size  int
+	lru   *list.List
+	stmts map[string]*list.Element
+}
+
+type stmtEntry struct {
+	query string
+	stmt  *sqlx.Stmt
+}
+
+func newStmtCacheDB(db *sqlx.DB, size int) *stmtCacheDB {
+	return &stmtCacheDB{
+		DB:    db,
+		size:  size,
+		lru:   list.New(),
+		stmts: make(map[string]*list.Element, size),
+	}
+}
+
+// prepare returns the cached statement for query, preparing it when needed.
+func (c *stmtCacheDB) prepare(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.stmts[query]; ok {
+		c.lru.MoveToFront(el)
+		return el.Value.(*stmtEntry).stmt, nil
+	}
+
+	stmt, err := c.DB.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = c.lru.PushFront(&stmtEntry{query: query, stmt: stmt})
+
+	for c.lru.Len() > c.size {
+		c.removeElement(c.lru.Back())
+	}
+	return stmt, nil
+}
+
+// evict removes the statement for query if it is still stmt.
+func (c *stmtCacheDB) evict(query string, stmt *sqlx.Stmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.stmts[query]; ok && el.Value.(*stmtEntry).stmt == stmt {
+		c.removeElement(el)
+	}
+}
+
+func (c *stmtCacheDB) removeElement(el *list.Element) {
+	entry := c.lru.Remove(el).(*stmtEntry)
+	delete(c.stmts, entry.query)
+	entry.stmt.Close()
+}
+
+func (c *stmtCacheDB) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.lru.Len() > 0 {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// isStmtInvalid determines if a prepared statement must be prepared again
+// because it no longer exists on the server (invalid_sql_statement_name), the
+// connection was dropped or the statement was evicted while in use.
+func isStmtInvalid(err error) bool {
+	if pe, ok := dat.AsPgError(err); ok {
+		return pe.Code == "26000"
+	}
+	return err == driver.ErrBadConn || (err != nil && err.Error() == "sql: statement is closed")
+}
+
+// withStmt calls fn with the cached statement for query, preparing it again
+// once if it has become invalid.
+func (c *stmtCacheDB) withStmt(ctx context.Context, query string, fn func(*sqlx.Stmt) error) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var stmt *sqlx.Stmt
+		stmt, err = c.prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+		err = fn(stmt)
+		if !isStmtInvalid(err) {
+			return err
+		}
+		c.evict(query, stmt)
+	}
+	return err
+}
+
+func (c *stmtCacheDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.ExecContext(context.Background(), query, args...)
+}
+
+func (c *stmtCacheDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if len(args) == 0 {
+		return c.DB.ExecContext(ctx, query)
+	}
+	var result sql.Result
+	err := c.withStmt(ctx, query, func(stmt *sqlx.Stmt) (err error) {
+		result, err = stmt.ExecContext(ctx, args...)
+		return err
+	})
+	return result, err
+}
+
+func (c *stmtCacheDB) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return c.QueryxContext(context.Background(), query, args...)
+}
+
+func (c *stmtCacheDB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	if len(args) == 0 {
+		return c.DB.QueryxContext(ctx, query)
+	}
+	var rows *sqlx.Rows
+	err := c.withStmt(ctx, query, func(stmt *sqlx.Stmt) (err error) {
+		rows, err = stmt.QueryxContext(ctx, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (c *stmtCacheDB) Select(dest interface{}, query string, args ...interface{}) error {
+	return c.SelectContext(context.Background(), dest, query, args...)
+}
+
+func (c *stmtCacheDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if len(args) == 0 {
+		return c.DB.SelectContext(ctx, dest, query)
+	}
+	return c.withStmt(ctx, query, func(stmt *sqlx.Stmt) error {
+		return stmt.SelectContext(ctx, dest, args...)
+	})
+}
+
+func (c *stmtCacheDB) Get(dest interface{}, query string, args ...interface{}) error {
+	return c.GetContext(context.Background(), dest, query, args...)
+}
+
+func (c *stmtCacheDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if len(args) == 0 {
+		return c.DB.GetContext(ctx, dest, query)
+	}
+	return c.withStmt(ctx, query, func(stmt *sqlx.Stmt) error {
+		return stmt.GetContext(ctx, dest, args...)
+	})
+}
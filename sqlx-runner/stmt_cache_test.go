@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreparedStatementCache(t *testing.T) {
+	installFixtures()
+
+	db := NewDB(realDb(), "postgres")
+	defer db.DB.Close()
+	db.DB.SetMaxOpenConns(1)
+	db.SetPreparedStatementCache(2)
+	sc := db.Queryable.runner.(*stmtCacheDB)
+
+	var name string
+	for i := 0; i < 3; i++ {
+		err := db.SQL("SELECT name FROM people WHERE id = $1", 1).QueryScalar(&name)
+		assert.NoError(t, err)
+		assert.Equal(t, "Mario", name)
+	}
+	assert.Equal(t, 1, sc.lru.Len())
+
+	// statements without args are not prepared
+	var n int
+	err := db.SQL("SELECT 1").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sc.lru.Len())
+
+	// least recently used is evicted
+	var people []Person
+	err = db.Select("*").From("people").Where("id > $1", 1).QueryStructs(&people)
+	assert.NoError(t, err)
+	_, err = db.Update("people").Set("name", "Mario").Where("id = $1", 1).Exec()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sc.lru.Len())
+	_, ok := sc.stmts["SELECT name FROM people WHERE id = $1"]
+	assert.False(t, ok)
+
+	// statements dropped on the server are prepared again
+	_, err = db.Exec("DEALLOCATE ALL")
+	assert.NoError(t, err)
+	_, err = db.Update("people").Set("name", "Mario").Where("id = $1", 1).Exec()
+	assert.NoError(t, err)
+
+	db.SetPreparedStatementCache(0)
+	assert.Equal(t, 0, sc.lru.Len())
+	assert.Equal(t, db.DB, db.Queryable.runner)
+}
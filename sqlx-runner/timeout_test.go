@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -101,3 +102,26 @@ func TestTimeoutJSON(t *testing.T) {
 	assert.Equal(t, "john", obj.AsString("[0].name"))
 	assert.Equal(t, 10, obj.AsInt("[0].age"))
 }
+
+func TestDefaultQueryTimeout(t *testing.T) {
+	testDB.SetQueryTimeout(10 * time.Millisecond)
+	defer testDB.SetQueryTimeout(0)
+
+	_, err := testDB.SQL("SELECT pg_sleep(1)").Exec()
+	assert.Equal(t, dat.ErrTimedout, err)
+
+	_, err = testDB.Exec("SELECT pg_sleep(1)")
+	assert.Equal(t, dat.ErrTimedout, err)
+
+	// per query timeout overrides the default
+	_, err = testDB.SQL("SELECT pg_sleep(0.1)").Timeout(1 * time.Second).Exec()
+	assert.NoError(t, err)
+
+	// so does the deadline of ctx
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	_, err = testDB.SQL("SELECT pg_sleep(0.1)").ExecContext(ctx)
+	assert.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, "SELECT pg_sleep(0.1)")
+	assert.NoError(t, err)
+}
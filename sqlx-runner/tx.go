@@ -1,8 +1,13 @@
 package runner
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"runtime"
+	"strconv"
 	"sync"
 	"time"
 
@@ -11,13 +16,35 @@ import (
 	"go.uber.org/zap"
 )
 
+// TxState is the state of a transaction.
+type TxState int
+
 const (
-	txPending = iota
-	txCommitted
-	txRollbacked
-	txErred
+	// TxPending is a transaction which has neither been committed nor rolled back.
+	TxPending TxState = iota
+	// TxCommitted is a committed transaction.
+	TxCommitted
+	// TxRollbacked is a rolled back transaction.
+	TxRollbacked
+	// TxErred is a transaction whose commit or rollback failed.
+	TxErred
 )
 
+// String returns the name of the state.
+func (s TxState) String() string {
+	switch s {
+	case TxPending:
+		return "pending"
+	case TxCommitted:
+		return "committed"
+	case TxRollbacked:
+		return "rollbacked"
+	case TxErred:
+		return "erred"
+	}
+	return "TxState(" + strconv.Itoa(int(s)) + ")"
+}
+
 // ErrTxRollbacked occurs when Commit() or Rollback() is called on a
 // transaction that has already been rollbacked.
 var ErrTxRollbacked = errors.New("Nested transaction already rolled back")
@@ -28,38 +55,145 @@ type Tx struct {
 	*sqlx.Tx
 	*Queryable
 	IsRollbacked bool
-	state        int
-	stateStack   []int
+	state        TxState
+	stateStack   []TxState
+
+	// release releases the transaction from the DB it was begun from
+	release func()
 }
 
 // WrapSqlxTx creates a Tx from a sqlx.Tx
 func WrapSqlxTx(tx *sqlx.Tx) *Tx {
-	newtx := &Tx{Tx: tx, Queryable: &Queryable{tx}}
+	return wrapSqlxTx(tx, 1)
+}
+
+// wrapSqlxTx creates a Tx from a sqlx.Tx, skip is the number of callers to
+// skip when capturing the call site for LeakWarnTimeout.
+func wrapSqlxTx(tx *sqlx.Tx, skip int) *Tx {
+	newtx := &Tx{Tx: tx, Queryable: &Queryable{runner: tx, txID: newTxID()}}
 	if dat.Strict {
-		time.AfterFunc(1*time.Minute, func() {
-			if !newtx.IsRollbacked && newtx.state == txPending {
+		// detect a transaction leaked across goroutines
+		newtx.Queryable.runner = newGuardedTx(tx)
+	}
+	if dat.Strict && StrictLeakTimeout > 0 {
+		time.AfterFunc(StrictLeakTimeout, func() {
+			if !newtx.IsRollbacked && newtx.state == TxPending {
 				panic("A database transaction was not closed!")
 			}
 		})
 	}
+	if LeakWarnTimeout > 0 {
+		timeout := LeakWarnTimeout
+		caller, stack := callerStack(skip + 1)
+		time.AfterFunc(timeout, func() {
+			if newtx.State() == TxPending && !newtx.IsRollbacked {
+				newtx.log().Warn("transaction held open",
+					zap.Duration("timeout", timeout),
+					zap.String("caller", caller),
+					zap.String("stack", stack))
+			}
+		})
+	}
 	return newtx
 }
 
+// callerStack returns the file:line of the caller skip frames above the
+// caller of callerStack and the stack from there.
+func callerStack(skip int) (string, string) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var caller string
+	var buf bytes.Buffer
+	for {
+		frame, more := frames.Next()
+		if caller == "" {
+			caller = frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		fmt.Fprintf(&buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return caller, buf.String()
+}
+
 // Begin creates a transaction for the given database
 func (db *DB) Begin() (*Tx, error) {
+	if err := db.txs.acquire(); err != nil {
+		return nil, err
+	}
 	tx, err := db.DB.Beginx()
 	if err != nil {
+		db.txs.release()
 		if dat.Strict {
-			logger.Fatal("Could not create transaction")
+			logger().Fatal("Could not create transaction")
 		}
-		logger.Error("begin.error", zap.Error(err))
+		logger().Error("begin.error", zap.Error(err))
 		return nil, err
 	}
-	logger.Debug("begin tx")
-	return WrapSqlxTx(tx), nil
+	newtx := wrapSqlxTx(tx, 1)
+	db.trackTx(context.Background(), newtx)
+	newtx.log().Debug("begin tx")
+	if err := db.setStatementTimeout(newtx); err != nil {
+		return nil, err
+	}
+	return newtx, nil
 }
 
-// Begin returns this transaction
+// BeginTx creates a transaction for the given database with options such as
+// the isolation level. opts.ReadOnly results in a READ ONLY Postgres
+// transaction. The transaction is rolled back by database/sql if ctx is done
+// before it is committed.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if err := db.txs.acquire(); err != nil {
+		return nil, err
+	}
+	tx, err := db.DB.BeginTxx(ctx, opts)
+	if err != nil {
+		db.txs.release()
+		if dat.Strict {
+			logger().Fatal("Could not create transaction")
+		}
+		logger().Error("begin.error", zap.Error(err))
+		return nil, err
+	}
+	newtx := wrapSqlxTx(tx, 1)
+	db.trackTx(ctx, newtx)
+	newtx.log().Debug("begin tx")
+	if err := db.setStatementTimeout(newtx); err != nil {
+		return nil, err
+	}
+	return newtx, nil
+}
+
+// WithTransaction begins a transaction and calls fn. The transaction is
+// committed if fn returns nil and rolled back if fn returns an error. If fn
+// panics, the transaction is rolled back before re-panicking so half-applied
+// work is never committed.
+func (db *DB) WithTransaction(fn func(tx *Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.AutoRollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.AutoRollback()
+		return err
+	}
+	return tx.AutoCommit()
+}
+
+// Begin returns this transaction after creating a SAVEPOINT. A nested
+// Rollback rolls back to the savepoint leaving the outer transaction
+// intact.
 func (tx *Tx) Begin() (*Tx, error) {
 	tx.Lock()
 	defer tx.Unlock()
@@ -67,134 +201,303 @@ func (tx *Tx) Begin() (*Tx, error) {
 		return nil, ErrTxRollbacked
 	}
 
-	logger.Debug("begin nested tx")
+	tx.log().Debug("begin nested tx")
 	tx.pushState()
+	if err := tx.execSavepoint(context.Background(), "SAVEPOINT "); err != nil {
+		tx.popState()
+		tx.log().Error("begin.savepoint_error", zap.Error(err))
+		return nil, err
+	}
 	return tx, nil
 }
 
 // Commit commits the transaction
 func (tx *Tx) Commit() error {
+	return tx.CommitContext(context.Background())
+}
+
+// CommitContext commits the transaction unless ctx is already done, in
+// which case ctx.Err() is returned as is so callers can tell a timeout apart
+// from a driver error. Once sent, the commit is awaited even if ctx expires
+// meanwhile, since the database may still apply it, and its result is
+// returned.
+//
+// A nested transaction releases its savepoint instead, which ctx may
+// cancel. If ctx is already done the savepoint is left untouched, allowing
+// the outer transaction to proceed.
+func (tx *Tx) CommitContext(ctx context.Context) error {
 	tx.Lock()
 	defer tx.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		tx.log().Error("commit.context", zap.Error(err))
+		return err
+	}
+
 	if tx.IsRollbacked {
-		logger.Error("Cannot commit", zap.Error(ErrTxRollbacked))
+		tx.log().Error("Cannot commit", zap.Error(ErrTxRollbacked))
 		return ErrTxRollbacked
 	}
 
-	if tx.state == txCommitted {
-		logger.Error("Transaction has already been commited")
+	if tx.state == TxCommitted {
+		tx.log().Error("Transaction has already been commited")
 		return errors.New("transaction has already been commited")
 	}
-	if tx.state == txRollbacked {
-		logger.Error("Transaction has already been rolled back")
+	if tx.state == TxRollbacked {
+		tx.log().Error("Transaction has already been rolled back")
 		return errors.New("transaction has already been rolled back")
 	}
 
+	var err error
 	if len(tx.stateStack) == 0 {
-		err := tx.Tx.Commit()
-		if err != nil {
-			tx.state = txErred
-			logger.Error("commit.error", zap.Error(err))
-			return err
-		}
+		err = tx.finish(tx.Tx.Commit)
+	} else {
+		err = tx.execSavepoint(ctx, "RELEASE SAVEPOINT ")
+	}
+	if err != nil {
+		tx.state = TxErred
+		tx.log().Error("commit.error", zap.Error(err))
+		return err
 	}
 
-	logger.Debug("commit")
-	tx.state = txCommitted
+	tx.log().Debug("commit")
+	tx.state = TxCommitted
 	return nil
 }
 
 // Rollback cancels the transaction
 func (tx *Tx) Rollback() error {
+	return tx.RollbackContext(context.Background())
+}
+
+// RollbackContext cancels the transaction unless ctx is already done, in
+// which case ctx.Err() is returned as is. Like CommitContext, the rollback
+// is awaited once sent.
+func (tx *Tx) RollbackContext(ctx context.Context) error {
 	tx.Lock()
 	defer tx.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		tx.log().Error("rollback.context", zap.Error(err))
+		return err
+	}
+
 	if tx.IsRollbacked {
-		logger.Error("Cannot rollback", zap.Error(ErrTxRollbacked))
+		tx.log().Error("Cannot rollback", zap.Error(ErrTxRollbacked))
 		return ErrTxRollbacked
 	}
-	if tx.state == txCommitted {
-		logger.Error("Cannot rollback, transaction has already been commited")
+	if tx.state == TxCommitted {
+		tx.log().Error("Cannot rollback, transaction has already been commited")
 		return errors.New("cannot rollback, transaction has already been commited")
 	}
 
-	// rollback is sent to the database even in nested state
-	err := tx.Tx.Rollback()
+	if tx.state == TxRollbacked {
+		tx.log().Error("Cannot rollback, transaction has already been rolled back")
+		return errors.New("cannot rollback, transaction has already been rolled back")
+	}
+
+	// a nested rollback only rolls back to its savepoint
+	nested := len(tx.stateStack) > 0
+	var err error
+	if nested {
+		err = tx.execSavepoint(ctx, "ROLLBACK TO SAVEPOINT ")
+	} else {
+		err = tx.finish(tx.Tx.Rollback)
+	}
 	if err != nil {
-		tx.state = txErred
-		logger.Error("Unable to rollback", zap.Error(err))
+		tx.state = TxErred
+		if err == ctx.Err() {
+			tx.log().Error("rollback.context", zap.Error(err))
+			return err
+		}
+		tx.log().Error("Unable to rollback", zap.Error(err))
 		return fmt.Errorf("Unable to rollback: %v", err)
 	}
 
-	logger.Debug("rollback")
-	tx.state = txRollbacked
-	tx.IsRollbacked = true
+	tx.log().Debug("rollback")
+	tx.state = TxRollbacked
+	tx.IsRollbacked = !nested
 	return nil
 }
 
 // AutoCommit commits a transaction IF neither Commit or Rollback were called.
+// A nested transaction releases its savepoint instead.
 func (tx *Tx) AutoCommit() error {
 	tx.Lock()
 	defer tx.Unlock()
 
-	if tx.state == txRollbacked || tx.IsRollbacked {
+	if tx.state == TxRollbacked || tx.state == TxCommitted || tx.IsRollbacked {
 		tx.popState()
 		return nil
 	}
 
-	err := tx.Tx.Commit()
+	var err error
+	if len(tx.stateStack) == 0 {
+		err = tx.finish(tx.Tx.Commit)
+	} else {
+		err = tx.execSavepoint(context.Background(), "RELEASE SAVEPOINT ")
+	}
 	if err != nil {
-		tx.state = txErred
+		tx.state = TxErred
 		if dat.Strict {
-			logger.Fatal("Could not commit transaction", zap.Error(err))
+			tx.log().Fatal("Could not commit transaction", zap.Error(err))
 		}
 		tx.popState()
-		logger.Error("transaction.AutoCommit.commit_error", zap.Error(err))
+		tx.log().Error("transaction.AutoCommit.commit_error", zap.Error(err))
 		return err
 	}
-	logger.Debug("autocommit")
-	tx.state = txCommitted
+	tx.log().Debug("autocommit")
+	tx.state = TxCommitted
 	tx.popState()
 	return err
 }
 
 // AutoRollback rolls back transaction IF neither Commit or Rollback were called.
+// A nested transaction rolls back to its savepoint instead.
 func (tx *Tx) AutoRollback() error {
 	tx.Lock()
 	defer tx.Unlock()
 
-	if tx.IsRollbacked || tx.state == txCommitted {
+	if tx.IsRollbacked || tx.state == TxCommitted || tx.state == TxRollbacked {
 		tx.popState()
 		return nil
 	}
 
-	err := tx.Tx.Rollback()
+	nested := len(tx.stateStack) > 0
+	var err error
+	if nested {
+		err = tx.execSavepoint(context.Background(), "ROLLBACK TO SAVEPOINT ")
+	} else {
+		err = tx.finish(tx.Tx.Rollback)
+	}
 	if err != nil {
-		tx.state = txErred
+		tx.state = TxErred
 		if dat.Strict {
-			logger.Fatal("Could not rollback transaction", zap.Error(err))
+			tx.log().Fatal("Could not rollback transaction", zap.Error(err))
 		}
 		tx.popState()
-		logger.Error("transaction.AutoRollback.rollback_error", zap.Error(err))
+		tx.log().Error("transaction.AutoRollback.rollback_error", zap.Error(err))
 		return fmt.Errorf("transaction.AutoRollback.rollback_error: %v", err)
 	}
-	logger.Debug("autorollback")
-	tx.state = txRollbacked
-	tx.IsRollbacked = true
+	tx.log().Debug("autorollback")
+	tx.state = TxRollbacked
+	tx.IsRollbacked = !nested
 	tx.popState()
 	return err
 }
 
+// finish ends the top level transaction with end, tx.Tx.Commit or
+// tx.Tx.Rollback, and releases it from its DB once end has returned, even
+// if it failed since database/sql discards the transaction either way.
+func (tx *Tx) finish(end func() error) error {
+	err := end()
+	if tx.release != nil {
+		tx.release()
+	}
+	return err
+}
+
+// ID returns the correlation ID logged with every statement of the
+// transaction.
+func (tx *Tx) ID() string {
+	return tx.txID
+}
+
+// WithID sets the correlation ID of the transaction, eg to match the trace
+// of an incoming request. Set it before the transaction is shared with
+// other goroutines.
+func (tx *Tx) WithID(id string) *Tx {
+	tx.txID = id
+	return tx
+}
+
+// SetStatementTimeout makes the server abort any statement of the
+// transaction running longer than d with SET LOCAL statement_timeout, which
+// lasts until the end of the transaction. The statement fails with SQLSTATE
+// 57014 query_canceled. 0 disables the timeout.
+func (tx *Tx) SetStatementTimeout(d time.Duration) error {
+	tx.Lock()
+	defer tx.Unlock()
+	if tx.IsRollbacked {
+		return ErrTxRollbacked
+	}
+
+	sql := "SET LOCAL statement_timeout = " + strconv.FormatInt(statementTimeoutMillis(d), 10)
+	if _, err := tx.Tx.Exec(sql); err != nil {
+		return logSQLError(tx.log(), err, "setStatementTimeout", sql, nil)
+	}
+	tx.log().Debug("set statement timeout", zap.Duration("timeout", d))
+	return nil
+}
+
+// setStatementTimeout applies the default statement timeout of db to tx,
+// rolling tx back on failure.
+func (db *DB) setStatementTimeout(tx *Tx) error {
+	if db.statementTimeout <= 0 {
+		return nil
+	}
+	if err := tx.SetStatementTimeout(db.statementTimeout); err != nil {
+		tx.AutoRollback()
+		return err
+	}
+	return nil
+}
+
+// Close commits the transaction IF neither Commit or Rollback were called
+// and *errp is nil, otherwise it rolls back. A commit error is stored in
+// *errp. Close must be deferred directly by a function with a named error
+// result. On panic the transaction is rolled back before re-panicking.
+//
+//	func transfer(db *runner.DB) (err error) {
+//		tx, err := db.Begin()
+//		if err != nil {
+//			return err
+//		}
+//		defer tx.Close(&err)
+//		...
+//	}
+func (tx *Tx) Close(errp *error) {
+	if p := recover(); p != nil {
+		tx.AutoRollback()
+		panic(p)
+	}
+
+	if *errp != nil {
+		tx.AutoRollback()
+		return
+	}
+	*errp = tx.AutoCommit()
+}
+
+// State returns the state of the transaction at the current nesting level.
+func (tx *Tx) State() TxState {
+	tx.Lock()
+	defer tx.Unlock()
+	return tx.state
+}
+
 // Select creates a new SelectBuilder for the given columns.
 // This disambiguates between Queryable.Select and sqlx's Select
 func (tx *Tx) Select(columns ...string) *dat.SelectBuilder {
 	return tx.Queryable.Select(columns...)
 }
 
+// log returns the logger tagged with the transaction ID.
+func (tx *Tx) log() *zap.Logger {
+	if tx.txID == "" {
+		return logger()
+	}
+	return logger().With(zap.String("tx", tx.txID))
+}
+
+// newTxID generates a short random transaction ID.
+func newTxID() string {
+	return uuid()[:8]
+}
+
 func (tx *Tx) pushState() {
 	tx.stateStack = append(tx.stateStack, tx.state)
-	tx.state = txPending
+	tx.state = TxPending
 }
 
 func (tx *Tx) popState() {
@@ -202,7 +505,28 @@ func (tx *Tx) popState() {
 		return
 	}
 
-	var val int
+	var val TxState
 	val, tx.stateStack = tx.stateStack[len(tx.stateStack)-1], tx.stateStack[:len(tx.stateStack)-1]
 	tx.state = val
 }
+
+// savepoint returns the name of the savepoint for the current nesting level,
+// see dat.SavepointNamer.
+func (tx *Tx) savepoint() string {
+	depth := len(tx.stateStack)
+	if namer, ok := dat.Dialect.(dat.SavepointNamer); ok {
+		return namer.SavepointName(depth)
+	}
+	return "sp_" + strconv.Itoa(depth)
+}
+
+// execSavepoint executes a savepoint command such as "SAVEPOINT " for the
+// current nesting level. database/sql cancels the command, returning
+// ctx.Err(), if ctx is done before it completes.
+func (tx *Tx) execSavepoint(ctx context.Context, cmd string) error {
+	_, err := tx.Tx.ExecContext(ctx, cmd+tx.savepoint())
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// errConcurrentTx is the panic raised when a Tx is used by two goroutines at
+// the same time in strict mode.
+const errConcurrentTx = "A database transaction was used concurrently!"
+
+// guardedTx is a database which panics when a statement is executed while
+// another statement of the same transaction is still running, including a
+// query whose rows are not closed yet. It is used for transactions when
+// dat.Strict is enabled.
+type guardedTx struct {
+	*sqlx.Tx
+
+	inUse int32
+	// open is the result of the last query, which keeps the transaction in
+	// use until it is closed. It is only accessed while inUse is set.
+	open queryResult
+}
+
+// queryResult is a *sqlx.Rows or *sqlx.Row, whose Columns fails once it
+// is closed.
+type queryResult interface {
+	Columns() ([]string, error)
+}
+
+func newGuardedTx(tx *sqlx.Tx) *guardedTx {
+	return &guardedTx{Tx: tx}
+}
+
+// acquire marks the transaction in use, panicking if it already is or the
+// rows of the last query are still open.
+func (g *guardedTx) acquire() {
+	if !atomic.CompareAndSwapInt32(&g.inUse, 0, 1) {
+		panic(errConcurrentTx)
+	}
+	if g.open != nil {
+		if _, err := g.open.Columns(); err == nil {
+			g.release()
+			panic(errConcurrentTx)
+		}
+		g.open = nil
+	}
+}
+
+func (g *guardedTx) release() {
+	atomic.StoreInt32(&g.inUse, 0)
+}
+
+func (g *guardedTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return g.ExecContext(context.Background(), query, args...)
+}
+
+func (g *guardedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	g.acquire()
+	defer g.release()
+	return g.Tx.ExecContext(ctx, query, args...)
+}
+
+func (g *guardedTx) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return g.QueryxContext(context.Background(), query, args...)
+}
+
+func (g *guardedTx) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	g.acquire()
+	defer g.release()
+	rows, err := g.Tx.QueryxContext(ctx, query, args...)
+	if err == nil {
+		g.open = rows
+	}
+	return rows, err
+}
+
+func (g *guardedTx) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	g.acquire()
+	defer g.release()
+	row := g.Tx.QueryRowx(query, args...)
+	g.open = row
+	return row
+}
+
+func (g *guardedTx) Select(dest interface{}, query string, args ...interface{}) error {
+	return g.SelectContext(context.Background(), dest, query, args...)
+}
+
+func (g *guardedTx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	g.acquire()
+	defer g.release()
+	return g.Tx.SelectContext(ctx, dest, query, args...)
+}
+
+func (g *guardedTx) Get(dest interface{}, query string, args ...interface{}) error {
+	return g.GetContext(context.Background(), dest, query, args...)
+}
+
+func (g *guardedTx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	g.acquire()
+	defer g.release()
+	return g.Tx.GetContext(ctx, dest, query, args...)
+}
@@ -2,9 +2,14 @@ package runner
 
 import (
 	// "database/sql"
+	"context"
 	"database/sql"
+	"errors"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/casualjim/dat"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -146,7 +151,7 @@ func TestRollbackWithNestedCommit(t *testing.T) {
 		From("people").
 		Where("email = $1", "mario@mgutz.com").
 		QueryStruct(&person)
-	assert.Exactly(t, sql.ErrNoRows, err)
+	assert.Exactly(t, dat.ErrNotFound, err)
 	assert.EqualValues(t, 0, person.ID)
 }
 
@@ -203,7 +208,7 @@ func TestRollbackWithNestedRollback(t *testing.T) {
 	err = nestedRollback(tx)
 	assert.NoError(t, err)
 	err = tx.Rollback()
-	assert.Exactly(t, ErrTxRollbacked, err)
+	assert.NoError(t, err)
 
 	var person Person
 	err = testDB.
@@ -211,7 +216,7 @@ func TestRollbackWithNestedRollback(t *testing.T) {
 		From("people").
 		Where("email = $1", "mario@mgutz.com").
 		QueryStruct(&person)
-	assert.Exactly(t, sql.ErrNoRows, err)
+	assert.Exactly(t, dat.ErrNotFound, err)
 }
 
 func TestCommitWithNestedRollback(t *testing.T) {
@@ -221,7 +226,7 @@ func TestCommitWithNestedRollback(t *testing.T) {
 	err = nestedRollback(tx)
 	assert.NoError(t, err)
 	err = tx.Commit()
-	assert.Exactly(t, ErrTxRollbacked, err)
+	assert.NoError(t, err)
 
 	var person Person
 	err = testDB.
@@ -229,7 +234,7 @@ func TestCommitWithNestedRollback(t *testing.T) {
 		From("people").
 		Where("email = $1", "mario@mgutz.com").
 		QueryStruct(&person)
-	assert.Exactly(t, sql.ErrNoRows, err)
+	assert.Exactly(t, dat.ErrNotFound, err)
 }
 
 func TestCommitWithNestedNestedRollback(t *testing.T) {
@@ -239,7 +244,7 @@ func TestCommitWithNestedNestedRollback(t *testing.T) {
 	err = nestedNestedRollback(tx)
 	assert.NoError(t, err)
 	err = tx.Commit()
-	assert.Exactly(t, ErrTxRollbacked, err)
+	assert.NoError(t, err)
 
 	var person Person
 	err = testDB.
@@ -247,7 +252,7 @@ func TestCommitWithNestedNestedRollback(t *testing.T) {
 		From("people").
 		Where("email = $1", "mario@mgutz.com").
 		QueryStruct(&person)
-	assert.Exactly(t, sql.ErrNoRows, err)
+	assert.Exactly(t, dat.ErrNotFound, err)
 }
 
 func TestErrorInBeginIfRollbacked(t *testing.T) {
@@ -260,3 +265,372 @@ func TestErrorInBeginIfRollbacked(t *testing.T) {
 	_, err = tx.Begin()
 	assert.Exactly(t, ErrTxRollbacked, err)
 }
+
+func TestCommitContextCanceled(t *testing.T) {
+	installFixtures()
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = tx.CommitContext(ctx)
+	assert.Exactly(t, context.Canceled, err)
+
+	// transaction is still usable
+	err = tx.CommitContext(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestNestedCommitContextCanceled(t *testing.T) {
+	installFixtures()
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	nested, err := tx.Begin()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = nested.CommitContext(ctx)
+	assert.Exactly(t, context.Canceled, err)
+	assert.False(t, tx.IsRollbacked)
+
+	err = nested.CommitContext(context.Background())
+	assert.NoError(t, err)
+	err = nested.AutoRollback()
+	assert.NoError(t, err)
+
+	err = tx.Commit()
+	assert.NoError(t, err)
+}
+
+func TestRollbackContextDeadline(t *testing.T) {
+	installFixtures()
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	ctx, cancel := context.WithTimeout(context.Background(), -1)
+	defer cancel()
+
+	err = tx.RollbackContext(ctx)
+	assert.Exactly(t, context.DeadlineExceeded, err)
+	assert.False(t, tx.IsRollbacked)
+}
+
+func TestNestedRollbackKeepsOuterWork(t *testing.T) {
+	installFixtures()
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	_, err = tx.InsertInto("people").Columns("name", "email").
+		Values("Outer", "outer@mgutz.com").
+		Exec()
+	assert.NoError(t, err)
+
+	err = nestedRollback(tx)
+	assert.NoError(t, err)
+	assert.False(t, tx.IsRollbacked)
+
+	err = tx.Commit()
+	assert.NoError(t, err)
+
+	var person Person
+	err = testDB.
+		Select("*").
+		From("people").
+		Where("email = $1", "outer@mgutz.com").
+		QueryStruct(&person)
+	assert.NoError(t, err)
+
+	err = testDB.
+		Select("*").
+		From("people").
+		Where("email = $1", "mario@mgutz.com").
+		QueryStruct(&person)
+	assert.Exactly(t, dat.ErrNotFound, err)
+}
+
+func TestBeginTxReadOnly(t *testing.T) {
+	installFixtures()
+	tx, err := testDB.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	var readOnly string
+	err = tx.SQL("SHOW transaction_read_only").QueryScalar(&readOnly)
+	assert.NoError(t, err)
+	assert.Equal(t, "on", readOnly)
+
+	_, err = tx.InsertInto("people").Columns("name", "email").
+		Values("Readonly", "readonly@mgutz.com").
+		Exec()
+	assert.Error(t, err)
+}
+
+func TestBeginTxSerializable(t *testing.T) {
+	installFixtures()
+	tx, err := testDB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	var level string
+	err = tx.SQL("SHOW transaction_isolation").QueryScalar(&level)
+	assert.NoError(t, err)
+	assert.Equal(t, "serializable", level)
+	assert.NoError(t, tx.Commit())
+}
+
+func TestTxState(t *testing.T) {
+	installFixtures()
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	assert.Equal(t, TxPending, tx.State())
+
+	nested, err := tx.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, nested.Rollback())
+	assert.Equal(t, TxRollbacked, nested.State())
+	assert.NoError(t, nested.AutoRollback())
+	assert.Equal(t, TxPending, tx.State())
+
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, TxCommitted, tx.State())
+	assert.Equal(t, "committed", tx.State().String())
+}
+
+func TestWithTransactionCommit(t *testing.T) {
+	installFixtures()
+	var txn *Tx
+	err := testDB.WithTransaction(func(tx *Tx) error {
+		txn = tx
+		_, err := tx.InsertInto("people").Columns("name", "email").
+			Values("With", "with@mgutz.com").
+			Exec()
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, TxCommitted, txn.State())
+}
+
+func TestWithTransactionError(t *testing.T) {
+	installFixtures()
+	var txn *Tx
+	expected := errors.New("boom")
+	err := testDB.WithTransaction(func(tx *Tx) error {
+		txn = tx
+		return expected
+	})
+	assert.Exactly(t, expected, err)
+	assert.True(t, txn.IsRollbacked)
+}
+
+func TestWithTransactionPanic(t *testing.T) {
+	installFixtures()
+	var txn *Tx
+	func() {
+		defer func() {
+			assert.Equal(t, "boom", recover())
+		}()
+		testDB.WithTransaction(func(tx *Tx) error {
+			txn = tx
+			_, err := tx.InsertInto("people").Columns("name", "email").
+				Values("Panic", "panic@mgutz.com").
+				Exec()
+			assert.NoError(t, err)
+			panic("boom")
+		})
+	}()
+
+	assert.True(t, txn.IsRollbacked)
+	// underlying sqlx.Tx is done
+	_, err := txn.Tx.Exec("SELECT 1")
+	assert.Exactly(t, sql.ErrTxDone, err)
+
+	var n int
+	err = testDB.SQL("SELECT count(*) FROM people WHERE email = $1", "panic@mgutz.com").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestTxClose(t *testing.T) {
+	installFixtures()
+	var txn *Tx
+	insert := func(email string, fail error, boom bool) (err error) {
+		tx, err := testDB.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Close(&err)
+		txn = tx
+
+		_, err = tx.InsertInto("people").Columns("name", "email").
+			Values("Close", email).
+			Exec()
+		assert.NoError(t, err)
+		if boom {
+			panic("boom")
+		}
+		return fail
+	}
+	count := func(email string) int {
+		var n int
+		err := testDB.SQL("SELECT count(*) FROM people WHERE email = $1", email).QueryScalar(&n)
+		assert.NoError(t, err)
+		return n
+	}
+
+	assert.NoError(t, insert("commit@mgutz.com", nil, false))
+	assert.Equal(t, TxCommitted, txn.State())
+	assert.Equal(t, 1, count("commit@mgutz.com"))
+
+	expected := errors.New("fail")
+	assert.Exactly(t, expected, insert("error@mgutz.com", expected, false))
+	assert.True(t, txn.IsRollbacked)
+	assert.Equal(t, 0, count("error@mgutz.com"))
+
+	func() {
+		defer func() {
+			assert.Equal(t, "boom", recover())
+		}()
+		insert("panic@mgutz.com", nil, true)
+	}()
+	assert.True(t, txn.IsRollbacked)
+	assert.Equal(t, 0, count("panic@mgutz.com"))
+}
+
+func TestTxConcurrentUseStrict(t *testing.T) {
+	dat.Strict = true
+	defer func() { dat.Strict = false }()
+
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	done := make(chan error)
+	go func() {
+		_, err := tx.SQL("SELECT pg_sleep(0.2)").Exec()
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.PanicsWithValue(t, errConcurrentTx, func() {
+		tx.SQL("SELECT 1").Exec()
+	})
+	assert.NoError(t, <-done)
+
+	// sequential use is fine
+	var n int
+	assert.NoError(t, tx.SQL("SELECT 1").QueryScalar(&n))
+	assert.Equal(t, 1, n)
+
+	// rows keep the transaction in use until they are closed
+	rows, err := tx.runner.Queryx("SELECT 1")
+	assert.NoError(t, err)
+	assert.PanicsWithValue(t, errConcurrentTx, func() {
+		tx.SQL("SELECT 1").Exec()
+	})
+	assert.NoError(t, rows.Close())
+	_, err = tx.SQL("SELECT 1").Exec()
+	assert.NoError(t, err)
+}
+
+func TestTxCursor(t *testing.T) {
+	installFixtures()
+
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	cursor, err := tx.DeclareCursor("people_cursor", tx.Select("id", "name").From("people").OrderBy("id"))
+	assert.NoError(t, err)
+
+	var ids []int64
+	batches := 0
+	for {
+		var people []*Person
+		err = cursor.Fetch(4, &people)
+		assert.NoError(t, err)
+		if len(people) == 0 {
+			break
+		}
+		batches++
+		for _, p := range people {
+			ids = append(ids, p.ID)
+		}
+	}
+	assert.Equal(t, 2, batches)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, ids)
+
+	assert.NoError(t, cursor.Close())
+	assert.NoError(t, cursor.Close())
+	var people []*Person
+	assert.Equal(t, ErrCursorClosed, cursor.Fetch(1, &people))
+	assert.NoError(t, tx.Commit())
+}
+
+func TestTxSetStatementTimeout(t *testing.T) {
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	assert.NoError(t, tx.SetStatementTimeout(50*time.Millisecond))
+	timeout, err := tx.SQL("SHOW statement_timeout").QueryString()
+	assert.NoError(t, err)
+	assert.Equal(t, "50ms", timeout)
+
+	_, err = tx.SQL("SELECT pg_sleep(1)").Exec()
+	assert.Error(t, err)
+	qe, ok := err.(*dat.QueryError)
+	assert.True(t, ok)
+	assert.Equal(t, "57014", qe.Code)
+}
+
+func TestDBSetStatementTimeout(t *testing.T) {
+	testDB.SetStatementTimeout(2 * time.Second)
+	defer testDB.SetStatementTimeout(0)
+
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	timeout, err := tx.SQL("SHOW statement_timeout").QueryString()
+	assert.NoError(t, err)
+	assert.Equal(t, "2s", timeout)
+	assert.NoError(t, tx.AutoRollback())
+
+	// SET LOCAL ends with the transaction, the pooled connection is unaffected
+	testDB.SetStatementTimeout(0)
+	tx, err = testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+	timeout, err = tx.SQL("SHOW statement_timeout").QueryString()
+	assert.NoError(t, err)
+	assert.Equal(t, "0", timeout)
+}
+
+func TestWithStatementTimeout(t *testing.T) {
+	dsn, err := WithStatementTimeout("postgres://dat@localhost/dbr_test?sslmode=disable", 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://dat@localhost/dbr_test?sslmode=disable&statement_timeout=5000", dsn)
+
+	dsn, err = WithStatementTimeout("dbname=dbr_test statement_timeout=1000", 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "dbname=dbr_test statement_timeout=1000 statement_timeout=50", dsn)
+
+	dsn, err = WithStatementTimeout(os.Getenv("DAT_DSN"), 50*time.Millisecond)
+	assert.NoError(t, err)
+	db := NewDBFromString(os.Getenv("DAT_DRIVER"), dsn)
+	defer db.DB.Close()
+
+	timeout, err := db.SQL("SHOW statement_timeout").QueryString()
+	assert.NoError(t, err)
+	assert.Equal(t, "50ms", timeout)
+
+	_, err = db.SQL("SELECT pg_sleep(1)").Exec()
+	assert.Error(t, err)
+	qe, ok := err.(*dat.QueryError)
+	assert.True(t, ok)
+	assert.Equal(t, "57014", qe.Code)
+}
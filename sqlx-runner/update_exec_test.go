@@ -232,3 +232,118 @@ func TestUpdateScopeFunc(t *testing.T) {
 	assert.Equal(t, person.Email.Valid, true)
 	assert.Equal(t, person.Email.String, "barack@whitehouse.gov")
 }
+
+func TestUpdateSoftDeleteScopeWithWhere(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.Update("posts").Set("deleted_at", dat.Expr("now()")).Where("id = $1", 2).Exec()
+	assert.NoError(t, err)
+
+	// the scope is AND-ed with Where, sparing the deleted post and the
+	// posts of other users
+	n, err := s.
+		Update("posts").
+		Set("state", "archived").
+		UseScope(dat.SoftDelete("deleted_at")).
+		Where("user_id = $1", 1).
+		ExecRows()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	var state string
+	err = s.Select("state").From("posts").Where("id = $1", 2).QueryScalar(&state)
+	assert.NoError(t, err)
+	assert.Equal(t, "draft", state)
+}
+
+func TestUpdateExecRows(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	n, err := s.Update("posts").Set("state", "archived").Where("user_id = $1", 1).ExecRows()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	n, err = s.DeleteFrom("comments").Where("user_id = $1", 1).ExecRows()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	n, err = s.Update("posts").Set("state", "archived").Where("id = $1", -1).ExecRows()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+}
+
+func TestUpdateSetFromValuesReal(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	n, err := s.Update("posts").
+		SetFromValues("id", []dat.M{
+			{"id": 1, "title": "Day One"},
+			{"id": 3, "title": "Green Apple"},
+		}).
+		ExecRows()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	var titles []string
+	err = s.SQL("SELECT title FROM posts WHERE id IN (1, 3) ORDER BY id").QuerySlice(&titles)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Day One", "Green Apple"}, titles)
+}
+
+func TestUpdateOptimisticLockReal(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.SQL("ALTER TABLE posts ADD COLUMN version int NOT NULL DEFAULT 1").Exec()
+	assert.NoError(t, err)
+
+	n, err := s.Update("posts").Set("title", "Day One").Where("id = $1", 1).OptimisticLock("version", 1).ExecRows()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	var version int
+	err = s.SQL("SELECT version FROM posts WHERE id = $1", 1).QueryScalar(&version)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	_, err = s.Update("posts").Set("title", "Day Uno").Where("id = $1", 1).OptimisticLock("version", 1).Exec()
+	assert.Equal(t, dat.ErrStaleObject, err)
+}
+
+type address struct {
+	Street string
+	City   string
+	Zip    *string
+}
+
+func TestUpdateComposite(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.SQL("CREATE TYPE address AS (street text, city text, zip text)").Exec()
+	assert.NoError(t, err)
+	_, err = s.SQL("ALTER TABLE people ADD COLUMN address address").Exec()
+	assert.NoError(t, err)
+
+	for _, interpolate := range []bool{false, true} {
+		dat.EnableInterpolation = interpolate
+
+		addr := address{Street: `1 "Main" St`, City: "Springfield"}
+		_, err = s.Update("people").Set("address", dat.Composite("address", addr)).Where("id = $1", 1).Exec()
+		assert.NoError(t, err)
+
+		var city string
+		err = s.Select("(address).city").From("people").Where("id = $1", 1).QueryScalar(&city)
+		assert.NoError(t, err)
+		assert.Equal(t, "Springfield", city)
+
+		var scanned address
+		err = s.Select("address").From("people").Where("id = $1", 1).QueryScalar(dat.ScanComposite(&scanned))
+		assert.NoError(t, err)
+		assert.Equal(t, addr, scanned)
+	}
+	dat.EnableInterpolation = false
+}
@@ -48,3 +48,56 @@ func TestUpsertReal(t *testing.T) {
 	assert.True(t, person.ID > 0)
 	assert.NotEqual(t, person.CreatedAt, dat.NullTime{})
 }
+
+func TestUpsertReturningInserted(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var id int64
+	var inserted bool
+	err := s.Upsert("people").
+		Columns("name", "email").
+		Values("Daisy", "daisy@sarasaland.com").
+		Where("name = $1", "Daisy").
+		Returning("id").
+		ReturningInserted(&inserted).
+		QueryScalar(&id)
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+
+	var person Person
+	err = s.Upsert("people").
+		Columns("name", "email").
+		Values("Daisy", "daisy@mushroom.com").
+		Where("name = $1", "Daisy").
+		Returning("id", "email").
+		ReturningInserted(&inserted).
+		QueryStruct(&person)
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	assert.Equal(t, id, person.ID)
+	assert.Equal(t, "daisy@mushroom.com", person.Email.String)
+}
+
+func TestUpsertOnConflict(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	p := &Person{ID: 1, Name: "Mario Bros", Email: dat.NullStringFrom("mario@bros.com")}
+	var out Person
+	var inserted bool
+	err := s.
+		Upsert("people").
+		Columns("id", "name", "email").
+		Record(p).
+		Returning("id", "name", "email").
+		OnConflict("id").
+		DoUpdateAllExcept().
+		ReturningInserted(&inserted).
+		QueryStruct(&out)
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	assert.EqualValues(t, 1, out.ID)
+	assert.Equal(t, "Mario Bros", out.Name)
+	assert.Equal(t, "mario@bros.com", out.Email.String)
+}
@@ -1,8 +1,13 @@
 package dat
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
 	"github.com/mgutz/str"
 
 	"github.com/casualjim/dat/reflectx"
@@ -10,42 +15,137 @@ import (
 
 var fieldMapper = reflectx.NewMapperTagFunc("db", nil, nil)
 
+// structMaps caches the *reflectx.StructMap of each record type so repeated
+// inserts and updates of the same type skip reflection and the lock of
+// fieldMapper.
+var structMaps = &sync.Map{}
+
+// structMapFor gets the cached field mapping of record type t.
+func structMapFor(t reflect.Type) *reflectx.StructMap {
+	if m, ok := structMaps.Load(t); ok {
+		return m.(*reflectx.StructMap)
+	}
+	m, _ := structMaps.LoadOrStore(t, fieldMapper.TypeMap(t))
+	return m.(*reflectx.StructMap)
+}
+
+// SetColumnMapper maps the names of struct fields without a db tag to
+// columns with mapper, eg SnakeCase, when deriving columns from records. A
+// nil mapper ignores untagged fields, the default. It should be called
+// before any builder is used. See runner.DB.SetColumnMapper to also scan
+// results with mapper.
+func SetColumnMapper(mapper func(string) string) {
+	fieldMapper = reflectx.NewMapperTagFunc("db", mapper, nil)
+	structMaps = &sync.Map{}
+}
+
+// SnakeCase converts a Go field name to snake case, eg UserID => user_id and
+// HTTPStatus => http_status.
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
 // reflectFields gets a cached field information about record
 func reflectFields(rec interface{}) *reflectx.StructMap {
-	val := reflect.Indirect(reflect.ValueOf(rec))
-	vtype := val.Type()
-	return fieldMapper.TypeMap(vtype)
+	return structMapFor(reflect.Indirect(reflect.ValueOf(rec)).Type())
 }
 
-// ValuesFor ...
+// valuesFor gets the values of columns from record. Columns of an embedded
+// struct behind a nil pointer are nil. Fields tagged `db:"name,json"` are
+// marshalled to JSON. The record is not modified.
 func valuesFor(recordType reflect.Type, record reflect.Value, columns []string) ([]interface{}, error) {
-	vals := fieldMapper.FieldsByName(record, columns)
+	tm := structMapFor(recordType)
 	values := make([]interface{}, len(columns))
-	for i, val := range vals {
-		if !val.IsValid() {
+	for i, column := range columns {
+		fi, ok := tm.Names[column]
+		if !ok {
 			return nil, fmt.Errorf("Could not find struct tag in type %s: `db:\"%s\"`", recordType.Name(), columns[i])
 		}
-		values[i] = val.Interface()
+		values[i] = fieldValue(record, fi.Index)
+		if _, isJSON := fi.Options["json"]; isJSON {
+			v, err := jsonFieldValue(values[i])
+			if err != nil {
+				return nil, fmt.Errorf("Could not marshal %s.%s to JSON: %s", recordType.Name(), fi.Field.Name, err)
+			}
+			values[i] = v
+		}
 	}
 	return values, nil
 }
 
+// jsonFieldValue marshals the value of a json field, nil is NULL.
+func jsonFieldValue(v interface{}) (interface{}, error) {
+	if isNil(v) {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return JSON(b), nil
+}
+
+// fieldValue gets the value of the field at indexes of v, or nil if an
+// embedded pointer along the way is nil.
+func fieldValue(v reflect.Value, indexes []int) interface{} {
+	for _, i := range indexes {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v.Interface()
+}
+
+// reflectColumns gets the declared columns of record v.
 func reflectColumns(v interface{}) []string {
+	columns := reflectFields(v).DeclaredNames
+	// copy since builders may append to their columns
+	return append(make([]string, 0, len(columns)), columns...)
+}
+
+func reflectExcludeColumns(v interface{}, blacklist []string) []string {
+	if len(blacklist) == 0 {
+		return reflectColumns(v)
+	}
 	cols := []string{}
 	for _, name := range reflectFields(v).DeclaredNames {
+		if str.SliceContains(blacklist, name) {
+			continue
+		}
 		cols = append(cols, name)
 	}
+
 	return cols
 }
 
-func reflectExcludeColumns(v interface{}, blacklist []string) []string {
-	cols := []string{}
-	for _, name := range reflectFields(v).DeclaredNames {
+// recordColumns gets the columns of rec in whitelist, or all columns when
+// whitelist is empty or "*", without the columns in blacklist.
+func recordColumns(rec interface{}, whitelist, blacklist []string) []string {
+	if len(whitelist) == 0 || whitelist[0] == "*" {
+		return reflectExcludeColumns(rec, blacklist)
+	}
+	cols := make([]string, 0, len(whitelist))
+	for _, name := range whitelist {
 		if str.SliceContains(blacklist, name) {
 			continue
 		}
 		cols = append(cols, name)
 	}
-
 	return cols
 }
@@ -1,7 +1,9 @@
 package dat
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -42,3 +44,131 @@ func TestEmbeddedStructInvalidColumns(t *testing.T) {
 		InsertInto("groups").Columns("group_uuid", "realm_uuid").Record(g).ToSQL()
 	})
 }
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"ID":         "id",
+		"UserID":     "user_id",
+		"Name":       "name",
+		"HTTPStatus": "http_status",
+		"CreatedAt":  "created_at",
+		"Address2":   "address2",
+	}
+	for name, expected := range cases {
+		assert.Equal(t, expected, SnakeCase(name), name)
+	}
+}
+
+func TestSetColumnMapper(t *testing.T) {
+	type Account struct {
+		ID       int64
+		UserID   int64
+		Nickname string `db:"nick"`
+	}
+
+	SetColumnMapper(SnakeCase)
+	defer SetColumnMapper(nil)
+
+	sql, args := InsertInto("accounts").Blacklist("id").Record(&Account{ID: 1, UserID: 2, Nickname: "gopher"}).ToSQL()
+	assert.Equal(t, `INSERT INTO accounts ("user_id","nick") VALUES ($1,$2)`, sql)
+	assert.Exactly(t, []interface{}{int64(2), "gopher"}, args)
+}
+
+func TestEmbeddedTimestampsRecord(t *testing.T) {
+	type Timestamps struct {
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	type Address struct {
+		Street string `db:"street"`
+	}
+	type User struct {
+		ID       int64  `db:"id"`
+		Name     string `db:"name"`
+		Password string `db:"-"`
+		Home     Address
+		Timestamps
+		Audit *Timestamps `db:"-"`
+	}
+
+	now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	u := &User{ID: 1, Name: "Gopher", Password: "secret", Timestamps: Timestamps{now, now}}
+
+	assert.Equal(t, []string{"id", "name", "created_at", "updated_at"}, reflectColumns(u))
+
+	sql, args := InsertInto("users").Whitelist("*").Record(u).ToSQL()
+	assert.Equal(t, `INSERT INTO users ("id","name","created_at","updated_at") VALUES ($1,$2,$3,$4)`, sql)
+	assert.Exactly(t, []interface{}{int64(1), "Gopher", now, now}, args)
+
+	sql, args = Update("users").SetBlacklist(u, "id", "created_at").Where("id = $1", u.ID).ToSQL()
+	assert.Equal(t, `UPDATE "users" SET "name" = $1, "updated_at" = $2 WHERE (id = $3)`, sql)
+	assert.Exactly(t, []interface{}{"Gopher", now, int64(1)}, args)
+}
+
+func TestEmbeddedNilPointerRecord(t *testing.T) {
+	type Realm struct {
+		RealmUUID string `db:"realm_uuid"`
+	}
+	type Group struct {
+		GroupUUID string `db:"group_uuid"`
+		*Realm
+	}
+
+	g := &Group{GroupUUID: "22"}
+	sql, args := InsertInto("groups").Whitelist("*").Record(g).ToSQL()
+	assert.Equal(t, `INSERT INTO groups ("group_uuid","realm_uuid") VALUES ($1,$2)`, sql)
+	assert.Exactly(t, []interface{}{"22", nil}, args)
+	assert.Nil(t, g.Realm)
+}
+
+func TestRecordMappingCache(t *testing.T) {
+	type Profile struct {
+		ID     int64 `db:"id"`
+		UserID int64 `db:"user_id"`
+		Bio    string
+	}
+
+	// columns are cached per type but whitelist and blacklist apply per
+	// statement
+	p := &Profile{ID: 1, UserID: 2, Bio: "hi"}
+	sql, _ := InsertInto("profiles").Whitelist("*").Record(p).ToSQL()
+	assert.Equal(t, `INSERT INTO profiles ("id","user_id") VALUES ($1,$2)`, sql)
+	sql, args := InsertInto("profiles").Blacklist("id").Record(p).ToSQL()
+	assert.Equal(t, `INSERT INTO profiles ("user_id") VALUES ($1)`, sql)
+	assert.Exactly(t, []interface{}{int64(2)}, args)
+	sql, _ = InsertInto("profiles").Whitelist("*").Record(p).ToSQL()
+	assert.Equal(t, `INSERT INTO profiles ("id","user_id") VALUES ($1,$2)`, sql)
+
+	// the returned columns are not shared with the cache
+	cols := reflectColumns(p)
+	cols[0] = "changed"
+	assert.Equal(t, []string{"id", "user_id"}, reflectColumns(p))
+
+	// a new column mapper clears the cache
+	SetColumnMapper(SnakeCase)
+	defer SetColumnMapper(nil)
+	assert.Equal(t, []string{"id", "user_id", "bio"}, reflectColumns(p))
+}
+
+func TestJSONFieldMapping(t *testing.T) {
+	type Config struct {
+		Theme string   `db:"theme" json:"theme"`
+		Tags  []string `json:"tags"`
+	}
+	type Account struct {
+		ID      int64              `db:"id"`
+		Config  Config             `db:"config,json"`
+		Limits  map[string]int     `db:"limits,json"`
+		History *[]json.RawMessage `db:"history,json"`
+	}
+
+	acct := &Account{ID: 1, Config: Config{Theme: "dark", Tags: []string{"a"}}}
+	sql, args := NewInsertBuilder("accounts").Columns("*").Record(acct).ToSQL()
+	// fields of a json struct are not columns
+	assert.Equal(t, `INSERT INTO accounts ("id","config","limits","history") VALUES ($1,$2,$3,$4)`, sql)
+	assert.Equal(t, []interface{}{int64(1), JSON(`{"theme":"dark","tags":["a"]}`), nil, nil}, args)
+
+	acct.Limits = map[string]int{"seats": 5}
+	_, args = NewUpdateBuilder("accounts").SetWhitelist(acct, "limits").Where("id = $1", 1).ToSQL()
+	assert.Equal(t, []interface{}{JSON(`{"seats":5}`), 1}, args)
+}
@@ -0,0 +1,43 @@
+package dat
+
+// In creates a condition which is true when column is in the result of the
+// subquery, eg id IN (SELECT ...). The subquery args are merged into the
+// outer statement.
+func In(column string, subquery Fragment) *Expression {
+	return subqueryExpr(column+" IN (", subquery)
+}
+
+// NotIn creates a condition which is true when column is not in the result
+// of the subquery, eg id NOT IN (SELECT ...).
+func NotIn(column string, subquery Fragment) *Expression {
+	return subqueryExpr(column+" NOT IN (", subquery)
+}
+
+// Exists creates a condition which is true when the subquery returns any
+// rows, eg EXISTS (SELECT ...).
+func Exists(subquery Fragment) *Expression {
+	return subqueryExpr("EXISTS (", subquery)
+}
+
+// NotExists creates a condition which is true when the subquery returns no
+// rows, eg NOT EXISTS (SELECT ...).
+func NotExists(subquery Fragment) *Expression {
+	return subqueryExpr("NOT EXISTS (", subquery)
+}
+
+func subqueryExpr(prefix string, subquery Fragment) *Expression {
+	sql, args := subquery.ToSQL()
+	return Expr(prefix+sql+")", args...)
+}
+
+// derivedTable is a subquery used as a FROM source, see
+// SelectBuilder.FromDerived.
+type derivedTable struct {
+	subquery Fragment
+	alias    string
+}
+
+func (d *derivedTable) expression() *Expression {
+	sql, args := d.subquery.ToSQL()
+	return Expr("("+sql+") AS "+QuoteIdent(d.alias), args...)
+}
@@ -0,0 +1,56 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInSubquery(t *testing.T) {
+	sub := Select("user_id").From("posts").Where("state = $1", "published")
+	sql, args := Select("*").
+		From("users").
+		Where("org = $1", 5).
+		Where(In("id", sub)).
+		Where(NotIn("id", Select("user_id").From("bans").Where("until > $1", 10))).
+		ToSQL()
+
+	assert.Equal(t, "SELECT * FROM users WHERE (org = $1) AND (id IN (SELECT user_id FROM posts WHERE (state = $2))) AND (id NOT IN (SELECT user_id FROM bans WHERE (until > $3)))", sql)
+	assert.Equal(t, []interface{}{5, "published", 10}, args)
+}
+
+func TestExistsSubquery(t *testing.T) {
+	sql, args := Select("*").
+		From("users u").
+		Where(Exists(Select("1").From("posts p").Where("p.user_id = u.id AND p.state = $1", "draft"))).
+		Where(NotExists(SQL("SELECT 1 FROM bans b WHERE b.user_id = u.id"))).
+		Where("u.id > $1", 1).
+		ToSQL()
+
+	assert.Equal(t, "SELECT * FROM users u WHERE (EXISTS (SELECT 1 FROM posts p WHERE (p.user_id = u.id AND p.state = $1))) AND (NOT EXISTS (SELECT 1 FROM bans b WHERE b.user_id = u.id)) AND (u.id > $2)", sql)
+	assert.Equal(t, []interface{}{"draft", 1}, args)
+}
+
+func TestSelectFromDerived(t *testing.T) {
+	counts := Select("user_id", "count(*) AS n").
+		From("posts").
+		Where("state = $1", "published").
+		GroupBy("user_id")
+
+	sql, args := Select("t.user_id", "t.n").
+		FromDerived(counts, "t").
+		Join("users u", "u.id = t.user_id AND u.active = $1", true).
+		Where("t.n > $1", 10).
+		ToSQL()
+
+	assert.Equal(t, stripWS(`
+		SELECT t.user_id, t.n
+		FROM (SELECT user_id, count(*) AS n FROM posts WHERE (state = $1) GROUP BY user_id) AS "t"
+		INNER JOIN users u ON (u.id = t.user_id AND u.active = $2)
+		WHERE (t.n > $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"published", true, 10}, args)
+}
+
+func TestSelectFromInvalid(t *testing.T) {
+	assert.Panics(t, func() { Select("*").FromDerived(Select("*").From("a"), "") })
+}
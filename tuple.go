@@ -0,0 +1,48 @@
+package dat
+
+import "fmt"
+
+// InTuple creates a condition which is true when the row value of columns is
+// in rows, eg (org_id, user_id) IN (($1, $2), ($3, $4)). An empty rows
+// produces FALSE. Panics if a row does not have one value per column.
+func InTuple(columns []string, rows [][]interface{}) *Expression {
+	if len(columns) == 0 {
+		panic("InTuple requires at least one column")
+	}
+	if len(rows) == 0 {
+		return Expr("FALSE")
+	}
+
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+
+	args := make([]interface{}, 0, len(columns)*len(rows))
+	buf.WriteRune('(')
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(column)
+	}
+	buf.WriteString(") IN (")
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			panic(fmt.Sprintf("InTuple row %d has %d values, expected %d", i, len(row), len(columns)))
+		}
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteRune('(')
+		for j, value := range row {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			args = append(args, value)
+			writePlaceholder(buf, len(args))
+		}
+		buf.WriteRune(')')
+	}
+	buf.WriteRune(')')
+
+	return Expr(buf.String(), args...)
+}
@@ -0,0 +1,37 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInTuple(t *testing.T) {
+	sql, args := Select("*").
+		From("memberships").
+		Where("active = $1", true).
+		Where(InTuple([]string{"org_id", "user_id"}, [][]interface{}{{1, 2}, {3, 4}})).
+		ToSQL()
+	assert.Equal(t, "SELECT * FROM memberships WHERE (active = $1) AND ((org_id, user_id) IN (($2, $3), ($4, $5)))", sql)
+	assert.Exactly(t, []interface{}{true, 1, 2, 3, 4}, args)
+
+	sql, args, err := Interpolate(sql, args)
+	assert.NoError(t, err)
+	assert.Empty(t, args)
+	assert.Equal(t, "SELECT * FROM memberships WHERE (active = 't') AND ((org_id, user_id) IN ((1, 2), (3, 4)))", sql)
+}
+
+func TestInTupleEmpty(t *testing.T) {
+	sql, args := Select("*").
+		From("memberships").
+		Where(InTuple([]string{"org_id", "user_id"}, nil)).
+		ToSQL()
+	assert.Equal(t, "SELECT * FROM memberships WHERE (FALSE)", sql)
+	assert.Empty(t, args)
+}
+
+func TestInTupleMismatch(t *testing.T) {
+	assert.Panics(t, func() {
+		InTuple([]string{"org_id", "user_id"}, [][]interface{}{{1, 2}, {3}})
+	})
+}
@@ -191,7 +191,7 @@ func (n *NullTime) UnmarshalJSON(b []byte) error {
 			return n.Scan(t)
 		}
 	}
-	logger.Error("Cannot parse time", zap.String("time", s), zap.Strings("formats", formats))
+	logger().Error("Cannot parse time", zap.String("time", s), zap.Strings("formats", formats))
 	return fmt.Errorf("cannot parse time %q for formats %+v", s, formats)
 }
 
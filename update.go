@@ -3,6 +3,8 @@ package dat
 import (
 	"reflect"
 	"strconv"
+
+	"github.com/casualjim/dat/common"
 )
 
 // UpdateBuilder contains the clauses for an UPDATE statement
@@ -10,8 +12,12 @@ type UpdateBuilder struct {
 	Execer
 
 	isInterpolated bool
+	arrayBinding   bool
 	table          string
 	setClauses     []*setClause
+	record         interface{}
+	whitelist      []string
+	blacklist      []string
 	whereFragments []*whereFragment
 	orderBys       []string
 	limitCount     uint64
@@ -20,6 +26,13 @@ type UpdateBuilder struct {
 	offsetValid    bool
 	returnings     []string
 	scope          Scope
+	lockColumn     string
+	lockVersion    interface{}
+
+	valuesKey     string
+	valuesColumns []string
+	valuesRows    [][]interface{}
+	valuesTypes   map[string]string
 }
 
 type setClause struct {
@@ -30,7 +43,7 @@ type setClause struct {
 // NewUpdateBuilder creates a new UpdateBuilder for the given table
 func NewUpdateBuilder(table string) *UpdateBuilder {
 	if table == "" {
-		logger.Error("Update requires a table name")
+		logger().Error("Update requires a table name")
 		return nil
 	}
 	return &UpdateBuilder{table: table, isInterpolated: EnableInterpolation}
@@ -93,13 +106,71 @@ func (b *UpdateBuilder) SetWhitelist(rec interface{}, whitelist ...string) *Upda
 	return b
 }
 
-// ScopeMap uses a predefined scope in place of WHERE.
+// Record creates SET clause(s) from the columns of record, limited by
+// Whitelist and Blacklist. All columns are set by default.
+func (b *UpdateBuilder) Record(record interface{}) *UpdateBuilder {
+	b.record = record
+	return b
+}
+
+// Whitelist defines a whitelist of columns of Record to be set. To specify
+// all columns of the record use "*".
+func (b *UpdateBuilder) Whitelist(columns ...string) *UpdateBuilder {
+	b.whitelist = columns
+	return b
+}
+
+// Blacklist defines a blacklist of columns of Record which are not set, eg
+// columns managed by the server.
+func (b *UpdateBuilder) Blacklist(columns ...string) *UpdateBuilder {
+	b.blacklist = columns
+	return b
+}
+
+// recordSetClauses creates the SET clauses of Record.
+func (b *UpdateBuilder) recordSetClauses() []*setClause {
+	if b.record == nil {
+		if len(b.whitelist) > 0 || len(b.blacklist) > 0 {
+			panic("Whitelist and Blacklist can only be used in conjunction with Record")
+		}
+		return nil
+	}
+
+	columns := recordColumns(b.record, b.whitelist, b.blacklist)
+	ind := reflect.Indirect(reflect.ValueOf(b.record))
+	vals, err := valuesFor(ind.Type(), ind, columns)
+	if err != nil {
+		panic(err)
+	}
+
+	clauses := make([]*setClause, len(columns))
+	for i, val := range vals {
+		clauses[i] = &setClause{column: columns[i], value: val}
+	}
+	return clauses
+}
+
+// ScopeMap uses a predefined scope whose WHERE condition is AND-ed with
+// any Where conditions.
 func (b *UpdateBuilder) ScopeMap(mapScope *MapScope, m M) *UpdateBuilder {
 	b.scope = mapScope.mergeClone(m)
 	return b
 }
 
-// Scope uses a predefined scope in place of WHERE.
+// UseScope uses scope, which may be shared across builders, like ScopeMap.
+func (b *UpdateBuilder) UseScope(scope Scope) *UpdateBuilder {
+	b.scope = scope
+	return b
+}
+
+// Unscoped removes any scope from the statement, e.g. to include soft
+// deleted rows.
+func (b *UpdateBuilder) Unscoped() *UpdateBuilder {
+	b.scope = nil
+	return b
+}
+
+// Scope uses a predefined scope like ScopeMap.
 func (b *UpdateBuilder) Scope(sql string, args ...interface{}) *UpdateBuilder {
 	b.scope = ScopeFunc(func(table string) (string, []interface{}) {
 		return escapeScopeTable(sql, table), args
@@ -113,6 +184,44 @@ func (b *UpdateBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *U
 	return b
 }
 
+// WhereIf appends a WHERE clause only if cond is true, eg for an optional
+// filter. The args are evaluated either way.
+func (b *UpdateBuilder) WhereIf(cond bool, whereSQLOrMap interface{}, args ...interface{}) *UpdateBuilder {
+	if cond {
+		b.Where(whereSQLOrMap, args...)
+	}
+	return b
+}
+
+// Filter appends the conditions of f to the WHERE clause.
+func (b *UpdateBuilder) Filter(f *FilterBuilder) *UpdateBuilder {
+	b.whereFragments = filterFragments(b.whereFragments, f)
+	return b
+}
+
+// OptimisticLock updates the row only if column still holds version, and
+// increments column. Exec returns ErrStaleObject when no row is updated.
+func (b *UpdateBuilder) OptimisticLock(column string, version interface{}) *UpdateBuilder {
+	if column == "" {
+		panic("OptimisticLock requires a column")
+	}
+	b.lockColumn = column
+	b.lockVersion = version
+	return b
+}
+
+// IsOptimisticLocked determines if the update uses OptimisticLock.
+func (b *UpdateBuilder) IsOptimisticLocked() bool {
+	return b.lockColumn != ""
+}
+
+// UseArrayBinding binds slices as a single Postgres array when enabled,
+// see SelectBuilder.UseArrayBinding.
+func (b *UpdateBuilder) UseArrayBinding(enable bool) *UpdateBuilder {
+	b.arrayBinding = enable
+	return b
+}
+
 // OrderBy appends a column to ORDER the statement by
 func (b *UpdateBuilder) OrderBy(ord string) *UpdateBuilder {
 	b.orderBys = append(b.orderBys, ord)
@@ -145,7 +254,17 @@ func (b *UpdateBuilder) ToSQL() (string, []interface{}) {
 	if len(b.table) == 0 {
 		panic("no table specified")
 	}
-	if len(b.setClauses) == 0 {
+	setClauses := b.setClauses
+	if recordClauses := b.recordSetClauses(); len(recordClauses) > 0 {
+		setClauses = append(setClauses[:len(setClauses):len(setClauses)], recordClauses...)
+	}
+	whereFragments := b.whereFragments
+	if b.lockColumn != "" {
+		quoted := QuoteIdent(b.lockColumn)
+		setClauses = append(setClauses[:len(setClauses):len(setClauses)], &setClause{column: b.lockColumn, value: Expr(quoted + " + 1")})
+		whereFragments = append(whereFragments[:len(whereFragments):len(whereFragments)], newWhereFragment(quoted+" = $1", []interface{}{b.lockVersion}))
+	}
+	if len(setClauses) == 0 && len(b.valuesRows) == 0 {
 		panic("no set clauses specified")
 	}
 
@@ -160,39 +279,29 @@ func (b *UpdateBuilder) ToSQL() (string, []interface{}) {
 	var placeholderStartPos int64 = 1
 
 	// Build SET clause SQL with placeholders and add values to args
-	for i, c := range b.setClauses {
-		if i > 0 {
+	writeSetClauses(buf, setClauses, &args, &placeholderStartPos)
+	if len(b.valuesRows) > 0 {
+		if len(setClauses) > 0 {
 			buf.WriteString(", ")
 		}
-		Dialect.WriteIdentifier(buf, c.column)
-		if e, ok := c.value.(*Expression); ok {
-			start := placeholderStartPos
-			buf.WriteString(" = ")
-			// map relative $1, $2 placeholders to absolute
-			remapPlaceholders(buf, e.Sql, start)
-			args = append(args, e.Args...)
-			placeholderStartPos += int64(len(e.Args))
-		} else {
-			// TOOD
-			if placeholderStartPos < maxLookup {
-				buf.WriteString(equalsPlaceholderTab[placeholderStartPos])
-			} else {
-				buf.WriteString(" = $")
-				buf.WriteString(strconv.FormatInt(placeholderStartPos, 10))
-			}
-			placeholderStartPos++
-			args = append(args, c.value)
-		}
+		b.writeValuesSetClauses(buf)
+		b.writeValuesFrom(buf, &args, &placeholderStartPos)
 	}
 
-	if b.scope == nil {
-		if len(b.whereFragments) > 0 {
-			buf.WriteString(" WHERE ")
-			writeAndFragmentsToSQL(buf, b.whereFragments, &args, &placeholderStartPos)
+	whereFragments = writeScope(buf, b.scope, b.table, whereFragments)
+	if b.arrayBinding {
+		whereFragments = arrayBindFragments(whereFragments)
+	}
+	if len(b.valuesRows) > 0 {
+		buf.WriteString(" WHERE ")
+		b.writeValuesJoin(buf)
+		if len(whereFragments) > 0 {
+			buf.WriteString(" AND ")
+			writeAndFragmentsToSQL(buf, whereFragments, &args, &placeholderStartPos)
 		}
-	} else {
-		whereFragment := newWhereFragment(b.scope.ToSQL(b.table))
-		writeScopeCondition(buf, whereFragment, &args, &placeholderStartPos)
+	} else if len(whereFragments) > 0 {
+		buf.WriteString(" WHERE ")
+		writeAndFragmentsToSQL(buf, whereFragments, &args, &placeholderStartPos)
 	}
 
 	// Ordering and limiting
@@ -228,3 +337,32 @@ func (b *UpdateBuilder) ToSQL() (string, []interface{}) {
 
 	return buf.String(), args
 }
+
+// writeSetClauses writes column = value pairs separated by commas. Values
+// which are expressions are written inline with their placeholders remapped.
+func writeSetClauses(buf common.BufferWriter, setClauses []*setClause, args *[]interface{}, pos *int64) {
+	for i, c := range setClauses {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		Dialect.WriteIdentifier(buf, c.column)
+		if e, ok := c.value.(*Expression); ok {
+			start := *pos
+			buf.WriteString(" = ")
+			// map relative $1, $2 placeholders to absolute
+			remapPlaceholders(buf, e.Sql, start)
+			*args = append(*args, e.Args...)
+			*pos += int64(len(e.Args))
+		} else {
+			// TOOD
+			if *pos < maxLookup {
+				buf.WriteString(equalsPlaceholderTab[*pos])
+			} else {
+				buf.WriteString(" = $")
+				buf.WriteString(strconv.FormatInt(*pos, 10))
+			}
+			*pos++
+			*args = append(*args, redact(c.column, c.value))
+		}
+	}
+}
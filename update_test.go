@@ -115,3 +115,43 @@ func TestUpdateBeyondMaxLookup(t *testing.T) {
 	assert.Equal(t, expectedArgs, args)
 
 }
+
+func TestUpdateRecord(t *testing.T) {
+	type Account struct {
+		ID        int64  `db:"id"`
+		Name      string `db:"name"`
+		Email     string `db:"email"`
+		CreatedAt string `db:"created_at"`
+	}
+	a := &Account{ID: 1, Name: "Gopher", Email: "go@pher.com", CreatedAt: "now"}
+
+	sql, args := Update("accounts").Record(a).Whitelist("name", "email").Where("id = $1", a.ID).ToSQL()
+	assert.Equal(t, `UPDATE "accounts" SET "name" = $1, "email" = $2 WHERE (id = $3)`, sql)
+	assert.Exactly(t, []interface{}{"Gopher", "go@pher.com", int64(1)}, args)
+
+	sql, args = Update("accounts").Record(a).Blacklist("id", "created_at").Where("id = $1", a.ID).ToSQL()
+	assert.Equal(t, `UPDATE "accounts" SET "name" = $1, "email" = $2 WHERE (id = $3)`, sql)
+	assert.Exactly(t, []interface{}{"Gopher", "go@pher.com", int64(1)}, args)
+
+	sql, args = Update("accounts").Set("version", 2).Record(a).Whitelist("*").Blacklist("id").Where("id = $1", a.ID).ToSQL()
+	assert.Equal(t, `UPDATE "accounts" SET "version" = $1, "name" = $2, "email" = $3, "created_at" = $4 WHERE (id = $5)`, sql)
+	assert.Exactly(t, []interface{}{2, "Gopher", "go@pher.com", "now", int64(1)}, args)
+
+	assert.Panics(t, func() { Update("accounts").Set("a", 1).Whitelist("name").ToSQL() })
+	assert.Panics(t, func() { Update("accounts").Record(a).Whitelist("missing").ToSQL() })
+}
+
+func TestUpdateOptimisticLock(t *testing.T) {
+	b := Update("accounts").Set("name", "Gopher").Where("id = $1", 1).OptimisticLock("version", 3)
+	assert.True(t, b.IsOptimisticLocked())
+
+	sql, args := b.ToSQL()
+	assert.Equal(t, `UPDATE "accounts" SET "name" = $1, "version" = "version" + 1 WHERE (id = $2) AND ("version" = $3)`, sql)
+	assert.Exactly(t, []interface{}{"Gopher", 1, 3}, args)
+
+	// building again does not repeat the lock
+	sql2, _ := b.ToSQL()
+	assert.Equal(t, sql, sql2)
+
+	assert.False(t, Update("accounts").Set("name", "Gopher").IsOptimisticLocked())
+}
@@ -0,0 +1,192 @@
+package dat
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/casualjim/dat/common"
+)
+
+// valuesAlias is the alias of the VALUES list joined by SetFromValues.
+const valuesAlias = "_v"
+
+// SetFromValues updates many rows, each with its own values, in a single
+// statement. Every row must have the same columns, one of which is keyCol.
+// The rows are joined as a VALUES list on keyCol and every other column is
+// SET from it.
+//
+//	UPDATE "people" SET "name" = "_v"."name"
+//	FROM (VALUES ($1::bigint, $2::text), ($3::bigint, $4::text)) AS "_v"("id", "name")
+//	WHERE "people"."id" = "_v"."id"
+//
+// Values are cast to a type inferred from the Go value so that Postgres does
+// not resolve them as text, see ValuesTypes to set the types explicitly.
+func (b *UpdateBuilder) SetFromValues(keyCol string, rows []M) *UpdateBuilder {
+	if len(rows) == 0 {
+		panic("SetFromValues requires at least one row")
+	}
+
+	columns := make([]string, 0, len(rows[0]))
+	hasKey := false
+	for column := range rows[0] {
+		if column == keyCol {
+			hasKey = true
+		}
+		columns = append(columns, column)
+	}
+	if !hasKey {
+		panic(fmt.Sprintf("SetFromValues rows must contain the key column %q", keyCol))
+	}
+	if len(columns) < 2 {
+		panic("SetFromValues rows require a column other than the key column")
+	}
+	// the key column goes first, the rest sorted for a stable statement
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i] == keyCol || columns[j] == keyCol {
+			return columns[i] == keyCol
+		}
+		return columns[i] < columns[j]
+	})
+
+	values := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			panic(fmt.Sprintf("SetFromValues row %d has %d columns, expected %d", i, len(row), len(columns)))
+		}
+		values[i] = make([]interface{}, len(columns))
+		for j, column := range columns {
+			value, ok := row[column]
+			if !ok {
+				panic(fmt.Sprintf("SetFromValues row %d is missing column %q", i, column))
+			}
+			values[i][j] = value
+		}
+	}
+
+	b.valuesKey = keyCol
+	b.valuesColumns = columns
+	b.valuesRows = values
+	return b
+}
+
+// ValuesTypes sets the Postgres types of the SetFromValues columns, eg
+// {"price": "numeric"}, overriding the types inferred from the values.
+func (b *UpdateBuilder) ValuesTypes(types map[string]string) *UpdateBuilder {
+	b.valuesTypes = types
+	return b
+}
+
+// valuesColumnTypes returns the type each VALUES column is cast to. An empty
+// type is not cast.
+func (b *UpdateBuilder) valuesColumnTypes() []string {
+	types := make([]string, len(b.valuesColumns))
+	for j, column := range b.valuesColumns {
+		if t, ok := b.valuesTypes[column]; ok {
+			types[j] = t
+			continue
+		}
+		for _, row := range b.valuesRows {
+			if t := inferPgType(row[j]); t != "" {
+				types[j] = t
+				break
+			}
+		}
+	}
+	return types
+}
+
+// inferPgType returns the Postgres type for the Go value v, or "" if it has
+// no obvious type.
+func inferPgType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return ""
+	case time.Time, *time.Time:
+		return "timestamptz"
+	case []byte:
+		return "bytea"
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "bigint"
+	case reflect.Float32, reflect.Float64:
+		return "double precision"
+	case reflect.String:
+		return "text"
+	case reflect.Bool:
+		return "boolean"
+	}
+	return ""
+}
+
+// writeValuesSetClauses writes the SET clauses of SetFromValues columns.
+func (b *UpdateBuilder) writeValuesSetClauses(buf common.BufferWriter) {
+	i := 0
+	for _, column := range b.valuesColumns {
+		if column == b.valuesKey {
+			continue
+		}
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		i++
+		Dialect.WriteIdentifier(buf, column)
+		buf.WriteString(" = ")
+		Dialect.WriteIdentifier(buf, valuesAlias)
+		buf.WriteRune('.')
+		Dialect.WriteIdentifier(buf, column)
+	}
+}
+
+// writeValuesFrom writes the FROM (VALUES ...) clause of SetFromValues.
+func (b *UpdateBuilder) writeValuesFrom(buf common.BufferWriter, args *[]interface{}, pos *int64) {
+	types := b.valuesColumnTypes()
+
+	buf.WriteString(" FROM (VALUES ")
+	for i, row := range b.valuesRows {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteRune('(')
+		for j, value := range row {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			writePlaceholder(buf, int(*pos))
+			if types[j] != "" {
+				buf.WriteString("::")
+				buf.WriteString(types[j])
+			}
+			*pos++
+			*args = append(*args, value)
+		}
+		buf.WriteRune(')')
+	}
+	buf.WriteString(") AS ")
+	Dialect.WriteIdentifier(buf, valuesAlias)
+	buf.WriteRune('(')
+	writeIdentifiers(buf, b.valuesColumns, ", ")
+	buf.WriteRune(')')
+}
+
+// writeValuesJoin writes the condition joining the table to SetFromValues.
+func (b *UpdateBuilder) writeValuesJoin(buf common.BufferWriter) {
+	writeIdentifier(buf, b.table)
+	buf.WriteRune('.')
+	Dialect.WriteIdentifier(buf, b.valuesKey)
+	buf.WriteString(" = ")
+	Dialect.WriteIdentifier(buf, valuesAlias)
+	buf.WriteRune('.')
+	Dialect.WriteIdentifier(buf, b.valuesKey)
+}
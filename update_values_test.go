@@ -0,0 +1,47 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateSetFromValues(t *testing.T) {
+	sql, args := Update("people").
+		Set("updated", true).
+		SetFromValues("id", []M{
+			{"id": 1, "name": "Mario", "amount": 1.5},
+			{"id": 2, "name": "John", "amount": nil},
+		}).
+		Where("deleted_at IS NULL").
+		ToSQL()
+
+	assert.Equal(t, stripWS(`
+		UPDATE "people"
+		SET "updated" = $1, "amount" = "_v"."amount", "name" = "_v"."name"
+		FROM (VALUES ($2::bigint, $3::double precision, $4::text), ($5::bigint, $6::double precision, $7::text)) AS "_v"("id", "amount", "name")
+		WHERE "people"."id" = "_v"."id" AND (deleted_at IS NULL)`), stripWS(sql))
+	assert.Equal(t, []interface{}{true, 1, 1.5, "Mario", 2, nil, "John"}, args)
+}
+
+func TestUpdateSetFromValuesTypes(t *testing.T) {
+	sql, _ := Update("products").
+		SetFromValues("sku", []M{{"sku": "a1", "price": "9.99", "meta": struct{}{}}}).
+		ValuesTypes(map[string]string{"price": "numeric"}).
+		ToSQL()
+
+	assert.Equal(t, stripWS(`
+		UPDATE "products"
+		SET "meta" = "_v"."meta", "price" = "_v"."price"
+		FROM (VALUES ($1::text, $2, $3::numeric)) AS "_v"("sku", "meta", "price")
+		WHERE "products"."sku" = "_v"."sku"`), stripWS(sql))
+}
+
+func TestUpdateSetFromValuesInvalid(t *testing.T) {
+	assert.Panics(t, func() { Update("a").SetFromValues("id", nil) })
+	assert.Panics(t, func() { Update("a").SetFromValues("id", []M{{"name": "x"}}) })
+	assert.Panics(t, func() { Update("a").SetFromValues("id", []M{{"id": 1}}) })
+	assert.Panics(t, func() {
+		Update("a").SetFromValues("id", []M{{"id": 1, "name": "x"}, {"id": 2, "other": "y"}})
+	})
+}
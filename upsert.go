@@ -10,16 +10,19 @@ type UpsertBuilder struct {
 	table          string
 	cols           []string
 	isBlacklist    bool
+	blacklist      []string
 	vals           []interface{}
 	record         interface{}
 	returnings     []string
 	whereFragments []*whereFragment
+	inserted       *bool
+	conflict       *ConflictBuilder
 }
 
 // NewUpsertBuilder creates a new UpsertBuilder for the given table.
 func NewUpsertBuilder(table string) *UpsertBuilder {
 	if table == "" {
-		logger.Error("Insect requires a table name.")
+		logger().Error("Insect requires a table name.")
 		return nil
 	}
 	return &UpsertBuilder{table: table, isInterpolated: EnableInterpolation}
@@ -31,10 +34,11 @@ func (b *UpsertBuilder) Columns(columns ...string) *UpsertBuilder {
 }
 
 // Blacklist defines a blacklist of columns and should only be used
-// in conjunction with Record.
+// in conjunction with Record. Blacklisted columns are removed from the
+// whitelist, which defaults to all columns of the record.
 func (b *UpsertBuilder) Blacklist(columns ...string) *UpsertBuilder {
 	b.isBlacklist = true
-	b.cols = columns
+	b.blacklist = columns
 	return b
 }
 
@@ -63,38 +67,80 @@ func (b *UpsertBuilder) Returning(columns ...string) *UpsertBuilder {
 	return b
 }
 
+// ReturningInserted returns whether the row was inserted rather than
+// updated, which the runner stores in inserted when scanning the row with
+// QueryStruct or QueryScalar.
+func (b *UpsertBuilder) ReturningInserted(inserted *bool) *UpsertBuilder {
+	b.inserted = inserted
+	return b
+}
+
+// InsertedDest returns the destination set by ReturningInserted.
+func (b *UpsertBuilder) InsertedDest() *bool {
+	if b.conflict != nil {
+		return b.conflict.InsertedDest()
+	}
+	return b.inserted
+}
+
+// OnConflict turns the upsert into an INSERT ... ON CONFLICT statement with
+// columns as the conflict target, see InsertBuilder.OnConflict. The
+// columns, values or record, RETURNING and ReturningInserted set so far are
+// inserted, Where is not used.
+//
+//	DB.Upsert("users").Columns("*").Record(u).OnConflict("email").DoUpdateAllExcept("id", "created_at")
+func (b *UpsertBuilder) OnConflict(columns ...string) *ConflictBuilder {
+	ib := &InsertBuilder{
+		Execer:         b.Execer,
+		isInterpolated: b.isInterpolated,
+		table:          b.table,
+		cols:           b.cols,
+		isBlacklist:    b.isBlacklist,
+		blacklist:      b.blacklist,
+		returnings:     b.returnings,
+		inserted:       b.inserted,
+	}
+	if b.record != nil {
+		ib.records = []interface{}{b.record}
+	} else if len(b.vals) > 0 {
+		ib.vals = [][]interface{}{b.vals}
+	}
+	b.conflict = ib.OnConflict(columns...)
+	return b.conflict
+}
+
 // ToSQL serialized the UpsertBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *UpsertBuilder) ToSQL() (string, []interface{}) {
+	if b.conflict != nil {
+		return b.conflict.InsertBuilder.ToSQL()
+	}
 	if len(b.table) == 0 {
 		panic("no table specified")
 	}
 	lenCols := len(b.cols)
-	if lenCols == 0 {
+	if lenCols == 0 && !b.isBlacklist {
 		panic("no columns specified")
 	}
 	if len(b.vals) == 0 && b.record == nil {
 		panic("no values or records specified")
 	}
 
-	if b.record == nil && b.cols[0] == "*" {
-		panic(`"*" can only be used in conjunction with Record`)
-	}
 	if b.record == nil && b.isBlacklist {
 		panic(`Blacklist can only be used in conjunction with Record`)
 	}
+	if b.record == nil && b.cols[0] == "*" {
+		panic(`"*" can only be used in conjunction with Record`)
+	}
 	// build where clause from columns and values
 	if len(b.whereFragments) == 0 {
 		panic("where clause required for upsert")
 	}
 
-	// reflect fields removing blacklisted columns
-	if b.record != nil && b.isBlacklist {
-		b.cols = reflectExcludeColumns(b.record, b.cols)
-	}
-	// reflect all fields
-	if b.record != nil && b.cols[0] == "*" {
-		b.cols = reflectColumns(b.record)
+	// reflect fields, all when whitelisted with "*", removing blacklisted
+	// columns
+	if b.record != nil && (b.isBlacklist || b.cols[0] == "*") {
+		b.cols = recordColumns(b.record, b.cols, b.blacklist)
 	}
 
 	if len(b.returnings) == 0 {
@@ -183,6 +229,9 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}) {
 	ub.returnings = b.returnings
 	updateSQL, args := ub.ToSQL()
 	buf.WriteString(updateSQL)
+	if b.inserted != nil {
+		buf.WriteString(",false AS " + InsertedColumn)
+	}
 
 	buf.WriteString("), ins AS (")
 
@@ -196,6 +245,9 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}) {
 
 	buf.WriteString(" WHERE NOT EXISTS (SELECT 1 FROM upd) RETURNING ")
 	writeIdentifiers(buf, b.returnings, ",")
+	if b.inserted != nil {
+		buf.WriteString(",true AS " + InsertedColumn)
+	}
 
 	buf.WriteString(") SELECT * FROM ins UNION ALL SELECT * FROM upd")
 
@@ -208,3 +260,18 @@ func (b *UpsertBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *U
 	b.whereFragments = append(b.whereFragments, newWhereFragment(whereSQLOrMap, args))
 	return b
 }
+
+// WhereIf appends a WHERE clause only if cond is true, eg for an optional
+// filter. The args are evaluated either way.
+func (b *UpsertBuilder) WhereIf(cond bool, whereSQLOrMap interface{}, args ...interface{}) *UpsertBuilder {
+	if cond {
+		b.Where(whereSQLOrMap, args...)
+	}
+	return b
+}
+
+// Filter appends the conditions of f to the WHERE clause.
+func (b *UpsertBuilder) Filter(f *FilterBuilder) *UpsertBuilder {
+	b.whereFragments = filterFragments(b.whereFragments, f)
+	return b
+}
@@ -88,3 +88,24 @@ func TestUpsertSQLRecord(t *testing.T) {
 	assert.Equal(t, stripWS(expected), stripWS(sql))
 	assert.Equal(t, []interface{}{1, 2, 4}, args)
 }
+
+func TestUpsertSQLReturningInserted(t *testing.T) {
+	var inserted bool
+	sql, _ := Upsert("tab").Columns("b").Values(1).Where("d=$1", 4).Returning("f").ReturningInserted(&inserted).ToSQL()
+	expected := `
+	WITH
+		upd AS (
+			UPDATE "tab"
+			SET "b" = $1
+			WHERE (d=$2)
+			RETURNING "f",false AS dat__inserted
+		), ins AS (
+			INSERT INTO "tab"("b")
+			SELECT $1
+			WHERE NOT EXISTS (SELECT 1 FROM upd)
+			RETURNING "f",true AS dat__inserted
+		)
+	SELECT * FROM ins UNION ALL SELECT * FROM upd
+	`
+	assert.Equal(t, stripWS(expected), stripWS(sql))
+}
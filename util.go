@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/mgutz/str"
 	"go.uber.org/zap"
@@ -20,6 +21,20 @@ import (
 // NameMapping is the routine to use when mapping column names to struct properties
 var NameMapping = camelCaseToSnakeCase
 
+// TruncateLogged truncates s to MaxLoggedSQLLength for logging, appending
+// the total length.
+func TruncateLogged(s string) string {
+	n := MaxLoggedSQLLength
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	// do not split a multibyte character
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "...(truncated, " + strconv.Itoa(len(s)) + " total)"
+}
+
 func camelCaseToSnakeCase(name string) string {
 	var buf bytes.Buffer
 
@@ -281,7 +296,7 @@ func ParseDir(dir string, version string) error {
 		if fi.IsDir() {
 			return nil
 		}
-		logger.Debug("MustRegisterFunctionsInDir", zap.String("dir", dir), zap.String("path", path))
+		logger().Debug("MustRegisterFunctionsInDir", zap.String("dir", dir), zap.String("path", path))
 
 		// bytes, err := ioutil.ReadFile(path) // path is the path to the file.
 		// if err != nil {
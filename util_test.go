@@ -75,3 +75,15 @@ end; $$ language plpgsql;
 	assert.Equal(t, "create function f_foo() as $$\nbegin\nend; $$ language plpgsql;\n", a[2]["_body"])
 	assert.Equal(t, "", a[2]["sproc"])
 }
+
+func TestTruncateLogged(t *testing.T) {
+	assert.Equal(t, "INSERT INTO people", TruncateLogged("INSERT INTO people"))
+
+	MaxLoggedSQLLength = 11
+	defer func() { MaxLoggedSQLLength = 0 }()
+	assert.Equal(t, "INSERT INTO...(truncated, 18 total)", TruncateLogged("INSERT INTO people"))
+	assert.Equal(t, "short", TruncateLogged("short"))
+	// multibyte characters are not split
+	MaxLoggedSQLLength = 12
+	assert.Equal(t, "name = 'Jos...(truncated, 14 total)", TruncateLogged("name = 'José'"))
+}
@@ -3,15 +3,92 @@ package dat
 import (
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/casualjim/dat/common"
 )
 
-// Eq is a map column -> value pairs which must be matched in a query
+// Eq is a map column -> value pairs which must be matched in a query. A nil
+// value matches NULL, eg "col" IS NULL, and a slice matches any of its
+// values, eg "col" IN $1. Conditions are ANDed in the order of the sorted
+// columns.
 type Eq map[string]interface{}
 
+// RewriteNullEquality rewrites comparisons of where conditions with a nil
+// arg to IS NULL when enabled, eg Where("col = $1", nil) becomes col IS NULL
+// and Where("col <> $1", nil) becomes col IS NOT NULL. Otherwise nil is sent
+// as NULL and col = NULL is never true.
+var RewriteNullEquality bool
+
+// reNullComparison matches an equality comparison with a placeholder,
+// capturing the preceding character which must not be part of <=, >=.
+var reNullComparison = regexp.MustCompile(`([^<>!=])\s*(=|<>|!=)\s*\$(\d+)\b`)
+
+// IsNull creates a condition which is true when column is NULL.
+func IsNull(column string) *Expression {
+	return Expr(column + " IS NULL")
+}
+
+// IsNotNull creates a condition which is true when column is not NULL.
+func IsNotNull(column string) *Expression {
+	return Expr(column + " IS NOT NULL")
+}
+
+// Or creates a condition which is true when any of conditions is true, eg
+// (a = $1) OR (b = $2). A condition is anything accepted by Where, a string,
+// Fragment, Expression or Eq map, including a nested And or Or. The
+// placeholders of each condition are relative and renumbered. No conditions
+// produces FALSE.
+func Or(conditions ...interface{}) *Expression {
+	if len(conditions) == 0 {
+		return Expr("FALSE")
+	}
+	return combineConditions(" OR ", conditions)
+}
+
+// And creates a condition which is true when all of conditions are true, eg
+// (a = $1) AND (b = $2), to group conditions within an Or. No conditions
+// produces TRUE.
+func And(conditions ...interface{}) *Expression {
+	if len(conditions) == 0 {
+		return Expr("TRUE")
+	}
+	return combineConditions(" AND ", conditions)
+}
+
+// combineConditions joins conditions with op, each in parentheses.
+func combineConditions(op string, conditions []interface{}) *Expression {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+
+	var args []interface{}
+	var pos int64 = 1
+	for i, c := range conditions {
+		if i > 0 {
+			buf.WriteString(op)
+		}
+		f := newWhereFragment(c, nil)
+		if f.EqualityMap == nil {
+			writeAndFragmentsToSQL(buf, []*whereFragment{f}, &args, &pos)
+			continue
+		}
+		// an Eq map of several columns is ANDed, group it
+		group := len(f.EqualityMap) > 1 && op != " AND "
+		if group {
+			buf.WriteRune('(')
+		}
+		if !writeEqualityMapToSQL(buf, f.EqualityMap, &args, false, &pos) {
+			buf.WriteString("(TRUE)")
+		}
+		if group {
+			buf.WriteRune(')')
+		}
+	}
+	return Expr(buf.String(), args...)
+}
+
 type whereFragment struct {
 	Condition   string
 	Values      []interface{}
@@ -19,24 +96,100 @@ type whereFragment struct {
 }
 
 func newWhereFragment(whereSQLOrMap interface{}, args []interface{}) *whereFragment {
+	f := newFragment(whereSQLOrMap, args)
+	if RewriteNullEquality && f.Condition != "" {
+		f.Condition, f.Values = rewriteNullComparisons(f.Condition, f.Values)
+	}
+	return f
+}
+
+func newFragment(whereSQLOrMap interface{}, args []interface{}) *whereFragment {
 	switch pred := whereSQLOrMap.(type) {
 	case Expression:
 		return &whereFragment{Condition: pred.Sql, Values: pred.Args}
 	case *Expression:
 		return &whereFragment{Condition: pred.Sql, Values: pred.Args}
+	case Fragment:
+		if len(args) > 0 {
+			panic("Where does not take args with a Fragment, the Fragment supplies its own")
+		}
+		sql, values := pred.ToSQL()
+		return &whereFragment{Condition: sql, Values: values}
 	case string:
+		if len(args) == 1 {
+			if named, ok := args[0].(NamedArgs); ok {
+				pred, args = bindNamedArgs(pred, named)
+			}
+		}
 		return &whereFragment{Condition: pred, Values: args}
 	case map[string]interface{}:
 		return &whereFragment{EqualityMap: pred}
 	case Eq:
 		return &whereFragment{EqualityMap: map[string]interface{}(pred)}
 	default:
-		panic("Invalid argument passed to Where. Pass a string or an Eq map.")
+		panic("Invalid argument passed to Where. Pass a string, Fragment or an Eq map.")
 	}
 }
 
 var rePlaceholder = regexp.MustCompile(`\$\d+`)
 
+// rewriteNullComparisons rewrites col = $n to col IS NULL and col <> $n to
+// col IS NOT NULL when arg n is nil. The args no longer referenced are
+// removed and the placeholders renumbered.
+func rewriteNullComparisons(condition string, values []interface{}) (string, []interface{}) {
+	matches := reNullComparison.FindAllStringSubmatchIndex(condition, -1)
+	if len(matches) == 0 {
+		return condition, values
+	}
+
+	var buf strings.Builder
+	rewritten := map[int]bool{}
+	last := 0
+	for _, m := range matches {
+		n, _ := strconv.Atoi(condition[m[6]:m[7]])
+		if n < 1 || n > len(values) || !isNilPointer(values[n-1]) {
+			continue
+		}
+		buf.WriteString(condition[last:m[3]])
+		if condition[m[4]:m[5]] == "=" {
+			buf.WriteString(" IS NULL")
+		} else {
+			buf.WriteString(" IS NOT NULL")
+		}
+		last = m[1]
+		rewritten[n] = true
+	}
+	if len(rewritten) == 0 {
+		return condition, values
+	}
+	buf.WriteString(condition[last:])
+	condition = buf.String()
+
+	// remove the nil args which are no longer referenced
+	referenced := map[int]bool{}
+	for _, p := range rePlaceholder.FindAllString(condition, -1) {
+		n, _ := strconv.Atoi(p[1:])
+		referenced[n] = true
+	}
+	renumbered := make(map[int]int, len(values))
+	var kept []interface{}
+	for i, v := range values {
+		if rewritten[i+1] && !referenced[i+1] {
+			continue
+		}
+		kept = append(kept, v)
+		renumbered[i+1] = len(kept)
+	}
+	condition = rePlaceholder.ReplaceAllStringFunc(condition, func(p string) string {
+		n, _ := strconv.Atoi(p[1:])
+		if to, ok := renumbered[n]; ok {
+			return "$" + strconv.Itoa(to)
+		}
+		return p
+	})
+	return condition, kept
+}
+
 func remapPlaceholders(buf common.BufferWriter, statement string, start int64) int64 {
 	if !strings.Contains(statement, "$") {
 		buf.WriteString(statement)
@@ -59,19 +212,6 @@ func remapPlaceholders(buf common.BufferWriter, statement string, start int64) i
 	return int64(highest)
 }
 
-// Invariant: for scope conditions only
-func writeScopeCondition(buf common.BufferWriter, f *whereFragment, args *[]interface{}, pos *int64) {
-	buf.WriteRune(' ')
-	if len(f.Values) > 0 {
-		// map relative $1, $2 placeholders to absolute
-		replaced := remapPlaceholders(buf, f.Condition, *pos)
-		*pos += replaced
-		*args = append(*args, f.Values...)
-	} else {
-		buf.WriteString(f.Condition)
-	}
-}
-
 func writeAndFragmentsToSQL(buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) {
 	writeFragmentsToSQL(" AND ", true, buf, fragments, args, pos)
 }
@@ -115,8 +255,16 @@ func writeFragmentsToSQL(delimiter string, addParens bool, buf common.BufferWrit
 }
 
 func writeEqualityMapToSQL(buf common.BufferWriter, eq map[string]interface{}, args *[]interface{}, anyConditions bool, pos *int64) bool {
-	for k, v := range eq {
-		if v == nil {
+	// sort the keys so the statement text and args are deterministic
+	keys := make([]string, 0, len(eq))
+	for k := range eq {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := eq[k]
+		if isNilPointer(v) {
 			anyConditions = writeWhereCondition(buf, k, " IS NULL", anyConditions)
 		} else {
 			vVal := reflect.ValueOf(v)
@@ -131,12 +279,24 @@ func writeEqualityMapToSQL(buf common.BufferWriter, eq map[string]interface{}, a
 							buf.WriteString(" AND (1=0)")
 						} else {
 							buf.WriteString("(1=0)")
+							anyConditions = true
 						}
 					}
 				} else if vValLen == 1 {
 					anyConditions = writeWhereCondition(buf, k, equalsPlaceholderTab[*pos], anyConditions)
-					*args = append(*args, vVal.Index(0).Interface())
+					*args = append(*args, redact(k, vVal.Index(0).Interface()))
 					*pos++
+				} else if isRedacted(k) {
+					// " IN ($n,$n+1)" with a secret per value as secrets
+					// are never interpolated
+					var in strings.Builder
+					in.WriteString(" IN ")
+					buildPlaceholders(&in, int(*pos), vValLen)
+					anyConditions = writeWhereCondition(buf, k, in.String(), anyConditions)
+					for i := 0; i < vValLen; i++ {
+						*args = append(*args, redact(k, vVal.Index(i).Interface()))
+						*pos++
+					}
 				} else {
 					// " IN $n"
 					anyConditions = writeWhereCondition(buf, k, inPlaceholderTab[*pos], anyConditions)
@@ -145,7 +305,7 @@ func writeEqualityMapToSQL(buf common.BufferWriter, eq map[string]interface{}, a
 				}
 			} else {
 				anyConditions = writeWhereCondition(buf, k, equalsPlaceholderTab[*pos], anyConditions)
-				*args = append(*args, v)
+				*args = append(*args, redact(k, v))
 				*pos++
 			}
 		}
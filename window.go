@@ -0,0 +1,98 @@
+package dat
+
+import "github.com/casualjim/dat/common"
+
+// WindowBuilder builds the window definition of an OVER or WINDOW clause.
+type WindowBuilder struct {
+	partitionBys []*whereFragment
+	orderBys     []*whereFragment
+	frame        string
+}
+
+type namedWindow struct {
+	name   string
+	window *WindowBuilder
+}
+
+// Window creates a new window definition.
+func Window() *WindowBuilder {
+	return &WindowBuilder{}
+}
+
+// PartitionBy appends an expression to PARTITION BY.
+func (w *WindowBuilder) PartitionBy(sql string, args ...interface{}) *WindowBuilder {
+	w.partitionBys = append(w.partitionBys, newWhereFragment(sql, args))
+	return w
+}
+
+// OrderBy appends an expression to ORDER BY.
+func (w *WindowBuilder) OrderBy(sql string, args ...interface{}) *WindowBuilder {
+	w.orderBys = append(w.orderBys, newWhereFragment(sql, args))
+	return w
+}
+
+// Frame sets the frame clause, eg "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW".
+func (w *WindowBuilder) Frame(frame string) *WindowBuilder {
+	w.frame = frame
+	return w
+}
+
+func (w *WindowBuilder) writeSQL(buf common.BufferWriter, args *[]interface{}, pos *int64) {
+	buf.WriteRune('(')
+	if len(w.partitionBys) > 0 {
+		buf.WriteString("PARTITION BY ")
+		writeCommaFragmentsToSQL(buf, w.partitionBys, args, pos)
+	}
+	if len(w.orderBys) > 0 {
+		if len(w.partitionBys) > 0 {
+			buf.WriteRune(' ')
+		}
+		buf.WriteString("ORDER BY ")
+		writeCommaFragmentsToSQL(buf, w.orderBys, args, pos)
+	}
+	if w.frame != "" {
+		if len(w.partitionBys) > 0 || len(w.orderBys) > 0 {
+			buf.WriteRune(' ')
+		}
+		buf.WriteString(w.frame)
+	}
+	buf.WriteRune(')')
+}
+
+// Over creates an expression applying a window function over w, eg
+//
+//		dat.Over("row_number()", dat.Window().PartitionBy("user_id").OrderBy("created_at DESC"))
+//
+// Use the resulting expression with SelectBuilder.ColumnExpr.
+func Over(expr string, w *WindowBuilder) *Expression {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	var args []interface{}
+	var pos int64 = 1
+
+	buf.WriteString(expr)
+	buf.WriteString(" OVER ")
+	w.writeSQL(buf, &args, &pos)
+	return Expr(buf.String(), args...)
+}
+
+// OverWindow applies a window function over a window defined with
+// SelectBuilder.Window, eg
+//
+//		dat.Select("id", dat.OverWindow("rank()", "w")).Window("w", window)
+func OverWindow(expr string, name string) string {
+	return expr + " OVER " + name
+}
+
+// writeWindowsToSQL writes the WINDOW clause.
+func writeWindowsToSQL(buf common.BufferWriter, windows []*namedWindow, args *[]interface{}, pos *int64) {
+	buf.WriteString(" WINDOW ")
+	for i, w := range windows {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(w.name)
+		buf.WriteString(" AS ")
+		w.window.writeSQL(buf, args, pos)
+	}
+}
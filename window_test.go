@@ -0,0 +1,44 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverToSql(t *testing.T) {
+	expr := Over("row_number()", Window().PartitionBy("user_id").OrderBy("created_at DESC"))
+	assert.Equal(t, "row_number() OVER (PARTITION BY user_id ORDER BY created_at DESC)", expr.Sql)
+	assert.Empty(t, expr.Args)
+
+	expr = Over("sum(amount)", Window().OrderBy("id").Frame("ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW"))
+	assert.Equal(t, "sum(amount) OVER (ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)", expr.Sql)
+}
+
+func TestSelectColumnExprWithArgs(t *testing.T) {
+	w := Window().PartitionBy("state = $1", "open").OrderBy("abs(score - $1)", 10)
+	sql, args := Select("id").
+		ColumnExpr(Over("rank()", w), "r").
+		From("posts").
+		Where("user_id = $1", 1).
+		ToSQL()
+
+	assert.Equal(t, "SELECT id, rank() OVER (PARTITION BY state = $1 ORDER BY abs(score - $2)) AS r FROM posts WHERE (user_id = $3)", sql)
+	assert.Equal(t, []interface{}{"open", 10, 1}, args)
+
+	sql, _, err := Interpolate(sql, args)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id, rank() OVER (PARTITION BY state = 'open' ORDER BY abs(score - 10)) AS r FROM posts WHERE (user_id = 1)", sql)
+}
+
+func TestSelectNamedWindow(t *testing.T) {
+	sql, args := Select("id", OverWindow("rank()", "w"), OverWindow("lag(id)", "w")).
+		From("posts").
+		Where("user_id = $1", 1).
+		Window("w", Window().PartitionBy("user_id").OrderBy("id + $1", 2)).
+		OrderBy("id").
+		ToSQL()
+
+	assert.Equal(t, "SELECT id, rank() OVER w, lag(id) OVER w FROM posts WHERE (user_id = $1) WINDOW w AS (PARTITION BY user_id ORDER BY id + $2) ORDER BY id", sql)
+	assert.Equal(t, []interface{}{1, 2}, args)
+}
@@ -0,0 +1,75 @@
+package dat
+
+import "github.com/casualjim/dat/common"
+
+// WithBuilder holds the common table expressions of a WITH clause.
+type WithBuilder struct {
+	isRecursive bool
+	ctes        []*cte
+}
+
+type cte struct {
+	name string
+	expr *Expression
+}
+
+// With creates a WITH clause for the named query. The name may include a
+// column list, eg "tree(id, parent_id)".
+func With(name string, sqlOrBuilder interface{}, args ...interface{}) *WithBuilder {
+	return (&WithBuilder{}).With(name, sqlOrBuilder, args...)
+}
+
+// WithRecursive creates a WITH RECURSIVE clause for the named query.
+func WithRecursive(name string, sqlOrBuilder interface{}, args ...interface{}) *WithBuilder {
+	return (&WithBuilder{}).WithRecursive(name, sqlOrBuilder, args...)
+}
+
+// With appends a named query to the WITH clause.
+func (w *WithBuilder) With(name string, sqlOrBuilder interface{}, args ...interface{}) *WithBuilder {
+	w.ctes = append(w.ctes, &cte{name: name, expr: cteExpr(sqlOrBuilder, args)})
+	return w
+}
+
+// WithRecursive appends a named query and marks the WITH clause as
+// RECURSIVE. Postgres applies RECURSIVE to the whole clause.
+func (w *WithBuilder) WithRecursive(name string, sqlOrBuilder interface{}, args ...interface{}) *WithBuilder {
+	w.isRecursive = true
+	return w.With(name, sqlOrBuilder, args...)
+}
+
+// Select creates a new SelectBuilder for the given columns which is
+// preceded by the WITH clause.
+func (w *WithBuilder) Select(columns ...string) *SelectBuilder {
+	b := Select(columns...)
+	b.with = w
+	return b
+}
+
+func cteExpr(sqlOrBuilder interface{}, args []interface{}) *Expression {
+	switch t := sqlOrBuilder.(type) {
+	default:
+		panic("sqlOrbuilder accepts only {string, Fragment} type")
+	case Fragment:
+		sql, args := t.ToSQL()
+		return Expr(sql, args...)
+	case string:
+		return Expr(t, args...)
+	}
+}
+
+func (w *WithBuilder) writeSQL(buf common.BufferWriter, args *[]interface{}, pos *int64) {
+	buf.WriteString("WITH ")
+	if w.isRecursive {
+		buf.WriteString("RECURSIVE ")
+	}
+	for i, c := range w.ctes {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(c.name)
+		buf.WriteString(" AS (")
+		c.expr.WriteRelativeArgs(buf, args, pos)
+		buf.WriteRune(')')
+	}
+	buf.WriteRune(' ')
+}
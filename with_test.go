@@ -0,0 +1,44 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithToSql(t *testing.T) {
+	sql, args := With("a", Select("id").From("x").Where("y = $1", 1)).
+		With("b", "SELECT id FROM z WHERE w = $1", 2).
+		Select("*").
+		From("a").
+		Where("id = $1", 3).
+		ToSQL()
+
+	assert.Equal(t, "WITH a AS (SELECT id FROM x WHERE (y = $1)), b AS (SELECT id FROM z WHERE w = $2) SELECT * FROM a WHERE (id = $3)", sql)
+	assert.Equal(t, []interface{}{1, 2, 3}, args)
+}
+
+func TestWithRecursiveToSql(t *testing.T) {
+	sql, args := Select("id", "parent_id").
+		WithRecursive("tree(id, parent_id)", `
+			SELECT id, parent_id FROM nodes WHERE id = $1
+			UNION ALL
+			SELECT n.id, n.parent_id FROM nodes n JOIN tree t ON n.parent_id = t.id`, 1).
+		From("tree").
+		Limit(10).
+		ToSQL()
+
+	assert.Equal(t, `WITH RECURSIVE tree(id, parent_id) AS (
+			SELECT id, parent_id FROM nodes WHERE id = $1
+			UNION ALL
+			SELECT n.id, n.parent_id FROM nodes n JOIN tree t ON n.parent_id = t.id) SELECT id, parent_id FROM tree LIMIT 10`, sql)
+	assert.Equal(t, []interface{}{1}, args)
+}
+
+func TestWithInterpolate(t *testing.T) {
+	sql, args := With("a", "SELECT $1::int AS n", 5).Select("n").From("a").Where("n > $1", 1).ToSQL()
+	sql, _, err := Interpolate(sql, args)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "WITH a AS (SELECT 5::int AS n) SELECT n FROM a WHERE (n > 1)", sql)
+}